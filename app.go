@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net"
 	"sync"
+	"time"
 
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/config"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/delay"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/faults"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/resample"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/scenario"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/serial"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
 )
@@ -15,7 +22,7 @@ type AppState uint8
 
 // Possible app states
 const (
-	// Incomplete is the state when the app is missing a X-Plane or a Serial port
+	// Incomplete is the state when the app is missing a X-Plane or somewhere to send positions
 	Incomplete AppState = iota
 	// Running is the state when the app is running
 	Running
@@ -23,22 +30,45 @@ const (
 	Runable
 )
 
+// SINK_BUFFER is how many positions wait for each sink. When a sink falls behind, its oldest positions are
+// dropped, so a slow sink doesn't hold up the others
+const SINK_BUFFER = 4
+
+// Sink is somewhere positions are sent, other than the serial port (eg a UDP sender)
+type Sink interface {
+	// SendPositions will send the positions from the channel, and send feedback to the feedback channel
+	SendPositions(c <-chan xplane.Position, feedback chan<- string) error
+}
+
+// Stage changes the positions between X-Plane and the sinks (eg adding the errors of a real receiver)
+type Stage interface {
+	// Apply returns the position received at t after the stage
+	Apply(p xplane.Position, t time.Time) xplane.Position
+}
+
 // App is the main application
 type App struct {
 	mu           sync.RWMutex
 	XPlane       *net.UDPAddr
 	Serial       serial.Sender
+	Faults       *faults.Injector
+	Scenario     *scenario.Engine
 	PositionFreq uint
 	Running      bool
+	Config       *config.Config
+	ConfigPath   string
 	Logger       *slog.Logger
+	// Sentences are the sentences chosen for NMEA sinks on the command line. They are used before the config's,
+	// and aren't saved in it
+	Sentences map[string][]string
 }
 
 // State returns the current state of the app
-// The app can not run without a X-Plane and a Serial port
+// The app can not run without a X-Plane and either a Serial port or another sink
 func (a *App) State() AppState {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	if a.XPlane != nil && a.Serial.Configured() {
+	if a.XPlane != nil && (a.Serial.Configured() || hasSinks(a.Config)) {
 		if a.Running {
 			return Running
 		}
@@ -71,6 +101,20 @@ func (a *App) SetBaudRate(baud int) {
 	a.Serial.SetBaud(baud)
 }
 
+// SetSerialPin pins the serial port to a USB device and saves it
+// An empty identity unpins the port
+func (a *App) SetSerialPin(id serial.USBID) {
+	a.Logger.Debug("Set SerialPin", "pin", id)
+	a.mu.Lock()
+	a.Serial.SetPin(id)
+	a.Config.Serial.Pin = nil
+	if !id.IsZero() {
+		a.Config.Serial.Pin = &config.USBDevice{VID: id.VID, PID: id.PID, SerialNumber: id.SerialNumber}
+	}
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
 // SetPositionFreq sets the position frequency
 func (a *App) SetPositionFreq(freq uint) {
 	a.Logger.Debug("Set PositionFreq", "freq", freq)
@@ -79,8 +123,237 @@ func (a *App) SetPositionFreq(freq uint) {
 	a.PositionFreq = freq
 }
 
+// SetGDL90 sets the GDL90 config and saves it
+func (a *App) SetGDL90(cfg config.GDL90) {
+	a.Logger.Debug("Set GDL90", "enabled", cfg.Enabled, "addr", cfg.Addr)
+	a.mu.Lock()
+	a.Config.GDL90 = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetForeFlight sets the ForeFlight config and saves it
+func (a *App) SetForeFlight(cfg config.ForeFlight) {
+	a.Logger.Debug("Set ForeFlight", "enabled", cfg.Enabled, "addr", cfg.Addr)
+	a.mu.Lock()
+	a.Config.ForeFlight = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetMAVLink sets the MAVLink config and saves it
+func (a *App) SetMAVLink(cfg config.MAVLink) {
+	a.Logger.Debug("Set MAVLink", "enabled", cfg.Enabled, "port", cfg.Port, "addr", cfg.Addr)
+	a.mu.Lock()
+	a.Config.MAVLink = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetGPSD sets the gpsd server config and saves it
+func (a *App) SetGPSD(cfg config.GPSD) {
+	a.Logger.Debug("Set gpsd", "enabled", cfg.Enabled, "addr", cfg.Addr)
+	a.mu.Lock()
+	a.Config.GPSD = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetPTY sets the virtual serial port config and saves it
+func (a *App) SetPTY(cfg config.PTY) {
+	a.Logger.Debug("Set PTY", "enabled", cfg.Enabled, "link", cfg.Link)
+	a.mu.Lock()
+	a.Config.PTY = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetSentences sets the sentences sent to the named NMEA sink and saves them, or the enabled outputters if
+// there are none
+// They replace the sentences chosen on the command line. The serial port sends them straight away, and the
+// virtual serial port the next time it runs.
+func (a *App) SetSentences(sink string, sentences []string) {
+	a.Logger.Debug("Set Sentences", "sink", sink, "sentences", sentences)
+	if len(sentences) == 0 {
+		sentences = nil
+	}
+	a.mu.Lock()
+	delete(a.Sentences, sink)
+	switch sink {
+	case "serial":
+		a.Config.Serial.Sentences = sentences
+		a.Serial.SetOutputs(newOutputs(a.Config, a.Sentences, a.Logger))
+	case "pty":
+		a.Config.PTY.Sentences = sentences
+	}
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// OnSentences returns the names of the outputters that are on for the named NMEA sink
+func (a *App) OnSentences(sink string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return onSentences(a.Config, a.Sentences, sink, a.Logger)
+}
+
+// SetErrors sets the GPS error model config and saves it
+func (a *App) SetErrors(cfg config.Errors) {
+	a.Logger.Debug("Set Errors", "enabled", cfg.Enabled, "seed", cfg.Seed)
+	a.mu.Lock()
+	a.Config.Errors = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetResample sets the rate the positions are resampled to and saves it
+func (a *App) SetResample(cfg config.Resample) {
+	a.Logger.Debug("Set Resample", "rate", cfg.Rate, "delay", cfg.Delay)
+	a.mu.Lock()
+	a.Config.Resample = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetDelay sets the simulated delay of the link to the named sink
+func (a *App) SetDelay(sink string, cfg config.Delay) {
+	a.Logger.Debug("Set Delay", "sink", sink, "latency", cfg.Latency, "jitter", cfg.Jitter)
+	a.mu.Lock()
+	if cfg.IsZero() {
+		delete(a.Config.Delays, sink)
+	} else {
+		if a.Config.Delays == nil {
+			a.Config.Delays = make(map[string]config.Delay)
+		}
+		a.Config.Delays[sink] = cfg
+	}
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// SetFault commands a simulated GPS fault, which lasts until another is commanded
+func (a *App) SetFault(f faults.Fault) {
+	a.Logger.Debug("Set Fault", "fault", f)
+	if a.Faults != nil {
+		a.Faults.Set(f)
+	}
+}
+
+// SetFaults sets the simulated GPS failures config and saves it
+func (a *App) SetFaults(cfg config.Faults) {
+	a.Logger.Debug("Set Faults", "schedule", len(cfg.Schedule), "follow", cfg.FollowXPlane)
+	a.mu.Lock()
+	a.Config.Faults = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+}
+
+// LoadScenario loads the jamming and spoofing scenario from a file, and turns it on or off
+func (a *App) LoadScenario(path string, enabled bool) error {
+	a.Logger.Debug("Load Scenario", "path", path, "enabled", enabled)
+	s, err := scenario.Load(path)
+	if err != nil {
+		return err
+	}
+	a.Scenario.Load(s)
+	a.Scenario.SetEnabled(enabled)
+	return nil
+}
+
+// SetScenario loads the jamming and spoofing scenario in the config, turns it on or off and saves it
+// The scenario changes straight away, even while running
+func (a *App) SetScenario(cfg config.Scenario) error {
+	if err := a.LoadScenario(cfg.File, cfg.Enabled); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.Config.Scenario = cfg
+	a.mu.Unlock()
+	a.SaveConfig()
+	return nil
+}
+
+// SaveConfig will save the config to the config path
+func (a *App) SaveConfig() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.Config.Save(a.ConfigPath); err != nil {
+		a.Logger.Error("Failed to save config", "err", err)
+		return
+	}
+	a.Logger.Debug("Saved config", "path", a.ConfigPath)
+}
+
+// sinks returns the sinks to send positions to
+// The serial port is only used if it is configured
+func (a *App) sinks() []namedSink {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var sinks []namedSink
+	if a.Serial.Configured() {
+		sinks = append(sinks, namedSink{"serial", a.Serial})
+	}
+	return append(sinks, newSinks(a.Config, a.Sentences, a.Logger)...)
+}
+
+// resampler returns the resampler for the positions, or nil if they are sent as they are received
+func (a *App) resampler() *resample.Resampler {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := newResampler(a.Config.Resample, a.Logger)
+	if r != nil && a.PositionFreq < r.Rate {
+		a.Logger.Warn("X-Plane positions are slower than the resample rate, most fixes will be extrapolated",
+			"freq", a.PositionFreq, "rate", r.Rate)
+	}
+	return r
+}
+
+// link returns the simulated link to the named sink, or nil if the config doesn't delay it
+func (a *App) link(sink string) *delay.Link {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return newLink(a.Config.Delay(sink), sink, a.Logger)
+}
+
+// stages returns the stages to apply to the positions before they are sent
+func (a *App) stages() []Stage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return newStages(a.Config, a.Scenario, a.Faults, a.Logger)
+}
+
+// followFailures will follow the GPS failures in X-Plane until the context is canceled, if the config says to
+func (a *App) followFailures(ctx context.Context) {
+	a.mu.Lock()
+	follow := a.Config.Faults.FollowXPlane && a.Faults != nil
+	addr := a.XPlane
+	a.mu.Unlock()
+	if !follow {
+		return
+	}
+
+	c := make(chan xplane.Dataref)
+	go func() {
+		defer close(c)
+		if err := xplane.RequestDatarefs(ctx, addr, 1, xplane.GPS_FAILURE_DATAREFS, c); err != nil {
+			a.Logger.Error("Failed to follow X-Plane failures", "err", err)
+		}
+	}()
+
+	failed := make([]bool, len(xplane.GPS_FAILURE_DATAREFS))
+	for dr := range c {
+		if dr.Index < 0 || dr.Index >= len(failed) {
+			continue
+		}
+		failed[dr.Index] = dr.Value == xplane.FAILED
+		// the connector is one receiver, so it fails when the first GPS does
+		a.Faults.SetFailed(failed[0])
+	}
+	a.Faults.SetFailed(false)
+}
+
 // Run will start the app
-// It will request positions from X-Plane and send them to the serial port.
+// It will request positions from X-Plane and send them to the serial port and any other sinks.
 // It will stop when the context is canceled.
 func (a *App) Run(ctx context.Context, feedback chan<- string) {
 	var wg sync.WaitGroup
@@ -99,19 +372,88 @@ func (a *App) Run(ctx context.Context, feedback chan<- string) {
 		xplane.RequestPositions(ctx, a.XPlane, a.PositionFreq, c, feedback)
 		a.Logger.Debug("RequestPositions Done")
 	}()
+
+	// the positions are resampled to an exact rate before the stages, if the config says to
+	var positions <-chan xplane.Position = c
+	if r := a.resampler(); r != nil {
+		rc := make(chan xplane.Position)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Run(c, rc)
+			a.Logger.Debug("Resampler Done")
+		}()
+		positions = rc
+	}
+
 	wg.Add(1)
 	go func() {
-		err := a.Serial.SendPositions(c, feedback)
-		if err != nil {
-			a.Logger.Info("SendPositions failed", "err", err)
-			feedback <- "XXX"
+		defer wg.Done()
+		a.followFailures(ctx)
+	}()
+
+	// each sink gets its own copy of the positions, after the stages
+	stages := a.stages()
+	sinks := a.sinks()
+	cs := make([]chan xplane.Position, len(sinks))
+	for i, s := range sinks {
+		cs[i] = make(chan xplane.Position, SINK_BUFFER)
+		c := cs[i]
+		// a delayed sink gets its positions through its simulated link
+		if l := a.link(s.name); l != nil {
+			c = make(chan xplane.Position)
+			wg.Add(1)
+			go func(in chan xplane.Position, out chan xplane.Position) {
+				defer wg.Done()
+				l.Run(in, out)
+			}(cs[i], c)
 		}
-		for range c {
+		wg.Add(1)
+		go func(s namedSink, c chan xplane.Position) {
+			err := s.sink.SendPositions(c, feedback)
+			if err != nil {
+				// the other sinks carry on without it
+				a.Logger.Info("SendPositions failed", "sink", s.name, "err", err)
+				feedback <- fmt.Sprintf("Sending to %s failed: %v", s.name, err)
+			}
+			for range c {
+			}
+			a.Logger.Debug("SendPositions Done, channel drained", "sink", s.name)
+			wg.Done()
+		}(s, c)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for pos := range positions {
+			t := time.Now()
+			for _, st := range stages {
+				pos = st.Apply(pos, t)
+			}
+			for _, sc := range cs {
+				sendLatest(sc, pos)
+			}
+		}
+		for _, sc := range cs {
+			close(sc)
 		}
-		a.Logger.Debug("SendPositions Done, channel drained")
-		wg.Done()
 	}()
 
 	wg.Wait()
 	a.Logger.Debug("Run Done")
 }
+
+// sendLatest will send pos to c, dropping the oldest position waiting in c if it is full
+func sendLatest(c chan xplane.Position, pos xplane.Position) {
+	for {
+		select {
+		case c <- pos:
+			return
+		default:
+		}
+		select {
+		case <-c:
+		default:
+		}
+	}
+}
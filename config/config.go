@@ -0,0 +1,276 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config is the persistent configuration of the app
+type Config struct {
+	// Talker is the NMEA talker ID used by outputters that don't set their own (eg "GP" or "GN")
+	Talker string `json:"talker,omitempty"`
+	// Outputters holds the settings for individual outputters, keyed by sentence type (eg "GGA")
+	Outputters map[string]Outputter `json:"outputters,omitempty"`
+	// Templates holds user defined sentences, keyed by name. Each is a Go text/template of the sentence between
+	// the $ and the checksum
+	Templates map[string]string `json:"templates,omitempty"`
+	// GDL90 is the configuration of the GDL90 output for EFBs
+	GDL90 GDL90 `json:"gdl90"`
+	// ForeFlight is the configuration of the ForeFlight XGPS and XATT output
+	ForeFlight ForeFlight `json:"foreflight"`
+	// MAVLink is the configuration of the MAVLink GPS output for autopilots
+	MAVLink MAVLink `json:"mavlink"`
+	// GPSD is the configuration of the gpsd JSON server for Linux tools
+	GPSD GPSD `json:"gpsd"`
+	// PTY is the configuration of the virtual serial port on Linux
+	PTY PTY `json:"pty"`
+	// Serial is the configuration of the serial port
+	Serial Serial `json:"serial"`
+	// Errors is the configuration of the GPS error model applied to the positions before they are sent
+	Errors Errors `json:"errors"`
+	// Faults is the configuration of the simulated GPS failures
+	Faults Faults `json:"faults"`
+	// Scenario is the configuration of the jamming and spoofing scenario
+	Scenario Scenario `json:"scenario"`
+	// Resample is the configuration of the resampling of X-Plane's positions to an exact rate
+	Resample Resample `json:"resample"`
+	// Delays holds the simulated delays of the links to each sink, keyed by sink (eg "serial" or "gdl90")
+	Delays map[string]Delay `json:"delays,omitempty"`
+}
+
+// GDL90 is the configuration of the GDL90 UDP output
+type GDL90 struct {
+	// Enabled turns the GDL90 output on
+	Enabled bool `json:"enabled,omitempty"`
+	// Addr is the UDP address to send to. If not set, it is broadcast on port 4000
+	Addr string `json:"addr,omitempty"`
+	// Callsign is the callsign of the ownship, up to 8 characters
+	Callsign string `json:"callsign,omitempty"`
+	// ICAO is the 24 bit ICAO address of the ownship in hex (eg "ABCDEF")
+	ICAO string `json:"icao,omitempty"`
+}
+
+// Outputter is the configuration of a single outputter
+type Outputter struct {
+	// Enabled turns the outputter on or off. If not set, the outputter's default is used
+	Enabled *bool `json:"enabled,omitempty"`
+	// Rate is how often the outputter is sent when it is on, in navigation solutions. If not set, the
+	// outputter's default is used
+	Rate uint `json:"rate,omitempty"`
+	// Talker overrides the global talker ID for this outputter
+	Talker string `json:"talker,omitempty"`
+	// Altitude is the altitude reference for GGA sentences, "msl" (the default) or "ellipsoid"
+	Altitude string `json:"altitude,omitempty"`
+	// Deviation is the compass deviation for HDG sentences in degrees, positive east
+	Deviation float64 `json:"deviation,omitempty"`
+	// Mode is the mode indicator for THS sentences (eg "A" or "S")
+	Mode string `json:"mode,omitempty"`
+}
+
+// ForeFlight is the configuration of the ForeFlight XGPS and XATT UDP output
+type ForeFlight struct {
+	// Enabled turns the ForeFlight output on
+	Enabled bool `json:"enabled,omitempty"`
+	// Addr is the UDP address to send to. If not set, it is broadcast on port 49002
+	Addr string `json:"addr,omitempty"`
+	// Name is the simulator name shown in ForeFlight
+	Name string `json:"name,omitempty"`
+	// NoAttitude turns off the XATT attitude messages, so only XGPS positions are sent
+	NoAttitude bool `json:"no_attitude,omitempty"`
+}
+
+// MAVLink is the configuration of the MAVLink GPS output, over a serial port or UDP
+type MAVLink struct {
+	// Enabled turns the MAVLink output on
+	Enabled bool `json:"enabled,omitempty"`
+	// Message is the GPS message to send, "gps_input" (the default) or "hil_gps"
+	Message string `json:"message,omitempty"`
+	// Port is the serial port to send to. If not set, MAVLink is sent over UDP
+	Port string `json:"port,omitempty"`
+	// Baud is the baud rate of the serial port. If not set, 57600 is used
+	Baud int `json:"baud,omitempty"`
+	// Addr is the UDP address to send to. If not set, 127.0.0.1:14550 is used
+	Addr string `json:"addr,omitempty"`
+	// SystemID and ComponentID identify the connector on the MAVLink network. If not set, 1 and 220 (GPS) are
+	// used
+	SystemID    int `json:"system_id,omitempty"`
+	ComponentID int `json:"component_id,omitempty"`
+}
+
+// GPSD is the configuration of the gpsd compatible JSON server
+type GPSD struct {
+	// Enabled turns the gpsd server on
+	Enabled bool `json:"enabled,omitempty"`
+	// Addr is the TCP address to listen on. If not set, ":2947" is used
+	Addr string `json:"addr,omitempty"`
+}
+
+// PTY is the configuration of the virtual serial port
+type PTY struct {
+	// Enabled turns the virtual serial port on
+	Enabled bool `json:"enabled,omitempty"`
+	// Link is the path of the symlink to the port. If not set, "/tmp/xplane-gps" is used
+	Link string `json:"link,omitempty"`
+	// Sentences are the names of the outputters to send. If not set, the outputters enabled in Outputters are
+	// sent
+	Sentences []string `json:"sentences,omitempty"`
+}
+
+// Serial is the configuration of the serial port
+type Serial struct {
+	// Pin pins the serial port to a USB device, so it is used whichever port it is plugged in to
+	Pin *USBDevice `json:"pin,omitempty"`
+	// Sentences are the names of the outputters to send. If not set, the outputters enabled in Outputters are
+	// sent
+	Sentences []string `json:"sentences,omitempty"`
+}
+
+// USBDevice identifies a USB device by its vendor and product IDs in hex, and its serial number if it has one
+type USBDevice struct {
+	VID          string `json:"vid"`
+	PID          string `json:"pid"`
+	SerialNumber string `json:"serial_number,omitempty"`
+}
+
+// Errors is the configuration of the GPS error model
+// Parameters that are not set use the typical errors of a consumer receiver, and a negative value turns that
+// error off. Distances are in meters, speeds in m/s and times in seconds.
+type Errors struct {
+	// Enabled turns the error model on
+	Enabled bool `json:"enabled,omitempty"`
+	// Seed seeds the errors, so a run can be repeated. If not set, a new seed is used each run
+	Seed int64 `json:"seed,omitempty"`
+	// HorizontalDrift and VerticalDrift are the 1 sigma of the slowly wandering error on each axis
+	HorizontalDrift float64 `json:"horizontal_drift,omitempty"`
+	VerticalDrift   float64 `json:"vertical_drift,omitempty"`
+	// CorrelationTime is how long the drift takes to wander
+	CorrelationTime float64 `json:"correlation_time,omitempty"`
+	// HorizontalNoise and VerticalNoise are the 1 sigma of the noise on each fix
+	HorizontalNoise float64 `json:"horizontal_noise,omitempty"`
+	VerticalNoise   float64 `json:"vertical_noise,omitempty"`
+	// VelocityNoise is the 1 sigma of the noise on each velocity component
+	VelocityNoise float64 `json:"velocity_noise,omitempty"`
+	// MultipathInterval is the mean time between multipath jumps
+	MultipathInterval float64 `json:"multipath_interval,omitempty"`
+	// MultipathSize is the 1 sigma size of a multipath jump
+	MultipathSize float64 `json:"multipath_size,omitempty"`
+	// MultipathDuration is how long a multipath jump lasts
+	MultipathDuration float64 `json:"multipath_duration,omitempty"`
+}
+
+// Faults is the configuration of the simulated GPS failures
+type Faults struct {
+	// Schedule is the faults to inject, timed from the first position of each run
+	Schedule []FaultStep `json:"schedule,omitempty"`
+	// FollowXPlane loses the fix while the GPS is failed in X-Plane
+	FollowXPlane bool `json:"follow_xplane,omitempty"`
+}
+
+// FaultStep is a fault in the schedule, which lasts until the next one
+type FaultStep struct {
+	// At is when the fault starts, in seconds
+	At float64 `json:"at"`
+	// Fault is "none", "no_fix", "2d", "dr" (dead reckoning) or "frozen"
+	Fault string `json:"fault"`
+}
+
+// Scenario is the configuration of the jamming and spoofing scenario
+type Scenario struct {
+	// File is the path of the scenario file
+	File string `json:"file,omitempty"`
+	// Enabled turns the scenario on when the app starts
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// Resample is the configuration of the resampling of X-Plane's positions to an exact rate
+type Resample struct {
+	// Rate is the rate of the fixes in Hz, 1, 5, 10, 20 or 50. If not set, positions are sent as they are received
+	Rate uint `json:"rate,omitempty"`
+	// Delay is how long after its epoch each fix is sent, in seconds, so it can be interpolated
+	Delay float64 `json:"delay,omitempty"`
+}
+
+// Delay is the configuration of the simulated delay of the link to a sink
+type Delay struct {
+	// Latency is the fixed delay of every position, in seconds
+	Latency float64 `json:"latency,omitempty"`
+	// Jitter is the size of the random delay added to the latency, in seconds
+	Jitter float64 `json:"jitter,omitempty"`
+	// Distribution is the distribution of the jitter, "uniform" (the default), "normal" or "exponential"
+	Distribution string `json:"distribution,omitempty"`
+	// Reorder is the probability, from 0 to 1, that a position is sent after the next one
+	Reorder float64 `json:"reorder,omitempty"`
+	// Drop is the probability, from 0 to 1, that a position is dropped
+	Drop float64 `json:"drop,omitempty"`
+	// Seed seeds the delays, so a run can be repeated. If not set, a new seed is used each run
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// DefaultPath returns the default location of the config file
+// This is in the user's config directory, or the working directory if that can't be found
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.json"
+	}
+	return filepath.Join(dir, "xplane-serial-gps-connector", "config.json")
+}
+
+// Load will read the config from path
+// A missing file is not an error, and returns an empty config
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read config: %v", err)
+	}
+	if err := json.Unmarshal(bs, c); err != nil {
+		return nil, fmt.Errorf("could not parse config %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save will write the config to path, creating the directory if required
+func (c *Config) Save(path string) error {
+	bs, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("could not create config directory: %v", err)
+	}
+	if err := os.WriteFile(path, bs, 0o644); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	return nil
+}
+
+// Outputter returns the config for the named outputter, or an empty config if it has none
+func (c *Config) Outputter(name string) Outputter {
+	return c.Outputters[name]
+}
+
+// Delay returns the config for the delay of the named sink, or an empty config if it has none
+func (c *Config) Delay(sink string) Delay {
+	return c.Delays[sink]
+}
+
+// IsZero returns whether the delay does nothing
+func (d Delay) IsZero() bool {
+	return d.Latency <= 0 && d.Jitter <= 0 && d.Reorder <= 0 && d.Drop <= 0
+}
+
+// IsEnabled returns whether the outputter is enabled, or def if the config doesn't say
+func (o Outputter) IsEnabled(def bool) bool {
+	if o.Enabled == nil {
+		return def
+	}
+	return *o.Enabled
+}
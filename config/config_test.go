@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if !reflect.DeepEqual(c, &Config{}) {
+		t.Errorf("Expected empty config, but got: %+v", c)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{talker"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("Expected an error, but got none")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.json")
+	expected := &Config{
+		Talker: "GN",
+		Outputters: map[string]Outputter{
+			"GGA": {Talker: "II", Rate: 2},
+		},
+		Templates:  map[string]string{"PXYZ": "PXYZ,{{.Lat}},{{.Lon}}"},
+		GDL90:      GDL90{Enabled: true, Callsign: "N123AB", ICAO: "ABCDEF"},
+		ForeFlight: ForeFlight{Enabled: true, Name: "Sim", NoAttitude: true},
+		MAVLink:    MAVLink{Enabled: true, Message: "hil_gps", Port: "/dev/ttyUSB0", Baud: 115200},
+		GPSD:       GPSD{Enabled: true, Addr: "127.0.0.1:2947"},
+		PTY:        PTY{Enabled: true, Link: "/tmp/gps", Sentences: []string{"GGA", "GSV"}},
+		Serial:     Serial{Pin: &USBDevice{VID: "0403", PID: "6001", SerialNumber: "A10K3XYZ"}, Sentences: []string{"RMC"}},
+		Errors:     Errors{Enabled: true, Seed: 42, HorizontalDrift: 5, MultipathInterval: -1},
+		Scenario:   Scenario{File: "/tmp/spoof.json", Enabled: true},
+		Faults:     Faults{Schedule: []FaultStep{{At: 60, Fault: "no_fix"}, {At: 120, Fault: "none"}}, FollowXPlane: true},
+		Resample:   Resample{Rate: 10, Delay: 0.1},
+		Delays: map[string]Delay{
+			"gdl90": {Latency: 0.5, Jitter: 0.1, Distribution: "normal", Reorder: 0.05, Drop: 0.01, Seed: 7},
+		},
+	}
+	if err := expected.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	result, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected: %+v, but got: %+v", expected, result)
+	}
+	if !reflect.DeepEqual(result.Outputter("VTG"), Outputter{}) {
+		t.Errorf("Expected empty outputter config, but got: %+v", result.Outputter("VTG"))
+	}
+	if !result.Delay("serial").IsZero() {
+		t.Errorf("Expected no delay, but got: %+v", result.Delay("serial"))
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	on, off := true, false
+	testCases := []struct {
+		name      string
+		outputter Outputter
+		def       bool
+		expected  bool
+	}{
+		{"Unset Default On", Outputter{}, true, true},
+		{"Unset Default Off", Outputter{}, false, false},
+		{"Enabled", Outputter{Enabled: &on}, false, true},
+		{"Disabled", Outputter{Enabled: &off}, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := tc.outputter.IsEnabled(tc.def); result != tc.expected {
+				t.Errorf("Expected: %v, but got: %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package delay delays the positions on their way to a sink, like a receiver that delivers its fixes late
+package delay
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Distribution is the distribution of the jitter added to the latency
+type Distribution uint8
+
+// Possible distributions
+// Each adds a delay that is never negative, so Latency is the shortest delay
+const (
+	// UNIFORM jitter is spread evenly between 0 and Jitter
+	UNIFORM Distribution = iota
+	// NORMAL jitter is the size of a normal distribution with a standard deviation of Jitter
+	NORMAL
+	// EXPONENTIAL jitter has a mean of Jitter, with occasional long delays
+	EXPONENTIAL
+)
+
+// String returns the name of the distribution, as used in the config
+func (d Distribution) String() string {
+	switch d {
+	case UNIFORM:
+		return "uniform"
+	case NORMAL:
+		return "normal"
+	case EXPONENTIAL:
+		return "exponential"
+	}
+	return fmt.Sprintf("distribution(%d)", uint8(d))
+}
+
+// ParseDistribution returns the Distribution for a name, eg "normal"
+func ParseDistribution(s string) (Distribution, error) {
+	for _, d := range []Distribution{UNIFORM, NORMAL, EXPONENTIAL} {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+	return UNIFORM, fmt.Errorf("unsupported distribution: %q", s)
+}
+
+// Link delays positions between the fan out of positions and a sink
+// The positions keep the time of their fix, so the sentences still have the time the fix was taken.
+type Link struct {
+	// Latency is the fixed delay of every position
+	Latency time.Duration
+	// Jitter is the size of the random delay added to the latency, with the distribution Distribution
+	Jitter       time.Duration
+	Distribution Distribution
+	// Reorder is the probability that a position is held back and sent after the next one
+	Reorder float64
+	// Drop is the probability that a position is dropped
+	Drop float64
+
+	rnd *rand.Rand
+}
+
+// NewLink returns a new Link without any delay, seeded with seed
+func NewLink(seed int64) *Link {
+	return &Link{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// pending is a position waiting to be sent
+type pending struct {
+	pos xplane.Position
+	due time.Time
+}
+
+// Run will send the positions from in to out after their delays, and close out once in is closed and the
+// positions waiting have been sent
+func (l *Link) Run(in <-chan xplane.Position, out chan<- xplane.Position) {
+	defer close(out)
+	if l.rnd == nil {
+		l.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	var (
+		queue []pending
+		// held is a position being reordered, which is sent after the next one
+		held    *pending
+		lastDue time.Time
+	)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for in != nil || len(queue) > 0 {
+		var wake <-chan time.Time
+		if len(queue) > 0 {
+			timer.Reset(time.Until(queue[0].due))
+			wake = timer.C
+		}
+
+		select {
+		case pos, ok := <-in:
+			if !ok {
+				in = nil
+				if held != nil {
+					queue = insert(queue, *held)
+					held = nil
+				}
+				break
+			}
+			if l.rnd.Float64() < l.Drop {
+				continue
+			}
+
+			p := pending{pos: pos, due: time.Now().Add(l.delay())}
+			// without reordering, a position never overtakes the one before it, however much jitter it has
+			if p.due.Before(lastDue) {
+				p.due = lastDue
+			}
+			lastDue = p.due
+
+			if held != nil {
+				queue = insert(queue, p)
+				held.due = p.due
+				queue = insert(queue, *held)
+				held = nil
+				continue
+			}
+			if l.rnd.Float64() < l.Reorder {
+				held = &p
+				continue
+			}
+			queue = insert(queue, p)
+		case <-wake:
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		now := time.Now()
+		for len(queue) > 0 && !queue[0].due.After(now) {
+			out <- queue[0].pos
+			queue = queue[1:]
+		}
+	}
+}
+
+// delay returns a delay for a position
+func (l *Link) delay() time.Duration {
+	if l.Jitter <= 0 {
+		return l.Latency
+	}
+	j := float64(l.Jitter)
+	switch l.Distribution {
+	case NORMAL:
+		j *= math.Abs(l.rnd.NormFloat64())
+	case EXPONENTIAL:
+		j *= l.rnd.ExpFloat64()
+	default:
+		j *= l.rnd.Float64()
+	}
+	return l.Latency + time.Duration(j)
+}
+
+// insert returns the queue with p inserted in order of when it is due, after any due at the same time
+func insert(queue []pending, p pending) []pending {
+	i := sort.Search(len(queue), func(i int) bool { return queue[i].due.After(p.due) })
+	queue = append(queue, pending{})
+	copy(queue[i+1:], queue[i:])
+	queue[i] = p
+	return queue
+}
@@ -0,0 +1,166 @@
+package delay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var start = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// run sends n positions through the link, one every interval, and returns the positions that come out with
+// how long after it was sent each one came out
+func run(l *Link, n int, interval time.Duration) ([]xplane.Position, []time.Duration) {
+	in := make(chan xplane.Position)
+	out := make(chan xplane.Position, n)
+	go l.Run(in, out)
+
+	sent := make(map[time.Time]time.Time)
+	for i := 0; i < n; i++ {
+		p := xplane.Position{Dat_lat: float64(i), Time: start.Add(time.Duration(i) * time.Second)}
+		sent[p.Time] = time.Now()
+		in <- p
+		time.Sleep(interval)
+	}
+	close(in)
+
+	var ps []xplane.Position
+	var delays []time.Duration
+	for p := range out {
+		ps = append(ps, p)
+		delays = append(delays, time.Since(sent[p.Time]))
+	}
+	return ps, delays
+}
+
+func TestParseDistribution(t *testing.T) {
+	for _, d := range []Distribution{UNIFORM, NORMAL, EXPONENTIAL} {
+		got, err := ParseDistribution(d.String())
+		if err != nil || got != d {
+			t.Errorf("Expected: %s, but got: %s (%v)", d, got, err)
+		}
+	}
+	if _, err := ParseDistribution("gaussian"); err == nil {
+		t.Errorf("Expected an error for an unsupported distribution")
+	}
+}
+
+func TestLatency(t *testing.T) {
+	l := NewLink(1)
+	l.Latency = 50 * time.Millisecond
+	ps, delays := run(l, 5, 10*time.Millisecond)
+	if len(ps) != 5 {
+		t.Fatalf("Expected: %d positions, but got: %d", 5, len(ps))
+	}
+	for i, p := range ps {
+		if p.Dat_lat != float64(i) {
+			t.Errorf("Expected: position %d, but got: %v", i, p.Dat_lat)
+		}
+		// the time of the fix is not changed by the delay
+		if !p.Time.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("Expected: %s, but got: %s", start.Add(time.Duration(i)*time.Second), p.Time)
+		}
+		if delays[i] < l.Latency {
+			t.Errorf("Expected a delay of at least: %s, but got: %s", l.Latency, delays[i])
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, d := range []Distribution{UNIFORM, NORMAL, EXPONENTIAL} {
+		t.Run(d.String(), func(t *testing.T) {
+			l := NewLink(2)
+			l.Latency = 10 * time.Millisecond
+			l.Jitter = 20 * time.Millisecond
+			l.Distribution = d
+
+			varied := false
+			for i := 0; i < 100; i++ {
+				j := l.delay()
+				if j < l.Latency {
+					t.Errorf("Expected a delay of at least: %s, but got: %s", l.Latency, j)
+				}
+				if d == UNIFORM && j >= l.Latency+l.Jitter {
+					t.Errorf("Expected a delay less than: %s, but got: %s", l.Latency+l.Jitter, j)
+				}
+				varied = varied || j != l.Latency
+			}
+			if !varied {
+				t.Errorf("Expected the delay to vary")
+			}
+		})
+	}
+}
+
+func TestOrder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		reorder float64
+		ordered bool
+	}{
+		{"jitter", 0, true},
+		{"reorder", 1, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLink(3)
+			l.Jitter = 30 * time.Millisecond
+			l.Reorder = tc.reorder
+			ps, _ := run(l, 6, time.Millisecond)
+			if len(ps) != 6 {
+				t.Fatalf("Expected: %d positions, but got: %d", 6, len(ps))
+			}
+			inOrder := true
+			for i, p := range ps {
+				if p.Dat_lat != float64(i) {
+					inOrder = false
+				}
+			}
+			if inOrder != tc.ordered {
+				t.Errorf("Expected in order: %t, but got: %v", tc.ordered, ps)
+			}
+		})
+	}
+}
+
+func TestReorder(t *testing.T) {
+	l := NewLink(4)
+	l.Reorder = 1
+	ps, _ := run(l, 4, time.Millisecond)
+	// every position is held back and sent after the next one
+	expected := []float64{1, 0, 3, 2}
+	if len(ps) != len(expected) {
+		t.Fatalf("Expected: %d positions, but got: %d", len(expected), len(ps))
+	}
+	for i, p := range ps {
+		if p.Dat_lat != expected[i] {
+			t.Errorf("Expected: %v, but got: %v", expected[i], p.Dat_lat)
+		}
+	}
+}
+
+func TestDrop(t *testing.T) {
+	testCases := []struct {
+		name string
+		drop float64
+		min  int
+		max  int
+	}{
+		{"none", 0, 50, 50},
+		{"half", 0.5, 10, 40},
+		{"all", 1, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLink(5)
+			l.Drop = tc.drop
+			ps, _ := run(l, 50, 0)
+			if len(ps) < tc.min || len(ps) > tc.max {
+				t.Errorf("Expected: %d to %d positions, but got: %d", tc.min, tc.max, len(ps))
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package faults simulates GPS failures, so pilots can practise loss of GPS procedures
+package faults
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Logger is the default logger for the faults package
+var Logger = slog.Default()
+
+// DR_ERROR_GROWTH is how much the horizontal accuracy of a dead reckoned fix gets worse each second, in meters
+const DR_ERROR_GROWTH = 0.5
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000.0
+
+// Fault is a simulated GPS failure
+type Fault uint8
+
+// Possible faults
+const (
+	// NONE is a working GPS
+	NONE Fault = iota
+	// NO_FIX loses the fix, and the last position is reported as not valid
+	NO_FIX
+	// FIX_2D drops to a 2D fix, holding the altitude from when the fault started
+	FIX_2D
+	// DEAD_RECKONING loses the satellites, and the position is dead reckoned from the last fix
+	DEAD_RECKONING
+	// FROZEN freezes the position at the last fix, which is still reported as valid
+	FROZEN
+)
+
+// FAULTS is all the faults, in order
+var FAULTS = []Fault{NONE, NO_FIX, FIX_2D, DEAD_RECKONING, FROZEN}
+
+// String returns the name of the fault, as used in the config
+func (f Fault) String() string {
+	switch f {
+	case NONE:
+		return "none"
+	case NO_FIX:
+		return "no_fix"
+	case FIX_2D:
+		return "2d"
+	case DEAD_RECKONING:
+		return "dr"
+	case FROZEN:
+		return "frozen"
+	}
+	return fmt.Sprintf("fault(%d)", uint8(f))
+}
+
+// ParseFault returns the Fault for a name, eg "no_fix"
+func ParseFault(s string) (Fault, error) {
+	for _, f := range FAULTS {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return NONE, fmt.Errorf("unsupported fault: %q", s)
+}
+
+// Step is a fault in a schedule, which lasts until the next step
+type Step struct {
+	// At is when the fault starts, after the first position
+	At    time.Duration
+	Fault Fault
+}
+
+// Injector injects faults into the positions, on a schedule or on command
+// A commanded fault takes priority over a failure in X-Plane, which takes priority over the schedule.
+type Injector struct {
+	mu        sync.Mutex
+	schedule  []Step
+	commanded Fault
+	failed    bool
+
+	start  time.Time
+	active Fault
+	// good is the last position before the active fault started, at goodAt
+	good   xplane.Position
+	goodAt time.Time
+}
+
+// NewInjector returns a new Injector with a schedule of faults
+func NewInjector(schedule []Step) *Injector {
+	i := &Injector{}
+	i.Reset(schedule)
+	return i
+}
+
+// Reset will replace the schedule, and start it again from the next position
+func (i *Injector) Reset(schedule []Step) {
+	s := append([]Step(nil), schedule...)
+	sort.SliceStable(s, func(a, b int) bool { return s[a].At < s[b].At })
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.schedule = s
+	i.start = time.Time{}
+	i.goodAt = time.Time{}
+}
+
+// Set will command a fault, which lasts until another is commanded. NONE returns to the schedule
+func (i *Injector) Set(f Fault) {
+	Logger.Debug("Set fault", "fault", f)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.commanded = f
+}
+
+// SetFailed will set whether the GPS has failed in X-Plane, which loses the fix
+func (i *Injector) SetFailed(failed bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if failed != i.failed {
+		Logger.Info("X-Plane GPS failure", "failed", failed)
+	}
+	i.failed = failed
+}
+
+// Fault returns the fault that was applied to the last position
+func (i *Injector) Fault() Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.active
+}
+
+// Apply returns the position received at t with the current fault applied
+func (i *Injector) Apply(p xplane.Position, t time.Time) xplane.Position {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.start.IsZero() {
+		i.start = t
+	}
+	f := i.current(t)
+	if f != i.active {
+		Logger.Info("GPS fault", "fault", f)
+		i.active = f
+	}
+	// without a fix before the fault, the fault starts from the first position
+	if f == NONE || i.goodAt.IsZero() {
+		i.good, i.goodAt = p, t
+	}
+	if f == NONE {
+		return p
+	}
+
+	q := i.good.Quality.OrNominal()
+	switch f {
+	case NO_FIX:
+		p = hold(p, i.good)
+		p.Vx_wrl, p.Vy_wrl, p.Vz_wrl = 0, 0, 0
+		p.Quality = q
+		p.Quality.Fix = gnss.FIX_NONE
+	case FIX_2D:
+		// the receiver assumes the altitude hasn't changed, and without it the position is poorer
+		p.Dat_ele = i.good.Dat_ele
+		p.Y_agl_mtr = i.good.Y_agl_mtr
+		p.Vy_wrl = 0
+		p.Quality = gnss.QualityFor(2*q.HAcc, 10*q.VAcc, q.SAcc)
+		p.Quality.Fix = gnss.FIX_2D
+	case DEAD_RECKONING:
+		p = hold(p, i.good)
+		dt := t.Sub(i.goodAt).Seconds()
+		north, east := -float64(i.good.Vz_wrl)*dt, float64(i.good.Vx_wrl)*dt
+		p.Dat_lat += north / earthRadius * 180 / math.Pi
+		p.Dat_lon += east / (earthRadius * math.Cos(p.Dat_lat*math.Pi/180)) * 180 / math.Pi
+		p.Dat_ele += float64(i.good.Vy_wrl) * dt
+		p.Quality = gnss.QualityFor(q.HAcc+DR_ERROR_GROWTH*dt, q.VAcc+DR_ERROR_GROWTH*dt, q.SAcc)
+		p.Quality.Fix = gnss.FIX_DR
+	case FROZEN:
+		p = hold(p, i.good)
+	}
+	return p
+}
+
+// current returns the fault at t
+// The lock must be held
+func (i *Injector) current(t time.Time) Fault {
+	if i.commanded != NONE {
+		return i.commanded
+	}
+	if i.failed {
+		return NO_FIX
+	}
+	f := NONE
+	elapsed := t.Sub(i.start)
+	for _, s := range i.schedule {
+		if s.At > elapsed {
+			break
+		}
+		f = s.Fault
+	}
+	return f
+}
+
+// hold returns the position with the position, velocity and quality of good
+// The attitude doesn't come from the GPS, so it keeps coming from X-Plane
+func hold(p, good xplane.Position) xplane.Position {
+	p.Dat_lat, p.Dat_lon, p.Dat_ele = good.Dat_lat, good.Dat_lon, good.Dat_ele
+	p.Y_agl_mtr = good.Y_agl_mtr
+	p.Vx_wrl, p.Vy_wrl, p.Vz_wrl = good.Vx_wrl, good.Vy_wrl, good.Vz_wrl
+	p.Quality = good.Quality
+	return p
+}
@@ -0,0 +1,147 @@
+package faults
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var start = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// flying returns the position at t of an aircraft flying north at 50 m/s and climbing at 5 m/s
+func flying(t time.Duration) xplane.Position {
+	return xplane.Position{
+		Dat_lat:     45 + 50*t.Seconds()/earthRadius*180/math.Pi,
+		Dat_lon:     -75,
+		Dat_ele:     1000 + 5*t.Seconds(),
+		Veh_psi_loc: float32(t.Seconds()),
+		Vy_wrl:      5,
+		Vz_wrl:      -50,
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	for _, f := range FAULTS {
+		got, err := ParseFault(f.String())
+		if err != nil || got != f {
+			t.Errorf("Expected: %s, but got: %s (%v)", f, got, err)
+		}
+	}
+	if _, err := ParseFault("broken"); err == nil {
+		t.Errorf("Expected an error for an unsupported fault")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fault Fault
+		check func(t *testing.T, good, p xplane.Position)
+	}{
+		{"None", NONE, func(t *testing.T, good, p xplane.Position) {
+			if p != flying(10*time.Second) {
+				t.Errorf("Expected the position unchanged, but got: %+v", p)
+			}
+		}},
+		{"No Fix", NO_FIX, func(t *testing.T, good, p xplane.Position) {
+			if p.Quality.Fix != gnss.FIX_NONE {
+				t.Errorf("Expected: %s, but got: %s", gnss.FIX_NONE, p.Quality.Fix)
+			}
+			if p.Dat_lat != good.Dat_lat || p.Vz_wrl != 0 {
+				t.Errorf("Expected the last position without a velocity, but got: %+v", p)
+			}
+		}},
+		{"2D", FIX_2D, func(t *testing.T, good, p xplane.Position) {
+			if p.Quality.Fix != gnss.FIX_2D {
+				t.Errorf("Expected: %s, but got: %s", gnss.FIX_2D, p.Quality.Fix)
+			}
+			if p.Dat_ele != good.Dat_ele || p.Dat_lat == good.Dat_lat {
+				t.Errorf("Expected a moving position at the held altitude, but got: %+v", p)
+			}
+			if p.Quality.HDOP <= gnss.NOMINAL_HDOP {
+				t.Errorf("Expected a worse HDOP, but got: %f", p.Quality.HDOP)
+			}
+		}},
+		{"Dead Reckoning", DEAD_RECKONING, func(t *testing.T, good, p xplane.Position) {
+			if p.Quality.Fix != gnss.FIX_DR {
+				t.Errorf("Expected: %s, but got: %s", gnss.FIX_DR, p.Quality.Fix)
+			}
+			// flying straight and level, dead reckoning keeps up with the truth
+			want := flying(10 * time.Second)
+			if math.Abs(p.Dat_lat-want.Dat_lat) > 1e-9 || math.Abs(p.Dat_ele-want.Dat_ele) > 1e-6 {
+				t.Errorf("Expected: %+v, but got: %+v", want, p)
+			}
+			if p.Quality.HAcc < DR_ERROR_GROWTH*5 {
+				t.Errorf("Expected a growing accuracy, but got: %f", p.Quality.HAcc)
+			}
+		}},
+		{"Frozen", FROZEN, func(t *testing.T, good, p xplane.Position) {
+			if p.Quality.Fix != gnss.FIX_3D {
+				t.Errorf("Expected: %s, but got: %s", gnss.FIX_3D, p.Quality.Fix)
+			}
+			if p.Dat_lat != good.Dat_lat || p.Dat_ele != good.Dat_ele || p.Vz_wrl != good.Vz_wrl {
+				t.Errorf("Expected: %+v, but got: %+v", good, p)
+			}
+			if p.Veh_psi_loc != 10 {
+				t.Errorf("Expected the attitude to keep coming from X-Plane, but got: %f", p.Veh_psi_loc)
+			}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			i := NewInjector([]Step{{At: 5 * time.Second, Fault: tc.fault}})
+			var good xplane.Position
+			for s := 0; s <= 10; s++ {
+				d := time.Duration(s) * time.Second
+				p := i.Apply(flying(d), start.Add(d))
+				if s < 5 {
+					good = p
+				}
+				if s == 10 {
+					tc.check(t, good, p)
+				}
+			}
+			if i.Fault() != tc.fault {
+				t.Errorf("Expected: %s, but got: %s", tc.fault, i.Fault())
+			}
+		})
+	}
+}
+
+func TestPriority(t *testing.T) {
+	i := NewInjector([]Step{{At: 0, Fault: FROZEN}, {At: 2 * time.Second, Fault: NONE}})
+	apply := func(s int) Fault {
+		d := time.Duration(s) * time.Second
+		i.Apply(flying(d), start.Add(d))
+		return i.Fault()
+	}
+
+	if f := apply(0); f != FROZEN {
+		t.Errorf("Expected: %s from the schedule, but got: %s", FROZEN, f)
+	}
+	i.SetFailed(true)
+	if f := apply(1); f != NO_FIX {
+		t.Errorf("Expected: %s from X-Plane, but got: %s", NO_FIX, f)
+	}
+	i.Set(FIX_2D)
+	if f := apply(2); f != FIX_2D {
+		t.Errorf("Expected: %s on command, but got: %s", FIX_2D, f)
+	}
+	i.Set(NONE)
+	i.SetFailed(false)
+	if f := apply(3); f != NONE {
+		t.Errorf("Expected: %s at the end of the schedule, but got: %s", NONE, f)
+	}
+
+	i.Reset([]Step{{At: time.Second, Fault: DEAD_RECKONING}})
+	if f := apply(4); f != NONE {
+		t.Errorf("Expected: %s after a reset, but got: %s", NONE, f)
+	}
+	if f := apply(5); f != DEAD_RECKONING {
+		t.Errorf("Expected: %s a second after a reset, but got: %s", DEAD_RECKONING, f)
+	}
+}
@@ -0,0 +1,169 @@
+package gdl90
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// GDL90 message IDs
+const (
+	HEARTBEAT                  = 0x00
+	OWNSHIP_REPORT             = 0x0A
+	OWNSHIP_GEOMETRIC_ALTITUDE = 0x0B
+)
+
+const (
+	// flagByte marks the start and end of a frame
+	flagByte = 0x7E
+	// escapeByte is inserted before flag and escape bytes in a frame
+	escapeByte = 0x7D
+)
+
+// crcTable is the CRC-CCITT (polynomial 0x1021) lookup table
+var crcTable = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC returns the GDL90 frame check sequence of a message
+func CRC(msg []byte) uint16 {
+	var crc uint16
+	for _, b := range msg {
+		crc = crcTable[crc>>8] ^ crc<<8 ^ uint16(b)
+	}
+	return crc
+}
+
+// Frame will add the CRC to a message, escape it and add the flag bytes, ready to send
+func Frame(msg []byte) []byte {
+	crc := CRC(msg)
+	bs := make([]byte, 0, len(msg)+6)
+	bs = append(bs, flagByte)
+	for _, b := range append(msg, byte(crc), byte(crc>>8)) {
+		if b == flagByte || b == escapeByte {
+			bs = append(bs, escapeByte, b^0x20)
+			continue
+		}
+		bs = append(bs, b)
+	}
+	return append(bs, flagByte)
+}
+
+// Heartbeat returns a heartbeat message for time t
+// gpsValid reports whether the ownship position is valid
+func Heartbeat(t time.Time, gpsValid bool) []byte {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	ts := uint32(t.Sub(midnight) / time.Second)
+
+	// status byte 1: bit 7 is GPS position valid, bit 0 is UAT initialised
+	status1 := byte(0x01)
+	if gpsValid {
+		status1 |= 0x80
+	}
+	// status byte 2: bit 7 is bit 16 of the timestamp, bit 0 is UTC OK
+	status2 := byte(0x01)
+	if ts&0x10000 != 0 {
+		status2 |= 0x80
+	}
+
+	msg := []byte{HEARTBEAT, status1, status2, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(msg[3:5], uint16(ts))
+	return msg
+}
+
+// Ownship is the state of the ownship for an ownship report
+type Ownship struct {
+	Address       uint32  // 24 bit ICAO address
+	Lat           float64 // latitude in degrees
+	Lon           float64 // longitude in degrees
+	Altitude      float64 // pressure altitude in feet
+	Airborne      bool
+	NIC           uint8   // navigation integrity category, 0-11
+	NACp          uint8   // navigation accuracy category for position, 0-11
+	GroundSpeed   float64 // in knots
+	VerticalSpeed float64 // in feet per minute
+	Track         float64 // true track in degrees
+	Emitter       uint8   // emitter category, eg 1 for a light aircraft
+	Callsign      string  // up to 8 characters
+}
+
+// OwnshipReport returns an ownship report message
+func OwnshipReport(o Ownship) []byte {
+	msg := make([]byte, 28)
+	msg[0] = OWNSHIP_REPORT
+	// alert status 0 and address type 0 (ADS-B with ICAO address)
+	msg[1] = 0x00
+	putUint24(msg[2:5], o.Address)
+	putUint24(msg[5:8], uint32(encodeAngle(o.Lat)))
+	putUint24(msg[8:11], uint32(encodeAngle(o.Lon)))
+
+	// altitude in 25 foot increments offset by 1000 feet, 0xFFF is invalid
+	alt := uint16(0xFFF)
+	if a := math.Round((o.Altitude + 1000) / 25); a >= 0 && a < 0xFFF {
+		alt = uint16(a)
+	}
+	// misc: bit 3 is airborne, bit 2 is 0 for an updated report, bits 1-0 are 1 for true track
+	misc := uint16(0x1)
+	if o.Airborne {
+		misc |= 0x8
+	}
+	binary.BigEndian.PutUint16(msg[11:13], alt<<4|misc)
+
+	msg[13] = o.NIC<<4 | o.NACp&0x0F
+
+	// horizontal velocity in knots, vertical velocity in 64 fpm increments
+	hv := uint32(math.Min(math.Max(math.Round(o.GroundSpeed), 0), 0xFFE))
+	vv := uint32(int32(math.Min(math.Max(math.Round(o.VerticalSpeed/64), -510), 510))) & 0xFFF
+	putUint24(msg[14:17], hv<<12|vv)
+
+	msg[17] = byte(math.Round(math.Mod(math.Mod(o.Track, 360)+360, 360) / (360.0 / 256)))
+	msg[18] = o.Emitter
+
+	cs := []byte(o.Callsign + "        ")
+	copy(msg[19:27], cs[:8])
+
+	// emergency/priority code 0 (no emergency)
+	msg[27] = 0x00
+	return msg
+}
+
+// OwnshipGeometricAltitude returns an ownship geometric altitude message
+// alt is the height above the WGS84 ellipsoid in feet and vfom is the vertical figure of merit in meters
+func OwnshipGeometricAltitude(alt float64, vfom float64) []byte {
+	msg := make([]byte, 5)
+	msg[0] = OWNSHIP_GEOMETRIC_ALTITUDE
+	binary.BigEndian.PutUint16(msg[1:3], uint16(int16(math.Round(alt/5))))
+
+	// vertical warning is bit 15, 0x7FFF is not available
+	v := uint16(0x7FFF)
+	if vfom >= 0 {
+		v = uint16(math.Min(math.Round(vfom), 0x7FFE))
+	}
+	binary.BigEndian.PutUint16(msg[3:5], v)
+	return msg
+}
+
+// encodeAngle will convert a latitude or longitude to a 24 bit signed fraction of 180 degrees
+func encodeAngle(deg float64) int32 {
+	return int32(math.Round(deg/(180.0/(1<<23)))) & 0xFFFFFF
+}
+
+// putUint24 will put the lower 24 bits of v into bs, big endian
+func putUint24(bs []byte, v uint32) {
+	bs[0] = byte(v >> 16)
+	bs[1] = byte(v >> 8)
+	bs[2] = byte(v)
+}
@@ -0,0 +1,133 @@
+package gdl90
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFrameHeartbeat(t *testing.T) {
+	// example heartbeat from the GDL90 ICD
+	msg := []byte{0x00, 0x81, 0x41, 0xDB, 0xD0, 0x08, 0x02}
+	expected := []byte{0x7E, 0x00, 0x81, 0x41, 0xDB, 0xD0, 0x08, 0x02, 0xB3, 0x8B, 0x7E}
+
+	if crc := CRC(msg); crc != 0x8BB3 {
+		t.Errorf("Expected CRC: 0x8BB3, but got: 0x%04X", crc)
+	}
+	if result := Frame(msg); !bytes.Equal(result, expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, result)
+	}
+}
+
+func TestFrameEscape(t *testing.T) {
+	msg := []byte{0x7E, 0x7D, 0x01}
+	result := Frame(msg)
+
+	if result[0] != flagByte || result[len(result)-1] != flagByte {
+		t.Fatalf("Expected flag bytes at each end, but got: % X", result)
+	}
+	if !bytes.Equal(result[1:5], []byte{0x7D, 0x5E, 0x7D, 0x5D}) {
+		t.Errorf("Expected escaped bytes, but got: % X", result)
+	}
+	if bytes.Contains(result[1:len(result)-1], []byte{flagByte}) {
+		t.Errorf("Expected no flag bytes inside the frame, but got: % X", result)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		t        time.Time
+		valid    bool
+		expected []byte
+	}{
+		{"Midnight", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), true, []byte{0x00, 0x81, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{"Invalid GPS", time.Date(2022, time.January, 1, 0, 0, 1, 0, time.UTC), false, []byte{0x00, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00}},
+		{"17 Bit Timestamp", time.Date(2022, time.January, 1, 23, 59, 59, 0, time.UTC), true, []byte{0x00, 0x81, 0x81, 0x7F, 0x51, 0x00, 0x00}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Heartbeat(tc.t, tc.valid)
+			if !bytes.Equal(result, tc.expected) {
+				t.Errorf("Expected: % X, but got: % X", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestOwnshipReport(t *testing.T) {
+	// example traffic report from the GDL90 ICD, as an ownship report
+	o := Ownship{
+		Address:       0xAB4549,
+		Lat:           2092821 * 180.0 / (1 << 23),
+		Lon:           -5731976 * 180.0 / (1 << 23),
+		Altitude:      5000,
+		Airborne:      true,
+		NIC:           10,
+		NACp:          9,
+		GroundSpeed:   123,
+		VerticalSpeed: 64,
+		Track:         45,
+		Emitter:       1,
+		Callsign:      "N825V",
+	}
+	expected := []byte{
+		0x0A, 0x00, 0xAB, 0x45, 0x49, 0x1F, 0xEF, 0x15, 0xA8, 0x89, 0x78, 0x0F, 0x09, 0xA9, 0x07, 0xB0,
+		0x01, 0x20, 0x01, 0x4E, 0x38, 0x32, 0x35, 0x56, 0x20, 0x20, 0x20, 0x00,
+	}
+
+	result := OwnshipReport(o)
+	if !bytes.Equal(result, expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, result)
+	}
+}
+
+func TestOwnshipReportLimits(t *testing.T) {
+	result := OwnshipReport(Ownship{
+		Altitude:      200000,
+		GroundSpeed:   -10,
+		VerticalSpeed: -100000,
+		Track:         -90,
+		Callsign:      "TOOLONGCALLSIGN",
+	})
+
+	if alt := uint16(result[11])<<4 | uint16(result[12])>>4; alt != 0xFFF {
+		t.Errorf("Expected invalid altitude 0xFFF, but got: 0x%03X", alt)
+	}
+	if hv := uint16(result[14])<<4 | uint16(result[15])>>4; hv != 0 {
+		t.Errorf("Expected horizontal velocity 0, but got: %d", hv)
+	}
+	if vv := uint16(result[15]&0x0F)<<8 | uint16(result[16]); vv != 0xE02 {
+		t.Errorf("Expected vertical velocity -510 (0xE02), but got: 0x%03X", vv)
+	}
+	if result[17] != 192 {
+		t.Errorf("Expected track 270 (192), but got: %d", result[17])
+	}
+	if cs := string(result[19:27]); cs != "TOOLONGC" {
+		t.Errorf("Expected truncated callsign, but got: %q", cs)
+	}
+}
+
+func TestOwnshipGeometricAltitude(t *testing.T) {
+	testCases := []struct {
+		name     string
+		alt      float64
+		vfom     float64
+		expected []byte
+	}{
+		{"Zero", 0, 10, []byte{0x0B, 0x00, 0x00, 0x00, 0x0A}},
+		{"High", 5000, 3, []byte{0x0B, 0x03, 0xE8, 0x00, 0x03}},
+		{"Below Ellipsoid", -100, 3, []byte{0x0B, 0xFF, 0xEC, 0x00, 0x03}},
+		{"No VFOM", 5000, -1, []byte{0x0B, 0x03, 0xE8, 0x7F, 0xFF}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := OwnshipGeometricAltitude(tc.alt, tc.vfom)
+			if !bytes.Equal(result, tc.expected) {
+				t.Errorf("Expected: % X, but got: % X", tc.expected, result)
+			}
+		})
+	}
+}
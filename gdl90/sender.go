@@ -0,0 +1,159 @@
+package gdl90
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// DEFAULT_ADDR is the address EFBs listen for GDL90 on, broadcast to the local network
+const DEFAULT_ADDR = "255.255.255.255:4000"
+
+const (
+	// HEARTBEAT_INTERVAL is how often heartbeats are sent, independent of the position rate
+	HEARTBEAT_INTERVAL = time.Second
+	// AIRBORNE_AGL is the height above the terrain in meters above which the ownship is reported as airborne
+	AIRBORNE_AGL = 5.0
+	// EMITTER_LIGHT is the emitter category of a light aircraft
+	EMITTER_LIGHT = 1
+)
+
+const (
+	feetPerMeter = 3.28084
+	knotsPerMps  = 1.94384
+)
+
+// Logger is the default logger for the gdl90 package
+var Logger = slog.Default()
+
+// Sender is an object that will send positions as GDL90 messages over UDP
+type Sender struct {
+	// Addr is the address to send to, usually the broadcast address on port 4000
+	Addr *net.UDPAddr
+	// Address is the 24 bit ICAO address of the ownship
+	Address uint32
+	// Callsign is the callsign of the ownship, up to 8 characters
+	Callsign string
+}
+
+// NewSender returns a new Sender that sends to addr, eg "255.255.255.255:4000"
+func NewSender(addr string) (*Sender, error) {
+	a, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve GDL90 address %s: %v", addr, err)
+	}
+	return &Sender{Addr: a}, nil
+}
+
+// SendPositions will send a heartbeat every second and an ownship report and geometric altitude for each
+// position from the channel
+func (s *Sender) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
+	Logger.Debug("SendPositions Started", "addr", s.Addr)
+
+	// an unconnected socket, so an EFB that isn't listening doesn't cause write errors
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		Logger.Error("Failed to open UDP socket", "err", err)
+		feedback <- "Failed to open GDL90 socket"
+		return err
+	}
+	defer func() {
+		conn.Close()
+		Logger.Debug("UDP socket closed")
+	}()
+
+	send := func(msg []byte) {
+		if _, err := conn.WriteToUDP(Frame(msg), s.Addr); err != nil {
+			Logger.Warn("Write failed", "err", err)
+			feedback <- "GDL90 write failed"
+		}
+	}
+
+	ticker := time.NewTicker(HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	// the fix of the last position, and when it arrived
+	fix := gnss.FIX_NONE
+	var last time.Time
+	send(Heartbeat(time.Now(), false))
+	for {
+		select {
+		case now := <-ticker.C:
+			send(Heartbeat(now, positionValid(fix, last, now)))
+		case pos, ok := <-c:
+			if !ok {
+				return nil
+			}
+			fix, last = pos.Quality.Fix, time.Now()
+			send(OwnshipReport(s.ownship(pos)))
+			send(OwnshipGeometricAltitude(ellipsoidAltitude(pos), 3))
+		}
+	}
+}
+
+// positionValid returns whether the GPS position is valid at now, for the heartbeat
+// It is valid if the last position, which arrived at last, had a fix and is no older than a heartbeat interval.
+func positionValid(fix gnss.Fix, last time.Time, now time.Time) bool {
+	return fix.Valid() && !last.IsZero() && now.Sub(last) <= HEARTBEAT_INTERVAL
+}
+
+// ownship returns the ownship state for a position
+func (s *Sender) ownship(p xplane.Position) Ownship {
+	return Ownship{
+		Address:       s.Address,
+		Lat:           p.Dat_lat,
+		Lon:           p.Dat_lon,
+		Altitude:      p.Dat_ele * feetPerMeter,
+		Airborne:      p.Y_agl_mtr > AIRBORNE_AGL,
+		NIC:           nic(p.Quality),
+		NACp:          nacp(p.Quality),
+		GroundSpeed:   p.SOG() * knotsPerMps,
+		VerticalSpeed: float64(p.Vy_wrl) * feetPerMeter * 60,
+		Track:         p.Track(),
+		Emitter:       EMITTER_LIGHT,
+		Callsign:      s.Callsign,
+	}
+}
+
+// nic returns the navigation integrity category for the quality of a fix
+// The position is sim truth unless it has been degraded, so it gets the best integrity while there is a
+// satellite fix, and none without one
+func nic(q gnss.Quality) uint8 {
+	if q.Fix == gnss.FIX_DR || q.Fix == gnss.FIX_NONE {
+		return 0
+	}
+	return 11
+}
+
+// nacp returns the navigation accuracy category for the quality of a fix
+// The category bounds the 95% horizontal error, which is taken as twice the 1 sigma accuracy. Without an
+// estimate of the quality, the position is sim truth and gets the best category.
+func nacp(q gnss.Quality) uint8 {
+	if q.Fix == gnss.FIX_NONE {
+		return 0
+	}
+	if q.HAcc == 0 {
+		return 11
+	}
+	epu := 2 * q.HAcc
+	for _, c := range []struct {
+		limit float64
+		nacp  uint8
+	}{{3, 11}, {10, 10}, {30, 9}, {92.6, 8}, {185.2, 7}, {555.6, 6}, {926, 5}, {1852, 4}, {3704, 3},
+		{7408, 2}, {18520, 1}} {
+		if epu < c.limit {
+			return c.nacp
+		}
+	}
+	return 0
+}
+
+// ellipsoidAltitude returns the height above the WGS84 ellipsoid in feet
+func ellipsoidAltitude(p xplane.Position) float64 {
+	return (p.Dat_ele + geoid.Separation(p.Dat_lat, p.Dat_lon)) * feetPerMeter
+}
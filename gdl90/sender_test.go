@@ -0,0 +1,128 @@
+package gdl90
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestSendPositions(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+	defer conn.Close()
+
+	s := &Sender{Addr: conn.LocalAddr().(*net.UDPAddr), Address: 0xABCDEF, Callsign: "XPLANE"}
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 1000, Y_agl_mtr: 500, Vx_wrl: 50}
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	expected := []byte{HEARTBEAT, OWNSHIP_REPORT, OWNSHIP_GEOMETRIC_ALTITUDE}
+	buf := make([]byte, 1500)
+	for _, id := range expected {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("Expected message 0x%02X, but got: %v", id, err)
+		}
+		frame := buf[:n]
+		if frame[0] != flagByte || frame[n-1] != flagByte {
+			t.Fatalf("Expected a framed message, but got: % X", frame)
+		}
+		if frame[1] != id {
+			t.Errorf("Expected message 0x%02X, but got: 0x%02X", id, frame[1])
+		}
+		if id == OWNSHIP_REPORT && !bytes.Contains(frame, []byte("XPLANE  ")) {
+			t.Errorf("Expected callsign in ownship report, but got: % X", frame)
+		}
+	}
+}
+
+func TestOwnship(t *testing.T) {
+	s := &Sender{Address: 0xABCDEF, Callsign: "XPLANE"}
+	o := s.ownship(xplane.Position{
+		Dat_lat:   45,
+		Dat_lon:   -75,
+		Dat_ele:   1000,
+		Y_agl_mtr: 500,
+		Vx_wrl:    -50,
+		Vy_wrl:    5,
+	})
+
+	if !o.Airborne {
+		t.Errorf("Expected airborne")
+	}
+	if o.Altitude < 3280 || o.Altitude > 3281 {
+		t.Errorf("Expected altitude 3280.84, but got: %f", o.Altitude)
+	}
+	if o.GroundSpeed < 97.1 || o.GroundSpeed > 97.2 {
+		t.Errorf("Expected ground speed 97.19, but got: %f", o.GroundSpeed)
+	}
+	if o.VerticalSpeed < 984 || o.VerticalSpeed > 985 {
+		t.Errorf("Expected vertical speed 984.25, but got: %f", o.VerticalSpeed)
+	}
+	if o.Track != 270 {
+		t.Errorf("Expected track 270, but got: %f", o.Track)
+	}
+}
+
+func TestNACp(t *testing.T) {
+	testCases := []struct {
+		name string
+		hAcc float64
+		want uint8
+	}{
+		{"Sim truth", 0, 11},
+		{"Nominal", 1, 11},
+		{"Consumer", 2.5, 10},
+		{"Multipath", 12, 9},
+		{"Poor", 40, 8},
+		{"Lost", 1000, 3},
+		{"Drifting", 5000, 1},
+		{"Unbounded", 10000, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nacp(gnss.Quality{HAcc: tc.hAcc}); got != tc.want {
+				t.Errorf("Expected: %d, but got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPositionValid(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name string
+		fix  gnss.Fix
+		last time.Time
+		want bool
+	}{
+		{"No Position", gnss.FIX_3D, time.Time{}, false},
+		{"Fresh", gnss.FIX_3D, now.Add(-200 * time.Millisecond), true},
+		{"Dead Reckoning", gnss.FIX_DR, now.Add(-200 * time.Millisecond), true},
+		{"No Fix", gnss.FIX_NONE, now.Add(-200 * time.Millisecond), false},
+		{"Stale", gnss.FIX_3D, now.Add(-2 * HEARTBEAT_INTERVAL), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := positionValid(tc.fix, tc.last, now); got != tc.want {
+				t.Errorf("Expected: %v, but got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package geoid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeparation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lat, lon float64
+		expected float64
+		within   float64
+	}{
+		// grid points are exact
+		{"Grid Point", 0, 80, -102, 0},
+		{"South Pole", -90, 45, -30, 0},
+		{"North Pole", 90, -45, 13, 0},
+		{"Date Line East", 0, 180, 22, 0},
+		{"Date Line West", 0, -180, 22, 0},
+		{"Wrapped Longitude", 0, 440, -102, 0},
+		{"Midpoint", 5, 85, (-102 - 63 - 95 - 63) / 4.0, 1e-9},
+
+		// EGM96 values for a few places, which the coarse grid should be close to
+		{"Ottawa", 45.42, -75.70, -35, 5},
+		{"London", 51.51, -0.13, 46, 5},
+		{"Sydney", -33.87, 151.21, 22, 5},
+		{"Sri Lanka", 5, 80, -100, 5},
+		{"New Guinea", -5, 140, 72, 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Separation(tc.lat, tc.lon)
+			if math.Abs(result-tc.expected) > tc.within {
+				t.Errorf("Expected: %.2f, but got: %.2f", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package gnss
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// ELEVATION_MASK is the elevation in degrees above which satellites are visible
+	ELEVATION_MASK = 5.0
+	// USE_MASK is the elevation in degrees above which satellites are used in the fix
+	USE_MASK = 10.0
+	// MAX_USED is the most satellites used in a fix, as many receivers only report 12
+	MAX_USED = 12
+	// USE_SNR is the weakest signal in dB-Hz that is used in the fix
+	USE_SNR = 25.0
+	// TRACK_SNR is the weakest signal in dB-Hz that is tracked at all
+	TRACK_SNR = 20.0
+)
+
+const (
+	// GPS orbits are circular with a semi-major axis of 26559.7km, inclined at 55 degrees
+	orbitRadius      = 26559700.0
+	orbitInclination = 55.0 * math.Pi / 180
+	// earth's gravitational constant and rotation rate from WGS84
+	mu     = 3.986005e14
+	omegaE = 7.2921151467e-5
+	// WGS84 ellipsoid
+	wgs84A  = 6378137.0
+	wgs84E2 = 6.69437999014e-3
+)
+
+// epoch is the reference time of the nominal constellation
+var epoch = time.Date(1993, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+// slot is a slot in the nominal constellation, with the longitude of the ascending node and argument of
+// latitude at the epoch in degrees
+type slot struct {
+	raan float64
+	u    float64
+}
+
+// slots is the baseline 24 slot GPS constellation from the GPS SPS Performance Standard, with six planes
+// (A to F) of four slots each. PRNs are assigned to the slots in order.
+var slots = [...]slot{
+	{272.847, 268.126}, {272.847, 161.786}, {272.847, 11.676}, {272.847, 41.806},
+	{332.847, 80.956}, {332.847, 173.336}, {332.847, 309.976}, {332.847, 204.376},
+	{32.847, 111.876}, {32.847, 11.796}, {32.847, 339.666}, {32.847, 241.556},
+	{92.847, 135.226}, {92.847, 265.446}, {92.847, 35.156}, {92.847, 167.356},
+	{152.847, 197.046}, {152.847, 302.596}, {152.847, 66.066}, {152.847, 333.686},
+	{212.847, 238.886}, {212.847, 345.226}, {212.847, 105.206}, {212.847, 135.346},
+}
+
+// Satellite is a satellite as seen from a receiver
+type Satellite struct {
+	PRN       uint8
+	Elevation float64 // degrees above the horizon
+	Azimuth   float64 // degrees true, [0, 360)
+	SNR       float64 // carrier to noise density in dB-Hz
+	Used      bool    // used in the fix
+}
+
+// Visible returns the satellites of a nominal GPS constellation visible from lat, lon (in degrees) and alt
+// (in meters) at t, sorted by PRN
+// This is an idealised constellation, not the real ephemeris, but it gives plausible and consistent sky
+// views for GSV, NAV-SAT and similar outputs
+func Visible(lat, lon, alt float64, t time.Time) []Satellite {
+	rx, ry, rz := ecef(lat, lon, alt)
+	phi := lat * math.Pi / 180
+	lambda := lon * math.Pi / 180
+
+	var sats []Satellite
+	for i, s := range slots {
+		sx, sy, sz := s.position(t)
+		dx, dy, dz := sx-rx, sy-ry, sz-rz
+
+		// rotate into east, north, up
+		e := -math.Sin(lambda)*dx + math.Cos(lambda)*dy
+		n := -math.Sin(phi)*math.Cos(lambda)*dx - math.Sin(phi)*math.Sin(lambda)*dy + math.Cos(phi)*dz
+		u := math.Cos(phi)*math.Cos(lambda)*dx + math.Cos(phi)*math.Sin(lambda)*dy + math.Sin(phi)*dz
+
+		el := math.Atan2(u, math.Hypot(e, n)) * 180 / math.Pi
+		if el < ELEVATION_MASK {
+			continue
+		}
+		az := math.Mod(math.Atan2(e, n)*180/math.Pi+360, 360)
+		sats = append(sats, Satellite{
+			PRN:       uint8(i + 1),
+			Elevation: el,
+			Azimuth:   az,
+			SNR:       snr(el),
+		})
+	}
+
+	// use the highest satellites
+	byElevation := make([]*Satellite, len(sats))
+	for i := range sats {
+		byElevation[i] = &sats[i]
+	}
+	sort.Slice(byElevation, func(i, j int) bool { return byElevation[i].Elevation > byElevation[j].Elevation })
+	for i, s := range byElevation {
+		if i >= MAX_USED || s.Elevation < USE_MASK {
+			break
+		}
+		s.Used = true
+	}
+	return sats
+}
+
+// Used returns the number of satellites used in the fix
+func Used(sats []Satellite) int {
+	n := 0
+	for _, s := range sats {
+		if s.Used {
+			n++
+		}
+	}
+	return n
+}
+
+// position returns the ECEF position of the satellite in the slot at t
+func (s slot) position(t time.Time) (x, y, z float64) {
+	dt := t.Sub(epoch).Seconds()
+	n := math.Sqrt(mu / (orbitRadius * orbitRadius * orbitRadius))
+
+	u := s.u*math.Pi/180 + n*dt
+	raan := s.raan*math.Pi/180 - omegaE*dt
+
+	// position in the orbital plane, rotated by the inclination and the node
+	xp, yp := orbitRadius*math.Cos(u), orbitRadius*math.Sin(u)
+	x = xp*math.Cos(raan) - yp*math.Cos(orbitInclination)*math.Sin(raan)
+	y = xp*math.Sin(raan) + yp*math.Cos(orbitInclination)*math.Cos(raan)
+	z = yp * math.Sin(orbitInclination)
+	return x, y, z
+}
+
+// ecef returns the ECEF position of lat, lon (in degrees) and alt (in meters above the ellipsoid)
+func ecef(lat, lon, alt float64) (x, y, z float64) {
+	phi := lat * math.Pi / 180
+	lambda := lon * math.Pi / 180
+	n := wgs84A / math.Sqrt(1-wgs84E2*math.Sin(phi)*math.Sin(phi))
+	x = (n + alt) * math.Cos(phi) * math.Cos(lambda)
+	y = (n + alt) * math.Cos(phi) * math.Sin(lambda)
+	z = (n*(1-wgs84E2) + alt) * math.Sin(phi)
+	return x, y, z
+}
+
+// snr returns a typical carrier to noise density for a satellite at an elevation, from 25 dB-Hz at the
+// horizon to 50 dB-Hz overhead
+func snr(el float64) float64 {
+	return math.Round(25 + 25*math.Sin(el*math.Pi/180))
+}
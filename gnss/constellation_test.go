@@ -0,0 +1,88 @@
+package gnss
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVisible(t *testing.T) {
+	ts := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"Ottawa", 45.42, -75.70},
+		{"Equator", 0, 0},
+		{"South Pole", -89.9, 0},
+		{"Tokyo", 35.68, 139.69},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sats := Visible(tc.lat, tc.lon, 0, ts)
+			if len(sats) < 4 || len(sats) > len(slots) {
+				t.Fatalf("Expected 4 to %d visible satellites, but got: %d", len(slots), len(sats))
+			}
+			used := Used(sats)
+			if used < 4 || used > MAX_USED {
+				t.Errorf("Expected 4 to %d used satellites, but got: %d", MAX_USED, used)
+			}
+			for i, s := range sats {
+				if i > 0 && s.PRN <= sats[i-1].PRN {
+					t.Errorf("Expected satellites sorted by PRN, but got: %d after %d", s.PRN, sats[i-1].PRN)
+				}
+				if s.Elevation < ELEVATION_MASK || s.Elevation > 90 {
+					t.Errorf("PRN %d: Expected elevation above the mask, but got: %f", s.PRN, s.Elevation)
+				}
+				if s.Azimuth < 0 || s.Azimuth >= 360 {
+					t.Errorf("PRN %d: Expected azimuth in [0, 360), but got: %f", s.PRN, s.Azimuth)
+				}
+				if s.Used && s.Elevation < USE_MASK {
+					t.Errorf("PRN %d: Expected unused below the use mask, but got used at %f", s.PRN, s.Elevation)
+				}
+				if s.SNR < 25 || s.SNR > 50 {
+					t.Errorf("PRN %d: Expected SNR from 25 to 50, but got: %f", s.PRN, s.SNR)
+				}
+			}
+		})
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	ts := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	// a receiver directly below a satellite sees it at 90 degrees
+	x, y, z := slots[0].position(ts)
+	lat := math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+	lon := math.Atan2(y, x) * 180 / math.Pi
+
+	for _, s := range Visible(lat, lon, 0, ts) {
+		if s.PRN != 1 {
+			continue
+		}
+		// geocentric and geodetic latitude differ slightly, so allow a little error
+		if s.Elevation < 89.5 {
+			t.Errorf("Expected elevation of 90, but got: %f", s.Elevation)
+		}
+		return
+	}
+	t.Errorf("Expected PRN 1 to be visible")
+}
+
+func TestPeriod(t *testing.T) {
+	// GPS satellites repeat their ground track every sidereal day, which is two orbits
+	ts := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	sidereal := 86164*time.Second + 90*time.Millisecond
+
+	a := Visible(45, -75, 0, ts)
+	b := Visible(45, -75, 0, ts.Add(sidereal))
+	if len(a) != len(b) {
+		t.Fatalf("Expected the same sky view, but got %d and %d satellites", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].PRN != b[i].PRN || math.Abs(a[i].Elevation-b[i].Elevation) > 1 || math.Abs(a[i].Azimuth-b[i].Azimuth) > 1 {
+			t.Errorf("Expected the same sky view, but got: %+v and %+v", a[i], b[i])
+		}
+	}
+}
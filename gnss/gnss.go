@@ -0,0 +1,28 @@
+package gnss
+
+import "time"
+
+// Nominal quality of a fix of sim truth, for outputs that report accuracy estimates
+const (
+	NOMINAL_HDOP    = 0.5
+	NOMINAL_VDOP    = 0.8
+	NOMINAL_PDOP    = 1.0
+	NOMINAL_HACC    = 1.0   // horizontal accuracy in meters
+	NOMINAL_VACC    = 1.5   // vertical accuracy in meters
+	NOMINAL_SACC    = 0.1   // speed accuracy in m/s
+	NOMINAL_HEADACC = 0.5   // heading accuracy in degrees
+	NOMINAL_TACC    = 20e-9 // time accuracy in seconds
+)
+
+// LEAP_SECONDS is how far GPS time is ahead of UTC
+const LEAP_SECONDS = 18 * time.Second
+
+// gpsEpoch is the start of GPS time
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// GPSTime returns the GPS week number and the time of week for a UTC time
+func GPSTime(t time.Time) (week int, tow time.Duration) {
+	gps := t.UTC().Add(LEAP_SECONDS).Sub(gpsEpoch)
+	const w = 7 * 24 * time.Hour
+	return int(gps / w), gps % w
+}
@@ -0,0 +1,29 @@
+package gnss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGPSTime(t *testing.T) {
+	testCases := []struct {
+		name string
+		t    time.Time
+		week int
+		tow  time.Duration
+	}{
+		{"Epoch", time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC).Add(-LEAP_SECONDS), 0, 0},
+		// 1 January 2022 was a Saturday in GPS week 2190
+		{"Saturday", time.Date(2022, time.January, 1, 0, 0, 1, 500000000, time.UTC), 2190, 6*24*time.Hour + 19500*time.Millisecond},
+		{"Week Rollover", time.Date(2022, time.January, 1, 23, 59, 50, 0, time.UTC), 2191, 8 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			week, tow := GPSTime(tc.t)
+			if week != tc.week || tow != tc.tow {
+				t.Errorf("Expected: week %d %v, but got: week %d %v", tc.week, tc.tow, week, tow)
+			}
+		})
+	}
+}
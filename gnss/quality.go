@@ -0,0 +1,140 @@
+package gnss
+
+import (
+	"fmt"
+	"math"
+)
+
+// UERE is the nominal user equivalent range error in meters, which relates the accuracy of a fix to its
+// dilution of precision
+const UERE = NOMINAL_HACC / NOMINAL_HDOP
+
+// Fix is the type of fix a receiver has
+type Fix uint8
+
+// Possible fix types
+// The zero value is a full 3D fix, so positions that nothing has degraded have one
+const (
+	// FIX_3D is a position and altitude from the satellites
+	FIX_3D Fix = iota
+	// FIX_2D is a position from the satellites with an assumed altitude, when too few are in view for a 3D fix
+	FIX_2D
+	// FIX_DR is a position dead reckoned from the last fix, after the satellites have been lost
+	FIX_DR
+	// FIX_NONE is no fix at all, and the position is the last one known
+	FIX_NONE
+)
+
+// String returns the name of the fix type
+func (f Fix) String() string {
+	switch f {
+	case FIX_3D:
+		return "3D"
+	case FIX_2D:
+		return "2D"
+	case FIX_DR:
+		return "DR"
+	case FIX_NONE:
+		return "No Fix"
+	}
+	return fmt.Sprintf("Fix(%d)", uint8(f))
+}
+
+// Valid returns whether the fix has a usable position, which a dead reckoned fix does
+func (f Fix) Valid() bool {
+	return f != FIX_NONE
+}
+
+// Quality is the quality of a fix, as a receiver would estimate it
+// Zero fields are not known, and OrNominal fills them in with the nominal values for a fix of sim truth
+type Quality struct {
+	Fix Fix
+	// Jamming is how far the noise floor has risen in dB, which lowers the SNR of every satellite
+	Jamming float64
+	HDOP    float64
+	VDOP    float64
+	PDOP    float64
+	HAcc    float64 // horizontal accuracy in meters
+	VAcc    float64 // vertical accuracy in meters
+	SAcc    float64 // speed accuracy in m/s
+}
+
+// Satellites returns how many of the used satellites are used in the fix
+// A 2D fix only has 3 satellites, and a dead reckoned fix or no fix has none
+func (q Quality) Satellites(used int) int {
+	switch q.Fix {
+	case FIX_2D:
+		return min(used, 3)
+	case FIX_DR, FIX_NONE:
+		return 0
+	}
+	return used
+}
+
+// Sky returns the satellites as the receiver sees them with this quality
+// Jamming lowers the SNR of the satellites, so weak ones are no longer used or tracked, and fewer are used in
+// a degraded fix
+func (q Quality) Sky(sats []Satellite) []Satellite {
+	seen := make([]Satellite, len(sats))
+	used := 0
+	for i, s := range sats {
+		s.SNR = math.Max(0, s.SNR-q.Jamming)
+		if s.SNR < USE_SNR {
+			s.Used = false
+		}
+		if s.SNR < TRACK_SNR {
+			s.SNR = 0
+		}
+		if s.Used {
+			used++
+		}
+		seen[i] = s
+	}
+
+	limit := q.Satellites(used)
+	for i := range seen {
+		if seen[i].Used {
+			seen[i].Used = limit > 0
+			limit--
+		}
+	}
+	return seen
+}
+
+// OrNominal returns the quality with the fields that are not known set to the nominal values
+func (q Quality) OrNominal() Quality {
+	if q.HDOP == 0 {
+		q.HDOP = NOMINAL_HDOP
+	}
+	if q.VDOP == 0 {
+		q.VDOP = NOMINAL_VDOP
+	}
+	if q.PDOP == 0 {
+		q.PDOP = NOMINAL_PDOP
+	}
+	if q.HAcc == 0 {
+		q.HAcc = NOMINAL_HACC
+	}
+	if q.VAcc == 0 {
+		q.VAcc = NOMINAL_VACC
+	}
+	if q.SAcc == 0 {
+		q.SAcc = NOMINAL_SACC
+	}
+	return q
+}
+
+// QualityFor returns the quality matching horizontal and vertical accuracies in meters and a speed accuracy
+// in m/s, with the dilutions of precision derived from the UERE
+// A DOP is never reported better than nominal, as the constellation doesn't get better with a worse fix
+func QualityFor(hAcc, vAcc, sAcc float64) Quality {
+	q := Quality{
+		HDOP: math.Max(NOMINAL_HDOP, hAcc/UERE),
+		VDOP: math.Max(NOMINAL_VDOP, vAcc/UERE),
+		HAcc: math.Max(NOMINAL_HACC, hAcc),
+		VAcc: math.Max(NOMINAL_VACC, vAcc),
+		SAcc: math.Max(NOMINAL_SACC, sAcc),
+	}
+	q.PDOP = math.Max(NOMINAL_PDOP, math.Hypot(q.HDOP, q.VDOP))
+	return q
+}
@@ -0,0 +1,246 @@
+package gpsd
+
+import (
+	"math"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/wmm"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// The version of gpsd and its protocol the server claims to be
+const (
+	RELEASE        = "3.25"
+	PROTO_MAJOR    = 3
+	PROTO_MINOR    = 15
+	DEFAULT_DEVICE = "xplane"
+)
+
+// TPV modes
+const (
+	MODE_NO_FIX = 1
+	MODE_2D     = 2
+	MODE_3D     = 3
+)
+
+// STATUS_DR is the TPV status of a dead reckoned fix
+const STATUS_DR = 6
+
+// TIME_FORMAT is the ISO 8601 format gpsd uses for times, in UTC with milliseconds
+const TIME_FORMAT = "2006-01-02T15:04:05.000Z"
+
+// Version is the VERSION report, sent when a client connects
+type Version struct {
+	Class      string `json:"class"`
+	Release    string `json:"release"`
+	Rev        string `json:"rev"`
+	ProtoMajor int    `json:"proto_major"`
+	ProtoMinor int    `json:"proto_minor"`
+}
+
+// Device is a DEVICE object, describing a device the server reads from
+type Device struct {
+	Class     string  `json:"class"`
+	Path      string  `json:"path"`
+	Activated string  `json:"activated,omitempty"`
+	Driver    string  `json:"driver,omitempty"`
+	Flags     int     `json:"flags,omitempty"`
+	Cycle     float64 `json:"cycle,omitempty"`
+}
+
+// Devices is the DEVICES report, listing the devices
+type Devices struct {
+	Class   string   `json:"class"`
+	Devices []Device `json:"devices"`
+}
+
+// Watch is the WATCH report and the body of the ?WATCH command
+// The pointers are nil when a command doesn't set them
+type Watch struct {
+	Class  string `json:"class,omitempty"`
+	Enable *bool  `json:"enable,omitempty"`
+	JSON   *bool  `json:"json,omitempty"`
+	NMEA   *bool  `json:"nmea,omitempty"`
+	Raw    int    `json:"raw,omitempty"`
+	Scaled *bool  `json:"scaled,omitempty"`
+	Split  *bool  `json:"split24,omitempty"`
+	PPS    *bool  `json:"pps,omitempty"`
+	Device string `json:"device,omitempty"`
+}
+
+// TPV is the time-position-velocity report
+type TPV struct {
+	Class       string  `json:"class"`
+	Device      string  `json:"device"`
+	Mode        int     `json:"mode"`
+	Status      int     `json:"status,omitempty"`
+	Time        string  `json:"time"`
+	LeapSeconds int     `json:"leapseconds"`
+	Ept         float64 `json:"ept"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Alt         float64 `json:"alt"`
+	AltHAE      float64 `json:"altHAE"`
+	AltMSL      float64 `json:"altMSL"`
+	GeoidSep    float64 `json:"geoidSep"`
+	Epx         float64 `json:"epx"`
+	Epy         float64 `json:"epy"`
+	Epv         float64 `json:"epv"`
+	Track       float64 `json:"track"`
+	MagTrack    float64 `json:"magtrack"`
+	MagVar      float64 `json:"magvar"`
+	Speed       float64 `json:"speed"`
+	Climb       float64 `json:"climb"`
+	Eps         float64 `json:"eps"`
+	VelN        float64 `json:"velN"`
+	VelE        float64 `json:"velE"`
+	VelD        float64 `json:"velD"`
+}
+
+// Satellite is a satellite in a SKY report
+type Satellite struct {
+	PRN    int     `json:"PRN"`
+	GNSSID int     `json:"gnssid"`
+	SVID   int     `json:"svid"`
+	El     float64 `json:"el"`
+	Az     float64 `json:"az"`
+	SS     float64 `json:"ss"`
+	Used   bool    `json:"used"`
+}
+
+// Sky is the SKY report, with the satellites in view
+type Sky struct {
+	Class      string      `json:"class"`
+	Device     string      `json:"device"`
+	Time       string      `json:"time"`
+	HDOP       float64     `json:"hdop"`
+	VDOP       float64     `json:"vdop"`
+	PDOP       float64     `json:"pdop"`
+	NSat       int         `json:"nSat"`
+	USat       int         `json:"uSat"`
+	Satellites []Satellite `json:"satellites"`
+}
+
+// Att is the ATT report, with the attitude of the vehicle
+type Att struct {
+	Class    string  `json:"class"`
+	Device   string  `json:"device"`
+	Time     string  `json:"time"`
+	Heading  float64 `json:"heading"`
+	MHeading float64 `json:"mheading"`
+	Pitch    float64 `json:"pitch"`
+	Roll     float64 `json:"roll"`
+	GyroX    float64 `json:"gyro_x"`
+	GyroY    float64 `json:"gyro_y"`
+	GyroZ    float64 `json:"gyro_z"`
+}
+
+// Poll is the POLL report, with the latest TPV and SKY reports
+type Poll struct {
+	Class  string `json:"class"`
+	Time   string `json:"time"`
+	Active int    `json:"active"`
+	TPV    []TPV  `json:"tpv"`
+	Sky    []Sky  `json:"sky"`
+}
+
+// Error is the ERROR report, sent in reply to a command the server doesn't understand
+type Error struct {
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+// NewTPV returns the TPV report for a position at t from device
+func NewTPV(device string, p xplane.Position, t time.Time) TPV {
+	sep := geoid.Separation(p.Dat_lat, p.Dat_lon)
+	track := p.Track()
+	magvar := wmm.Declination(p.Dat_lat, p.Dat_lon, p.Dat_ele, t)
+	q := p.Quality.OrNominal()
+	mode, status := MODE_3D, 0
+	switch q.Fix {
+	case gnss.FIX_2D:
+		mode = MODE_2D
+	case gnss.FIX_DR:
+		status = STATUS_DR
+	case gnss.FIX_NONE:
+		mode = MODE_NO_FIX
+	}
+	return TPV{
+		Class:       "TPV",
+		Device:      device,
+		Mode:        mode,
+		Status:      status,
+		Time:        t.UTC().Format(TIME_FORMAT),
+		LeapSeconds: int(gnss.LEAP_SECONDS / time.Second),
+		Ept:         gnss.NOMINAL_TACC,
+		Lat:         p.Dat_lat,
+		Lon:         p.Dat_lon,
+		Alt:         p.Dat_ele,
+		AltHAE:      p.Dat_ele + sep,
+		AltMSL:      p.Dat_ele,
+		GeoidSep:    sep,
+		Epx:         q.HAcc,
+		Epy:         q.HAcc,
+		Epv:         q.VAcc,
+		Track:       track,
+		MagTrack:    math.Mod(track-magvar+360, 360),
+		MagVar:      magvar,
+		Speed:       p.SOG(),
+		Climb:       float64(p.Vy_wrl),
+		Eps:         q.SAcc,
+		// X-Plane's velocities are east, up and south
+		VelN: -float64(p.Vz_wrl),
+		VelE: float64(p.Vx_wrl),
+		VelD: -float64(p.Vy_wrl),
+	}
+}
+
+// NewSky returns the SKY report for the satellites visible from a position at t from device
+func NewSky(device string, p xplane.Position, t time.Time) Sky {
+	q := p.Quality.OrNominal()
+	// a jammed or degraded fix sees weaker signals and uses fewer of the satellites
+	visible := p.Sky(t)
+	sats := make([]Satellite, len(visible))
+	for i, s := range visible {
+		sats[i] = Satellite{
+			PRN:  int(s.PRN),
+			SVID: int(s.PRN),
+			El:   math.Round(s.Elevation),
+			Az:   math.Round(s.Azimuth),
+			SS:   math.Round(s.SNR),
+			Used: s.Used,
+		}
+	}
+	return Sky{
+		Class:      "SKY",
+		Device:     device,
+		Time:       t.UTC().Format(TIME_FORMAT),
+		HDOP:       q.HDOP,
+		VDOP:       q.VDOP,
+		PDOP:       q.PDOP,
+		NSat:       len(sats),
+		USat:       gnss.Used(visible),
+		Satellites: sats,
+	}
+}
+
+// NewAtt returns the ATT report for a position at t from device
+func NewAtt(device string, p xplane.Position, t time.Time) Att {
+	heading := math.Mod(float64(p.Veh_psi_loc)+360, 360)
+	magvar := wmm.Declination(p.Dat_lat, p.Dat_lon, p.Dat_ele, t)
+	const degPerRad = 180 / math.Pi
+	return Att{
+		Class:    "ATT",
+		Device:   device,
+		Time:     t.UTC().Format(TIME_FORMAT),
+		Heading:  heading,
+		MHeading: math.Mod(heading-magvar+360, 360),
+		Pitch:    float64(p.Veh_the_loc),
+		Roll:     float64(p.Veh_phi_loc),
+		GyroX:    float64(p.Prad) * degPerRad,
+		GyroY:    float64(p.Qrad) * degPerRad,
+		GyroZ:    float64(p.Rrad) * degPerRad,
+	}
+}
@@ -0,0 +1,82 @@
+package gpsd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestNewTPV(t *testing.T) {
+	ts := time.Date(2022, time.January, 1, 12, 0, 0, 500000000, time.UTC)
+	tpv := NewTPV("xplane", xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 1000, Vx_wrl: -50, Vy_wrl: 5}, ts)
+
+	if tpv.Time != "2022-01-01T12:00:00.500Z" {
+		t.Errorf("Expected: %s, but got: %s", "2022-01-01T12:00:00.500Z", tpv.Time)
+	}
+	if tpv.Mode != MODE_3D {
+		t.Errorf("Expected mode: %d, but got: %d", MODE_3D, tpv.Mode)
+	}
+	if tpv.Track != 270 {
+		t.Errorf("Expected track: 270, but got: %f", tpv.Track)
+	}
+	if tpv.Speed != 50 || tpv.Climb != 5 || tpv.VelE != -50 || tpv.VelD != -5 {
+		t.Errorf("Expected speed 50, climb 5, velE -50 and velD -5, but got: %f, %f, %f and %f", tpv.Speed, tpv.Climb, tpv.VelE, tpv.VelD)
+	}
+	if tpv.AltHAE != tpv.AltMSL+tpv.GeoidSep {
+		t.Errorf("Expected altHAE %f, but got: %f", tpv.AltMSL+tpv.GeoidSep, tpv.AltHAE)
+	}
+	// the variation near Ottawa is about 12 degrees west
+	if tpv.MagVar > -10 || tpv.MagVar < -15 {
+		t.Errorf("Expected variation around -12, but got: %f", tpv.MagVar)
+	}
+
+	bs, err := json.Marshal(tpv)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	for _, field := range []string{`"class":"TPV"`, `"device":"xplane"`, `"lat":45`, `"lon":-75`, `"altMSL":1000`} {
+		if !strings.Contains(string(bs), field) {
+			t.Errorf("Expected %s in %s", field, bs)
+		}
+	}
+}
+
+func TestNewSky(t *testing.T) {
+	sky := NewSky("xplane", xplane.Position{Dat_lat: 45, Dat_lon: -75}, time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	if sky.NSat != len(sky.Satellites) {
+		t.Errorf("Expected nSat: %d, but got: %d", len(sky.Satellites), sky.NSat)
+	}
+	if sky.NSat < 4 {
+		t.Errorf("Expected at least 4 satellites, but got: %d", sky.NSat)
+	}
+	used := 0
+	for _, s := range sky.Satellites {
+		if s.Used {
+			used++
+		}
+		if s.PRN != s.SVID {
+			t.Errorf("Expected svid %d, but got: %d", s.PRN, s.SVID)
+		}
+	}
+	if used != sky.USat {
+		t.Errorf("Expected uSat: %d, but got: %d", used, sky.USat)
+	}
+}
+
+func TestNewAtt(t *testing.T) {
+	att := NewAtt("xplane", xplane.Position{Veh_psi_loc: -90, Veh_the_loc: 5, Veh_phi_loc: -10, Rrad: 0.1}, time.Now())
+
+	if att.Heading != 270 {
+		t.Errorf("Expected heading: 270, but got: %f", att.Heading)
+	}
+	if att.Pitch != 5 || att.Roll != -10 {
+		t.Errorf("Expected pitch 5 and roll -10, but got: %f and %f", att.Pitch, att.Roll)
+	}
+	if att.GyroZ < 5.72 || att.GyroZ > 5.73 {
+		t.Errorf("Expected gyro_z 5.73, but got: %f", att.GyroZ)
+	}
+}
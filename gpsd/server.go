@@ -0,0 +1,341 @@
+package gpsd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// DEFAULT_ADDR is the address gpsd listens on
+const DEFAULT_ADDR = ":2947"
+
+const (
+	// SKY_INTERVAL is how often SKY reports are sent, as the satellites move slowly
+	SKY_INTERVAL = time.Second
+	// CLIENT_BUFFER is how many reports are queued for a client before reports are dropped
+	CLIENT_BUFFER = 32
+	// WRITE_TIMEOUT is how long a write to a client can block before the client is dropped
+	WRITE_TIMEOUT = 5 * time.Second
+)
+
+// Logger is the default logger for the gpsd package
+var Logger = slog.Default()
+
+// Server is an object that serves positions to clients with the gpsd JSON protocol over TCP
+// Clients that send ?WATCH={"enable":true,"json":true} are sent a TPV and ATT report for each position, and
+// a SKY report every second
+type Server struct {
+	// Addr is the TCP address to listen on, eg ":2947"
+	Addr string
+	// Device is the path reported for the device the positions come from
+	Device string
+
+	mu        sync.Mutex
+	clients   map[*client]struct{}
+	stopped   bool
+	activated time.Time
+	tpv       *TPV
+	sky       *Sky
+}
+
+// NewServer returns a new Server that listens on addr, eg ":2947"
+func NewServer(addr string) *Server {
+	return &Server{Addr: addr, Device: DEFAULT_DEVICE}
+}
+
+// SendPositions will listen for clients and send them the positions from the channel
+func (s *Server) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		Logger.Error("Failed to listen", "addr", s.Addr, "err", err)
+		feedback <- "Failed to start gpsd server"
+		return fmt.Errorf("could not listen on %s: %v", s.Addr, err)
+	}
+	return s.Serve(ln, c, feedback)
+}
+
+// Serve will accept clients from ln and send them the positions from the channel until it is closed
+func (s *Server) Serve(ln net.Listener, c <-chan xplane.Position, feedback chan<- string) error {
+	Logger.Debug("Serve Started", "addr", ln.Addr())
+
+	s.mu.Lock()
+	s.clients = make(map[*client]struct{})
+	s.stopped = false
+	s.activated = time.Now().UTC()
+	s.tpv, s.sky = nil, nil
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.accept(ln, &wg)
+	}()
+	defer func() {
+		ln.Close()
+		s.mu.Lock()
+		s.stopped = true
+		for cl := range s.clients {
+			cl.close()
+		}
+		s.mu.Unlock()
+		wg.Wait()
+		Logger.Debug("gpsd server closed")
+	}()
+
+	var lastSky time.Time
+	for pos := range c {
+		t := pos.FixTime()
+		tpv := NewTPV(s.Device, pos, t)
+		att := NewAtt(s.Device, pos, t)
+		var sky *Sky
+		if t.Sub(lastSky) >= SKY_INTERVAL {
+			sk := NewSky(s.Device, pos, t)
+			sky = &sk
+			lastSky = t
+		}
+
+		s.mu.Lock()
+		s.tpv = &tpv
+		if sky != nil {
+			s.sky = sky
+		}
+		for cl := range s.clients {
+			if !cl.watching() {
+				continue
+			}
+			cl.send(tpv)
+			if sky != nil {
+				cl.send(sky)
+			}
+			cl.send(att)
+		}
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// accept will accept clients from ln until it is closed, adding each client's goroutines to wg
+func (s *Server) accept(ln net.Listener, wg *sync.WaitGroup) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			Logger.Debug("Accept stopped", "err", err)
+			return
+		}
+		Logger.Info("Client connected", "addr", conn.RemoteAddr())
+
+		cl := newClient(conn)
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[cl] = struct{}{}
+		s.mu.Unlock()
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cl.write()
+		}()
+		go func() {
+			defer wg.Done()
+			s.read(cl)
+			s.mu.Lock()
+			delete(s.clients, cl)
+			s.mu.Unlock()
+			cl.close()
+			Logger.Info("Client disconnected", "addr", conn.RemoteAddr())
+		}()
+
+		cl.send(version())
+	}
+}
+
+// read will read commands from the client and answer them until the connection is closed
+// Commands start with ? and end with ; or a newline, eg ?WATCH={"enable":true};
+func (s *Server) read(cl *client) {
+	sc := bufio.NewScanner(cl.conn)
+	for sc.Scan() {
+		for _, cmd := range strings.Split(sc.Text(), ";") {
+			cmd = strings.TrimSpace(cmd)
+			if cmd == "" {
+				continue
+			}
+			Logger.Debug("Command", "cmd", cmd)
+			s.handle(cl, cmd)
+		}
+	}
+}
+
+// handle will answer a single command from the client
+func (s *Server) handle(cl *client, cmd string) {
+	name, arg, _ := strings.Cut(cmd, "=")
+	switch name {
+	case "?VERSION":
+		cl.send(version())
+	case "?DEVICES":
+		cl.send(s.devices())
+	case "?DEVICE":
+		cl.send(s.devices().Devices[0])
+	case "?WATCH":
+		if arg != "" {
+			var w Watch
+			if err := json.Unmarshal([]byte(arg), &w); err != nil {
+				cl.send(Error{Class: "ERROR", Message: fmt.Sprintf("Invalid WATCH: %v", err)})
+				return
+			}
+			cl.setWatch(w)
+		}
+		cl.send(s.devices())
+		cl.send(cl.watch())
+	case "?POLL":
+		s.mu.Lock()
+		p := Poll{
+			Class: "POLL",
+			Time:  time.Now().UTC().Format(TIME_FORMAT),
+			TPV:   []TPV{},
+			Sky:   []Sky{},
+		}
+		if s.tpv != nil {
+			p.Active = 1
+			p.TPV = append(p.TPV, *s.tpv)
+		}
+		if s.sky != nil {
+			p.Sky = append(p.Sky, *s.sky)
+		}
+		s.mu.Unlock()
+		cl.send(p)
+	default:
+		cl.send(Error{Class: "ERROR", Message: fmt.Sprintf("Unrecognized request '%s'", strings.TrimPrefix(name, "?"))})
+	}
+}
+
+// version returns the VERSION report
+func version() Version {
+	return Version{
+		Class:      "VERSION",
+		Release:    RELEASE,
+		Rev:        RELEASE,
+		ProtoMajor: PROTO_MAJOR,
+		ProtoMinor: PROTO_MINOR,
+	}
+}
+
+// devices returns the DEVICES report for the one device the server has
+func (s *Server) devices() Devices {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Devices{
+		Class: "DEVICES",
+		Devices: []Device{{
+			Class:     "DEVICE",
+			Path:      s.Device,
+			Activated: s.activated.Format(TIME_FORMAT),
+			Driver:    "X-Plane",
+			Flags:     1, // SEEN_GPS
+		}},
+	}
+}
+
+// client is a connection to a gpsd client, with a queue of reports to send to it
+type client struct {
+	conn net.Conn
+	out  chan []byte
+
+	mu     sync.Mutex
+	closed bool
+	enable bool
+	json   bool
+}
+
+// newClient returns a new client on conn
+func newClient(conn net.Conn) *client {
+	return &client{conn: conn, out: make(chan []byte, CLIENT_BUFFER)}
+}
+
+// send will queue a report for the client, dropping it if the client is not keeping up
+func (c *client) send(v any) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		Logger.Warn("Could not encode report", "err", err)
+		return
+	}
+	bs = append(bs, '\r', '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.out <- bs:
+	default:
+		Logger.Debug("Client too slow, report dropped", "addr", c.conn.RemoteAddr())
+	}
+}
+
+// write will write the queued reports to the client until the queue is closed
+func (c *client) write() {
+	for bs := range c.out {
+		c.conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+		if _, err := c.conn.Write(bs); err != nil {
+			Logger.Debug("Write failed", "addr", c.conn.RemoteAddr(), "err", err)
+			c.conn.Close()
+		}
+	}
+	c.conn.Close()
+}
+
+// close will stop sending to the client and close the connection once the queue is written
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.out)
+}
+
+// setWatch will update the client's watch policy with the fields set in w
+func (c *client) setWatch(w Watch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w.Enable != nil {
+		c.enable = *w.Enable
+	}
+	if w.JSON != nil {
+		c.json = *w.JSON
+	}
+	// enabling a watch without saying otherwise gets JSON, as it does from gpsd
+	if w.Enable != nil && *w.Enable && w.JSON == nil {
+		c.json = true
+	}
+}
+
+// watch returns the WATCH report of the client's watch policy
+// Only JSON reports are supported, so nmea is always false
+func (c *client) watch() Watch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	enable, js, nmea := c.enable, c.json, false
+	return Watch{Class: "WATCH", Enable: &enable, JSON: &js, NMEA: &nmea}
+}
+
+// watching returns whether the client wants JSON reports
+func (c *client) watching() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enable && c.json
+}
@@ -0,0 +1,127 @@
+package gpsd
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// readReport will read a report from r and return its class and the report
+func readReport(t *testing.T, conn net.Conn, r *bufio.Reader) (string, map[string]any) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Expected a report, but got: %v", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(line, &m); err != nil {
+		t.Fatalf("Expected JSON, but got: %q: %v", line, err)
+	}
+	class, _ := m["class"].(string)
+	return class, m
+}
+
+func TestServe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+
+	s := NewServer(ln.Addr().String())
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.Serve(ln, c, feedback) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Could not connect: %v", err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if class, m := readReport(t, conn, r); class != "VERSION" || m["proto_major"] != float64(PROTO_MAJOR) {
+		t.Errorf("Expected VERSION, but got: %v", m)
+	}
+
+	conn.Write([]byte("?WATCH={\"enable\":true,\"json\":true};\n"))
+	if class, m := readReport(t, conn, r); class != "DEVICES" {
+		t.Errorf("Expected DEVICES, but got: %v", m)
+	}
+	if class, m := readReport(t, conn, r); class != "WATCH" || m["enable"] != true || m["json"] != true {
+		t.Errorf("Expected WATCH enabled, but got: %v", m)
+	}
+
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 1000}
+	for _, expected := range []string{"TPV", "SKY", "ATT"} {
+		if class, m := readReport(t, conn, r); class != expected {
+			t.Errorf("Expected %s, but got: %v", expected, m)
+		}
+	}
+
+	// the satellites are only sent once a second
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 1000}
+	for _, expected := range []string{"TPV", "ATT"} {
+		if class, m := readReport(t, conn, r); class != expected {
+			t.Errorf("Expected %s, but got: %v", expected, m)
+		}
+	}
+
+	conn.Write([]byte("?POLL;\n"))
+	class, m := readReport(t, conn, r)
+	if class != "POLL" || m["active"] != float64(1) {
+		t.Errorf("Expected active POLL, but got: %v", m)
+	}
+	if tpv, _ := m["tpv"].([]any); len(tpv) != 1 {
+		t.Errorf("Expected 1 TPV, but got: %v", m["tpv"])
+	}
+
+	conn.Write([]byte("?FOO;\n"))
+	if class, m := readReport(t, conn, r); class != "ERROR" {
+		t.Errorf("Expected ERROR, but got: %v", m)
+	}
+
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := r.ReadBytes('\n'); err == nil {
+		t.Errorf("Expected the connection to be closed")
+	}
+}
+
+func TestNotWatching(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+
+	s := NewServer(ln.Addr().String())
+	c := make(chan xplane.Position)
+	done := make(chan error)
+	go func() { done <- s.Serve(ln, c, make(chan string, 10)) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Could not connect: %v", err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	readReport(t, conn, r)
+
+	// a client that hasn't asked to watch gets no reports, only answers
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75}
+	conn.Write([]byte("?VERSION;\n"))
+	if class, m := readReport(t, conn, r); class != "VERSION" {
+		t.Errorf("Expected VERSION, but got: %v", m)
+	}
+
+	close(c)
+	<-done
+}
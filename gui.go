@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -21,10 +23,12 @@ type AppUI struct {
 	XPlanes           xplane.XPlanes
 	xplaneSelect      *widget.Select
 	xplaneRefresh     *widget.Button
+	serialPorts       []serial.Port
 	serialPortsSelect *widget.Select
 	serialPortRefresh *widget.Button
+	serialPin         *widget.Check
 	baudRate          *widget.Select
-	refreshFreq       *widget.Select
+	refreshFreq       *widget.SelectEntry
 	runButton         *widget.Button
 	stopButton        *widget.Button
 	status            *widget.Label
@@ -35,9 +39,10 @@ type AppUI struct {
 var (
 	// PossibleBaudeRates is the list of possible baud rates
 	PossibleBaudeRates = [...]string{"9600", "14400", "19200", "38400", "57600", "115200"}
-	// PossiblePosFreqs is the list of possible position frequencies
-	// This will determine the rate that the X-Plane position is read
-	PossiblePosFreqs = [...]string{"1Hz", "2Hz", "5Hz", "10Hz", "20Hz"}
+	// PossiblePosFreqs is the list of suggested position frequencies
+	// This will determine the rate that the X-Plane position is read. Any rate up to xplane.MAX_POSITION_FREQ
+	// can also be typed in.
+	PossiblePosFreqs = [...]string{"1Hz", "2Hz", "5Hz", "10Hz", "20Hz", "30Hz", "50Hz", "60Hz"}
 )
 
 // NewAppUI returns a new AppUI
@@ -56,6 +61,8 @@ func NewAppUI(xApp *App, logger *slog.Logger) *AppUI {
 
 	ui.serialPortRefresh = widget.NewButton("Refresh Serial Port List", ui.getSerial)
 	ui.serialPortsSelect = widget.NewSelect([]string{}, ui.setSerialPort(xApp))
+	ui.serialPin = widget.NewCheck("Pin to this USB device", ui.pinSerialPort(xApp))
+	ui.serialPin.Disable()
 	ui.baudRate = widget.NewSelect(PossibleBaudeRates[:], func(value string) {
 		ui.Logger.Debug("Set BaudRate", "baud", value)
 
@@ -67,15 +74,20 @@ func NewAppUI(xApp *App, logger *slog.Logger) *AppUI {
 		xApp.SetBaudRate(v)
 	})
 
-	ui.refreshFreq = widget.NewSelect(PossiblePosFreqs[:], func(value string) {
+	ui.refreshFreq = widget.NewSelectEntry(PossiblePosFreqs[:])
+	ui.refreshFreq.OnChanged = func(value string) {
 		ui.Logger.Debug("Set PositionFreq", "freq", value)
-		v, err := strconv.Atoi(value[:len(value)-2])
+		// the rate can be typed, so it is only set once it is valid
+		v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(value, "Hz")), 10, 0)
+		if err == nil {
+			err = xplane.CheckPositionFreq(uint(v))
+		}
 		if err != nil {
-			ui.Logger.Error("Failed to convert PositionFreq", "err", err)
+			ui.Logger.Debug("Invalid PositionFreq", "freq", value, "err", err)
 			return
 		}
 		xApp.SetPositionFreq(uint(v))
-	})
+	}
 
 	ui.runButton = widget.NewButton("Run", ui.run)
 	ui.runButton.Disable()
@@ -85,7 +97,7 @@ func NewAppUI(xApp *App, logger *slog.Logger) *AppUI {
 	go ui.getSerial()
 	go ui.findXplanes(5 * time.Second)
 	ui.baudRate.SetSelected("38400")
-	ui.refreshFreq.SetSelected("10Hz")
+	ui.refreshFreq.SetText("10Hz")
 	ui.stopButton.Disable()
 
 	return ui
@@ -102,6 +114,7 @@ func (ui *AppUI) watchGUIState(state, last_state AppState) {
 		ui.xplaneSelect.Disable()
 		ui.serialPortRefresh.Disable()
 		ui.serialPortsSelect.Disable()
+		ui.serialPin.Disable()
 		ui.baudRate.Disable()
 		ui.refreshFreq.Disable()
 		ui.runButton.Disable()
@@ -111,6 +124,7 @@ func (ui *AppUI) watchGUIState(state, last_state AppState) {
 		ui.xplaneSelect.Enable()
 		ui.serialPortRefresh.Enable()
 		ui.serialPortsSelect.Enable()
+		ui.enableSerialPin()
 		ui.baudRate.Enable()
 		ui.refreshFreq.Enable()
 		ui.runButton.Enable()
@@ -120,6 +134,7 @@ func (ui *AppUI) watchGUIState(state, last_state AppState) {
 		ui.xplaneSelect.Enable()
 		ui.serialPortRefresh.Enable()
 		ui.serialPortsSelect.Enable()
+		ui.enableSerialPin()
 		ui.baudRate.Enable()
 		ui.refreshFreq.Enable()
 		ui.runButton.Disable()
@@ -174,6 +189,7 @@ func (ui *AppUI) serialLayout() fyne.CanvasObject {
 		container.New(layout.NewFormLayout(),
 			widget.NewLabel(""), ui.serialPortRefresh,
 			widget.NewLabel("Port"), ui.serialPortsSelect,
+			widget.NewLabel(""), ui.serialPin,
 			widget.NewLabel("Baud Rate"), ui.baudRate,
 		),
 	)
@@ -191,15 +207,59 @@ func (ui *AppUI) GetContent() fyne.CanvasObject {
 }
 
 // getSerial will populate the serialPortsSelect with the serial ports available on the system
+// If the serial port is pinned to a USB device that is plugged in, it is selected
 func (ui *AppUI) getSerial() {
 	ui.Logger.Debug("GetSerial")
-	ports := serial.FindPorts()
+	ports, err := serial.FindPorts()
+	if err != nil {
+		ui.Logger.Error("Failed to find serial ports", "err", err)
+		ui.status.SetText("Could not list serial ports")
+		return
+	}
 
 	ui.Logger.Debug("Serial Ports", "count", len(ports), "ports", ports)
 	if len(ports) == 0 {
 		return
 	}
-	ui.serialPortsSelect.SetOptions(ports)
+	ui.serialPorts = ports
+	options := make([]string, len(ports))
+	for i, p := range ports {
+		options[i] = p.Description()
+	}
+	ui.serialPortsSelect.SetOptions(options)
+
+	pin := serialPin(ui.app.Config)
+	if pin.IsZero() {
+		return
+	}
+	for _, p := range ports {
+		if p.IsUSB && p.USBID == pin {
+			ui.serialPortsSelect.SetSelected(p.Description())
+			return
+		}
+	}
+	ui.status.SetText(fmt.Sprintf("Pinned serial port %s not plugged in", pin))
+}
+
+// serialPort returns the serial port with the description, as shown in the serialPortsSelect
+func (ui *AppUI) serialPort(description string) (serial.Port, bool) {
+	for _, p := range ui.serialPorts {
+		if p.Description() == description {
+			return p, true
+		}
+	}
+	return serial.Port{}, false
+}
+
+// enableSerialPin will enable the serialPin check if the selected port is a USB device, and tick it if the
+// port is pinned to that device
+func (ui *AppUI) enableSerialPin() {
+	p, ok := ui.serialPort(ui.serialPortsSelect.Selected)
+	if !ok || !p.IsUSB {
+		ui.serialPin.Disable()
+		return
+	}
+	ui.serialPin.Enable()
 }
 
 // FindXplanes will search for X-Plane beacons and add them to the XPlanes map
@@ -228,7 +288,31 @@ func (ui *AppUI) findXplanes(timeout time.Duration) {
 
 // setSerialPort returns a function that will set the serial port on the app
 func (ui *AppUI) setSerialPort(xApp *App) func(string) {
-	return xApp.SetSerialPort
+	return func(description string) {
+		p, ok := ui.serialPort(description)
+		if !ok {
+			return
+		}
+		xApp.SetSerialPort(p.Name)
+		ui.serialPin.SetChecked(p.IsUSB && p.USBID == serialPin(xApp.Config))
+		ui.enableSerialPin()
+	}
+}
+
+// pinSerialPort returns a function that will pin the serial port to the selected USB device, or unpin it
+func (ui *AppUI) pinSerialPort(xApp *App) func(bool) {
+	return func(pinned bool) {
+		p, ok := ui.serialPort(ui.serialPortsSelect.Selected)
+		if !pinned || !ok || !p.IsUSB {
+			if !serialPin(xApp.Config).IsZero() {
+				xApp.SetSerialPin(serial.USBID{})
+			}
+			return
+		}
+		if p.USBID != serialPin(xApp.Config) {
+			xApp.SetSerialPin(p.USBID)
+		}
+	}
 }
 
 // setXPlane returns a function that will set the X-Plane on the app
@@ -257,14 +341,6 @@ func (ui *AppUI) run() {
 			if msg == "" && time.Since(t) < 20*time.Second {
 				continue
 			}
-			if msg == "XXX" {
-				ui.Logger.Warn("Quit on Feedback", "msg", msg)
-				// stop the app and drain the feedback channel
-				ui.stop()
-				for range feedback {
-				}
-				return
-			}
 			ui.status.SetText(msg)
 			ui.Logger.Info("Feedback", "msg", msg)
 			t = time.Now()
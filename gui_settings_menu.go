@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -10,7 +14,17 @@ import (
 	"fyne.io/fyne/v2/widget"
 	serialv "go.bug.st/serial"
 
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/config"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/delay"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/faults"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gdl90"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gpsd"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/mavlink"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/noise"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/pty"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/resample"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/serial"
 )
 
@@ -20,13 +34,13 @@ func (ui *AppUI) SettingsMenu(w fyne.Window) *fyne.Menu {
 		prGrp := widget.NewRadioGroup([]string{"Standard", "Enhanced"}, func(value string) {
 			switch value {
 			case "Standard":
-				nmea.Formats = nmea.DEFAULTS
+				nmea.SetFormats(nmea.DEFAULTS)
 			case "Enhanced":
-				nmea.Formats = nmea.ENHANCED
+				nmea.SetFormats(nmea.ENHANCED)
 			}
 			ui.Logger.Debug("Precision Changed", "precision", value)
 		})
-		if nmea.Formats == nmea.ENHANCED {
+		if nmea.CurrentFormats() == nmea.ENHANCED {
 			prGrp.SetSelected("Enhanced")
 		} else {
 			prGrp.SetSelected("Standard")
@@ -49,6 +63,503 @@ func (ui *AppUI) SettingsMenu(w fyne.Window) *fyne.Menu {
 		dialog.ShowCustom("Precision", "Done", c, w)
 
 	})
+	tkMenu := fyne.NewMenuItem("Talker ID", func() {
+		talkers := make([]string, len(nmea.Talkers))
+		for i, t := range nmea.Talkers {
+			talkers[i] = string(t)
+		}
+		tkGrp := widget.NewRadioGroup(talkers, func(value string) {
+			t, err := nmea.ParseTalker(value)
+			if err != nil {
+				ui.Logger.Error("Invalid Talker", "err", err)
+				return
+			}
+			if t == nmea.DefaultTalker() {
+				return
+			}
+			nmea.SetDefaultTalker(t)
+			ui.app.Config.Talker = value
+			ui.app.SaveConfig()
+			ui.Logger.Debug("Talker Changed", "talker", value)
+		})
+		tkGrp.SetSelected(string(nmea.DefaultTalker()))
+
+		info := widget.NewLabel(
+			"Sets the talker ID that starts each NMEA sentence.\n" +
+				"• GP is a GPS receiver.\n" +
+				"• GN is a multi-GNSS receiver.\n" +
+				"• GL is a GLONASS receiver, GA is a Galileo receiver.\n" +
+				"• II is integrated instrumentation.\n" +
+				"Individual sentences can override this in the config file.")
+
+		c := container.NewVBox(
+			info,
+			widget.NewSeparator(),
+			tkGrp,
+		)
+
+		dialog.ShowCustom("Talker ID", "Done", c, w)
+	})
+	gdMenu := fyne.NewMenuItem("GDL90", func() {
+		cfg := ui.app.Config.GDL90
+
+		enabled := widget.NewCheck("Send GDL90", nil)
+		enabled.SetChecked(cfg.Enabled)
+		addr := widget.NewEntry()
+		addr.SetPlaceHolder(gdl90.DEFAULT_ADDR)
+		addr.SetText(cfg.Addr)
+		callsign := widget.NewEntry()
+		callsign.SetText(cfg.Callsign)
+		icao := widget.NewEntry()
+		icao.SetPlaceHolder("ABCDEF")
+		icao.SetText(cfg.ICAO)
+		icao.Validator = func(s string) error {
+			if s == "" {
+				return nil
+			}
+			_, err := strconv.ParseUint(s, 16, 24)
+			return err
+		}
+
+		info := widget.NewLabel(
+			"Sends GDL90 over UDP for EFBs such as ForeFlight\n" +
+				"and Garmin Pilot. This can be used with or without\n" +
+				"a serial port, and applies the next time you Run.")
+
+		dialog.ShowForm("GDL90", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("Address", addr),
+			widget.NewFormItem("Callsign", callsign),
+			widget.NewFormItem("ICAO Address", icao),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			ui.app.SetGDL90(config.GDL90{
+				Enabled:  enabled.Checked,
+				Addr:     addr.Text,
+				Callsign: callsign.Text,
+				ICAO:     icao.Text,
+			})
+		}, w)
+	})
+	ffMenu := fyne.NewMenuItem("ForeFlight", func() {
+		cfg := ui.app.Config.ForeFlight
+
+		enabled := widget.NewCheck("Send XGPS positions", nil)
+		enabled.SetChecked(cfg.Enabled)
+		attitude := widget.NewCheck("Send XATT attitude", nil)
+		attitude.SetChecked(!cfg.NoAttitude)
+		addr := widget.NewEntry()
+		addr.SetPlaceHolder(outputters.FOREFLIGHT_ADDR)
+		addr.SetText(cfg.Addr)
+		name := widget.NewEntry()
+		name.SetPlaceHolder(outputters.DEFAULT_SIM_NAME)
+		name.SetText(cfg.Name)
+
+		info := widget.NewLabel(
+			"Sends the XGPS and XATT messages that ForeFlight\n" +
+				"accepts from simulators over UDP. This can be used\n" +
+				"with or without a serial port, and applies the next\n" +
+				"time you Run.")
+
+		dialog.ShowForm("ForeFlight", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("", attitude),
+			widget.NewFormItem("Address", addr),
+			widget.NewFormItem("Sim Name", name),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			ui.app.SetForeFlight(config.ForeFlight{
+				Enabled:    enabled.Checked,
+				Addr:       addr.Text,
+				Name:       name.Text,
+				NoAttitude: !attitude.Checked,
+			})
+		}, w)
+	})
+	mvMenu := fyne.NewMenuItem("MAVLink", func() {
+		cfg := ui.app.Config.MAVLink
+
+		enabled := widget.NewCheck("Send MAVLink GPS", nil)
+		enabled.SetChecked(cfg.Enabled)
+		message := widget.NewRadioGroup([]string{"GPS_INPUT", "HIL_GPS"}, nil)
+		message.Horizontal = true
+		if cfg.Message == "hil_gps" {
+			message.SetSelected("HIL_GPS")
+		} else {
+			message.SetSelected("GPS_INPUT")
+		}
+		port := widget.NewEntry()
+		port.SetPlaceHolder("None, use UDP")
+		port.SetText(cfg.Port)
+		baud := widget.NewSelect(PossibleBaudeRates[:], nil)
+		if cfg.Baud != 0 {
+			baud.SetSelected(strconv.Itoa(cfg.Baud))
+		} else {
+			baud.SetSelected("57600")
+		}
+		addr := widget.NewEntry()
+		addr.SetPlaceHolder(mavlink.DEFAULT_UDP_ADDR)
+		addr.SetText(cfg.Addr)
+
+		info := widget.NewLabel(
+			"Sends GPS_INPUT (ArduPilot) or HIL_GPS (PX4 and SITL)\n" +
+				"messages to an autopilot, over a serial port if one\n" +
+				"is set or UDP if not. This applies the next time you Run.")
+
+		dialog.ShowForm("MAVLink", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("Message", message),
+			widget.NewFormItem("Serial Port", port),
+			widget.NewFormItem("Baud Rate", baud),
+			widget.NewFormItem("UDP Address", addr),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			b, _ := strconv.Atoi(baud.Selected)
+			cfg.Enabled = enabled.Checked
+			cfg.Message = strings.ToLower(message.Selected)
+			cfg.Port = port.Text
+			cfg.Baud = b
+			cfg.Addr = addr.Text
+			ui.app.SetMAVLink(cfg)
+		}, w)
+	})
+	gpMenu := fyne.NewMenuItem("gpsd", func() {
+		cfg := ui.app.Config.GPSD
+
+		enabled := widget.NewCheck("Run gpsd server", nil)
+		enabled.SetChecked(cfg.Enabled)
+		addr := widget.NewEntry()
+		addr.SetPlaceHolder(gpsd.DEFAULT_ADDR)
+		addr.SetText(cfg.Addr)
+
+		info := widget.NewLabel(
+			"Serves positions with the gpsd JSON protocol over TCP,\n" +
+				"for Linux tools that use gpsd. This can be used with\n" +
+				"or without a serial port, and applies the next time\n" +
+				"you Run.")
+
+		dialog.ShowForm("gpsd", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("Address", addr),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			ui.app.SetGPSD(config.GPSD{
+				Enabled: enabled.Checked,
+				Addr:    addr.Text,
+			})
+		}, w)
+	})
+	ptyMenu := fyne.NewMenuItem("Virtual Serial Port", func() {
+		cfg := ui.app.Config.PTY
+
+		enabled := widget.NewCheck("Create virtual serial port", nil)
+		enabled.SetChecked(cfg.Enabled)
+		link := widget.NewEntry()
+		link.SetPlaceHolder(pty.DEFAULT_LINK)
+		link.SetText(cfg.Link)
+
+		info := widget.NewLabel(
+			"Creates a pseudo-terminal that local applications can\n" +
+				"open like a GPS device. The sentences it sends are\n" +
+				"chosen under Sentences. Linux only. This applies\n" +
+				"the next time you Run.")
+
+		dialog.ShowForm("Virtual Serial Port", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("Link", link),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			cfg.Enabled = enabled.Checked
+			cfg.Link = link.Text
+			ui.app.SetPTY(cfg)
+		}, w)
+	})
+	snMenu := fyne.NewMenuItem("Sentences", func() {
+		// the options show the description of each outputter
+		regs := registrations(ui.app.Config, ui.Logger)
+		options := make([]string, len(regs))
+		names := make(map[string]string, len(regs))
+		for i, r := range regs {
+			options[i] = fmt.Sprintf("%s: %s", r.Name, r.Description)
+			names[options[i]] = r.Name
+		}
+		sentences := widget.NewCheckGroup(options, nil)
+
+		// show the sentences of the sink that is selected
+		sink := widget.NewSelect(NMEA_SINKS, func(name string) {
+			on := ui.app.OnSentences(name)
+			var selected []string
+			for _, o := range options {
+				if slices.Contains(on, names[o]) {
+					selected = append(selected, o)
+				}
+			}
+			sentences.SetSelected(selected)
+		})
+		sink.SetSelected(NMEA_SINKS[0])
+		list := container.NewVScroll(sentences)
+		list.SetMinSize(fyne.NewSize(0, 300))
+
+		info := widget.NewLabel(
+			"Chooses the sentences sent to the serial port or the\n" +
+				"virtual serial port. With none ticked, the sentences\n" +
+				"turned on in the config file are sent. The serial port\n" +
+				"sends them straight away.")
+
+		dialog.ShowForm("Sentences", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("Sink", sink),
+			widget.NewFormItem("", list),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			var selected []string
+			for _, o := range options {
+				if slices.Contains(sentences.Selected, o) {
+					selected = append(selected, names[o])
+				}
+			}
+			ui.app.SetSentences(sink.Selected, selected)
+		}, w)
+	})
+	erMenu := fyne.NewMenuItem("Error Model", func() {
+		cfg := ui.app.Config.Errors
+
+		enabled := widget.NewCheck("Add GPS errors", nil)
+		enabled.SetChecked(cfg.Enabled)
+		seed := widget.NewEntry()
+		seed.SetPlaceHolder("New each run")
+		if cfg.Seed != 0 {
+			seed.SetText(strconv.FormatInt(cfg.Seed, 10))
+		}
+		seed.Validator = func(s string) error {
+			if s == "" {
+				return nil
+			}
+			_, err := strconv.ParseInt(s, 10, 64)
+			return err
+		}
+		hDrift := floatEntry(cfg.HorizontalDrift, noise.DEFAULT_HORIZONTAL_DRIFT)
+		vDrift := floatEntry(cfg.VerticalDrift, noise.DEFAULT_VERTICAL_DRIFT)
+		hNoise := floatEntry(cfg.HorizontalNoise, noise.DEFAULT_HORIZONTAL_NOISE)
+		vNoise := floatEntry(cfg.VerticalNoise, noise.DEFAULT_VERTICAL_NOISE)
+		mpInterval := floatEntry(cfg.MultipathInterval, noise.DEFAULT_MULTIPATH_INTERVAL.Seconds())
+		mpSize := floatEntry(cfg.MultipathSize, noise.DEFAULT_MULTIPATH_SIZE)
+
+		info := widget.NewLabel(
+			"Adds the errors of a real receiver to the positions:\n" +
+				"slow drift, noise and occasional multipath jumps,\n" +
+				"with a matching HDOP and accuracy. Distances are in\n" +
+				"meters and times in seconds, -1 turns an error off.\n" +
+				"This applies the next time you Run.")
+
+		dialog.ShowForm("Error Model", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("Seed", seed),
+			widget.NewFormItem("Horizontal Drift", hDrift),
+			widget.NewFormItem("Vertical Drift", vDrift),
+			widget.NewFormItem("Horizontal Noise", hNoise),
+			widget.NewFormItem("Vertical Noise", vNoise),
+			widget.NewFormItem("Multipath Interval", mpInterval),
+			widget.NewFormItem("Multipath Size", mpSize),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			cfg.Enabled = enabled.Checked
+			cfg.Seed, _ = strconv.ParseInt(seed.Text, 10, 64)
+			cfg.HorizontalDrift, _ = strconv.ParseFloat(hDrift.Text, 64)
+			cfg.VerticalDrift, _ = strconv.ParseFloat(vDrift.Text, 64)
+			cfg.HorizontalNoise, _ = strconv.ParseFloat(hNoise.Text, 64)
+			cfg.VerticalNoise, _ = strconv.ParseFloat(vNoise.Text, 64)
+			cfg.MultipathInterval, _ = strconv.ParseFloat(mpInterval.Text, 64)
+			cfg.MultipathSize, _ = strconv.ParseFloat(mpSize.Text, 64)
+			ui.app.SetErrors(cfg)
+		}, w)
+	})
+	rtMenu := fyne.NewMenuItem("Output Rate", func() {
+		cfg := ui.app.Config.Resample
+
+		// the first option sends the positions as they are received
+		options := []string{"As received"}
+		for _, r := range resample.RATES {
+			options = append(options, fmt.Sprintf("%dHz", r))
+		}
+		rate := widget.NewSelect(options, nil)
+		rate.SetSelected(options[0])
+		if cfg.Rate != 0 {
+			rate.SetSelected(fmt.Sprintf("%dHz", cfg.Rate))
+		}
+		hold := floatEntry(cfg.Delay, 0)
+
+		info := widget.NewLabel(
+			"Sends fixes at an exact rate, aligned to whole\n" +
+				"seconds like a real receiver, however unevenly\n" +
+				"X-Plane sends its positions. Fixes are extrapolated\n" +
+				"from the last position, or interpolated when they\n" +
+				"are delayed by longer than the time between them.\n" +
+				"The delay is in seconds.\n" +
+				"This applies the next time you Run.")
+
+		dialog.ShowForm("Output Rate", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("Rate", rate),
+			widget.NewFormItem("Delay", hold),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			cfg.Rate = 0
+			if rate.Selected != options[0] {
+				r, _ := strconv.Atoi(strings.TrimSuffix(rate.Selected, "Hz"))
+				cfg.Rate = uint(r)
+			}
+			cfg.Delay, _ = strconv.ParseFloat(hold.Text, 64)
+			ui.app.SetResample(cfg)
+		}, w)
+	})
+	dlMenu := fyne.NewMenuItem("Link Delays", func() {
+		latency := widget.NewEntry()
+		jitter := widget.NewEntry()
+		distribution := widget.NewSelect([]string{
+			delay.UNIFORM.String(), delay.NORMAL.String(), delay.EXPONENTIAL.String(),
+		}, nil)
+		reorder := widget.NewEntry()
+		drop := widget.NewEntry()
+		for _, e := range []*widget.Entry{latency, jitter, reorder, drop} {
+			e.SetPlaceHolder("0")
+			e.Validator = validateFloat
+		}
+
+		// show the delay of the sink that is selected
+		sink := widget.NewSelect(SINKS, func(name string) {
+			cfg := ui.app.Config.Delay(name)
+			latency.SetText(floatText(cfg.Latency))
+			jitter.SetText(floatText(cfg.Jitter))
+			distribution.SetSelected(delay.UNIFORM.String())
+			if cfg.Distribution != "" {
+				distribution.SetSelected(cfg.Distribution)
+			}
+			reorder.SetText(floatText(cfg.Reorder))
+			drop.SetText(floatText(cfg.Drop))
+		})
+		sink.SetSelected(SINKS[0])
+
+		info := widget.NewLabel(
+			"Delays the positions on their way to a sink, like a\n" +
+				"slow or busy link. The jitter, in seconds like the\n" +
+				"latency, is added to it at random. Reorder and drop\n" +
+				"are probabilities from 0 to 1. The time in the\n" +
+				"sentences is still the time of the fix.\n" +
+				"This applies the next time you Run.")
+
+		dialog.ShowForm("Link Delays", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("Sink", sink),
+			widget.NewFormItem("Latency", latency),
+			widget.NewFormItem("Jitter", jitter),
+			widget.NewFormItem("Distribution", distribution),
+			widget.NewFormItem("Reorder", reorder),
+			widget.NewFormItem("Drop", drop),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			cfg := ui.app.Config.Delay(sink.Selected)
+			cfg.Latency, _ = strconv.ParseFloat(latency.Text, 64)
+			cfg.Jitter, _ = strconv.ParseFloat(jitter.Text, 64)
+			cfg.Distribution = distribution.Selected
+			if cfg.Distribution == delay.UNIFORM.String() {
+				cfg.Distribution = ""
+			}
+			cfg.Reorder, _ = strconv.ParseFloat(reorder.Text, 64)
+			cfg.Drop, _ = strconv.ParseFloat(drop.Text, 64)
+			ui.app.SetDelay(sink.Selected, cfg)
+		}, w)
+	})
+	ftMenu := fyne.NewMenuItem("GPS Faults", func() {
+		cfg := ui.app.Config.Faults
+
+		fault := widget.NewSelect(faultLabels(), nil)
+		current := faults.NONE
+		if ui.app.Faults != nil {
+			current = ui.app.Faults.Fault()
+		}
+		fault.SetSelected(faultLabel(current))
+		follow := widget.NewCheck("Follow X-Plane GPS failure", nil)
+		follow.SetChecked(cfg.FollowXPlane)
+
+		info := widget.NewLabel(
+			"Simulates a GPS failure now, until it is set back to\n" +
+				"None. Following X-Plane loses the fix while the GPS\n" +
+				"is failed in X-Plane, from the next time you Run.\n" +
+				"A schedule of faults can be set in the config file.")
+
+		dialog.ShowForm("GPS Faults", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("Fault", fault),
+			widget.NewFormItem("", follow),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			for _, f := range faults.FAULTS {
+				if faultLabel(f) == fault.Selected {
+					ui.app.SetFault(f)
+				}
+			}
+			cfg.FollowXPlane = follow.Checked
+			ui.app.SetFaults(cfg)
+		}, w)
+	})
+	scMenu := fyne.NewMenuItem("Scenario", func() {
+		cfg := ui.app.Config.Scenario
+
+		enabled := widget.NewCheck("Run scenario", nil)
+		enabled.SetChecked(ui.app.Scenario.Enabled())
+		file := widget.NewEntry()
+		file.SetPlaceHolder("scenario.json")
+		file.SetText(cfg.File)
+
+		info := widget.NewLabel(
+			"Replays the jamming zones and spoofing attacks in a\n" +
+				"scenario file. Turning it on starts the scenario\n" +
+				"again, straight away if you are running.")
+
+		dialog.ShowForm("Scenario", "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("", info),
+			widget.NewFormItem("", enabled),
+			widget.NewFormItem("File", file),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			err := ui.app.SetScenario(config.Scenario{
+				File:    file.Text,
+				Enabled: enabled.Checked,
+			})
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+	})
 	spMenu := fyne.NewMenuItem("Serial Port", func() {
 		ser, ok := ui.app.Serial.(*serial.Serial)
 		if !ok {
@@ -160,6 +671,77 @@ func (ui *AppUI) SettingsMenu(w fyne.Window) *fyne.Menu {
 	})
 	return fyne.NewMenu("Settings",
 		prMenu,
+		tkMenu,
+		gdMenu,
+		ffMenu,
+		mvMenu,
+		gpMenu,
+		ptyMenu,
+		snMenu,
+		erMenu,
+		rtMenu,
+		dlMenu,
+		ftMenu,
+		scMenu,
 		spMenu,
 	)
 }
+
+// floatEntry returns an entry for a config value, which shows the default when the value is not set
+func floatEntry(v, def float64) *widget.Entry {
+	e := widget.NewEntry()
+	e.SetPlaceHolder(strconv.FormatFloat(def, 'f', -1, 64))
+	e.SetText(floatText(v))
+	e.Validator = validateFloat
+	return e
+}
+
+// validateFloat returns an error when the text of an entry is not a number, so a form can't be saved with it
+// An empty entry is valid, and leaves the value not set
+func validateFloat(s string) error {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%s is not a number", s)
+	}
+	return nil
+}
+
+// floatText returns the text of a config value for an entry, which is empty when the value is not set
+func floatText(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// faultLabel returns the name of a fault to show in the UI
+func faultLabel(f faults.Fault) string {
+	switch f {
+	case faults.NONE:
+		return "None"
+	case faults.NO_FIX:
+		return "No Fix"
+	case faults.FIX_2D:
+		return "2D Fix"
+	case faults.DEAD_RECKONING:
+		return "Dead Reckoning"
+	case faults.FROZEN:
+		return "Frozen Position"
+	}
+	return f.String()
+}
+
+// faultLabels returns the names of all the faults to show in the UI
+func faultLabels() []string {
+	labels := make([]string, len(faults.FAULTS))
+	for i, f := range faults.FAULTS {
+		labels[i] = faultLabel(f)
+	}
+	return labels
+}
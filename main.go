@@ -2,33 +2,98 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 
-	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/config"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/faults"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gdl90"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gpsd"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/mavlink"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/pty"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/scenario"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/serial"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/udp"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
 )
 
 func main() {
+	configPath := flag.String("config", config.DefaultPath(), "path to the config file")
+	scenarioPath := flag.String("scenario", "", "path to a jamming and spoofing scenario file to run")
+	serialSentences := flag.String("serial-sentences", "", "comma separated sentences to send to the serial port")
+	ptySentences := flag.String("pty-sentences", "", "comma separated sentences to send to the virtual serial port")
+	listOutputters := flag.Bool("list-outputters", false, "list the sentences that can be sent and exit")
+	flag.Parse()
+
 	// Create the logger
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
+	// Load the config
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Error("Failed to load config, using defaults", "err", err)
+		cfg = &config.Config{}
+	}
+	applyConfig(cfg, logger)
+	if *listOutputters {
+		for _, r := range registrations(cfg, logger) {
+			fmt.Printf("%-16s %s\n", r.Name, r.Description)
+		}
+		return
+	}
+	// the sentences chosen on the command line are only for this run, so they are kept out of the config
+	chosen := make(map[string][]string)
+	for sink, list := range map[string]string{"serial": *serialSentences, "pty": *ptySentences} {
+		if list == "" {
+			continue
+		}
+		names, err := parseSentences(cfg, list, logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -%s-sentences: %v\n", sink, err)
+			os.Exit(2)
+		}
+		chosen[sink] = names
+	}
+
 	// Create the app
+	ser := serial.NewSerial(newOutputs(cfg, chosen, logger))
+	ser.SetPin(serialPin(cfg))
 	a := &App{
-		Serial: serial.NewSerial([]outputters.Outputter{
-			&outputters.GGA{},
-			&outputters.VTG{},
-		}),
-		Logger: logger,
+		Serial:     ser,
+		Faults:     faults.NewInjector(nil),
+		Scenario:   scenario.NewEngine(),
+		Config:     cfg,
+		ConfigPath: *configPath,
+		Sentences:  chosen,
+		Logger:     logger,
 	}
 
 	// Set the serial and xplanes Loggers
 	xplane.Logger = logger.With("src", "XPlane")
 	serial.Logger = logger.With("src", "Serial")
+	gdl90.Logger = logger.With("src", "GDL90")
+	udp.Logger = logger.With("src", "UDP")
+	mavlink.Logger = logger.With("src", "MAVLink")
+	gpsd.Logger = logger.With("src", "gpsd")
+	pty.Logger = logger.With("src", "PTY")
+	faults.Logger = logger.With("src", "Faults")
+	scenario.Logger = logger.With("src", "Scenario")
+
+	// Load the scenario from the command line, or the config
+	if *scenarioPath != "" {
+		if err := a.LoadScenario(*scenarioPath, true); err != nil {
+			logger.Error("Failed to load scenario", "err", err)
+		}
+	} else if cfg.Scenario.File != "" {
+		if err := a.LoadScenario(cfg.Scenario.File, cfg.Scenario.Enabled); err != nil {
+			logger.Error("Failed to load scenario from config", "err", err)
+		}
+	}
 
 	// Create the UI
 	gui := app.New()
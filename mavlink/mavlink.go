@@ -0,0 +1,150 @@
+package mavlink
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// STX is the start of a MAVLink v2 frame
+const STX = 0xFD
+
+// Default IDs of the connector on the MAVLink network
+const (
+	DEFAULT_SYSTEM_ID    = 1
+	DEFAULT_COMPONENT_ID = 220 // MAV_COMP_ID_GPS
+)
+
+// headerLen is the length of a MAVLink v2 header, including STX
+const headerLen = 10
+
+// ErrInvalidFrame is returned when a frame can not be read
+var ErrInvalidFrame = errors.New("invalid MAVLink frame")
+
+// Message is a MAVLink message that can be encoded into a frame
+type Message interface {
+	// ID returns the message ID
+	ID() uint32
+	// CRCExtra returns the seed added to the checksum, which is derived from the message definition
+	CRCExtra() byte
+	// Marshal returns the payload of the message
+	Marshal() []byte
+}
+
+// crcExtras are the CRC seeds of the messages that can be read
+var crcExtras = map[uint32]byte{
+	MSG_HEARTBEAT: 50,
+	MSG_HIL_GPS:   124,
+	MSG_GPS_INPUT: 151,
+}
+
+// CRC returns the X.25 (CRC-16/MCRF4XX) checksum of bs, starting from crc
+// The checksum of a new message starts from 0xFFFF
+func CRC(crc uint16, bs []byte) uint16 {
+	for _, b := range bs {
+		t := b ^ byte(crc)
+		t ^= t << 4
+		crc = crc>>8 ^ uint16(t)<<8 ^ uint16(t)<<3 ^ uint16(t)>>4
+	}
+	return crc
+}
+
+// Encoder will encode messages into MAVLink v2 frames from a system and component
+type Encoder struct {
+	SystemID    byte
+	ComponentID byte
+	seq         byte
+}
+
+// Encode returns a MAVLink v2 frame of m, with the next sequence number
+func (e *Encoder) Encode(m Message) []byte {
+	payload := m.Marshal()
+	// MAVLink v2 drops trailing zeros from the payload, but always sends at least one byte
+	n := len(payload)
+	for n > 1 && payload[n-1] == 0 {
+		n--
+	}
+	payload = payload[:n]
+
+	id := m.ID()
+	frame := make([]byte, 0, headerLen+n+2)
+	frame = append(frame, STX, byte(n), 0, 0, e.seq, e.SystemID, e.ComponentID, byte(id), byte(id>>8), byte(id>>16))
+	frame = append(frame, payload...)
+	crc := CRC(0xFFFF, frame[1:])
+	crc = CRC(crc, []byte{m.CRCExtra()})
+	e.seq++
+	return append(frame, byte(crc), byte(crc>>8))
+}
+
+// Frame is a frame read from a MAVLink v2 stream
+type Frame struct {
+	Seq         byte
+	SystemID    byte
+	ComponentID byte
+	MessageID   uint32
+	// Payload is the payload of the frame, with any trailing zeros that were dropped
+	Payload []byte
+}
+
+// ReadFrame will read the next MAVLink v2 frame from br, skipping anything before it
+// Frames of messages with an unknown checksum seed can not be checked, and are returned with
+// ErrInvalidFrame
+func ReadFrame(br *bufio.Reader) (Frame, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return Frame{}, err
+		}
+		if b == STX {
+			break
+		}
+	}
+
+	header := make([]byte, headerLen)
+	header[0] = STX
+	if _, err := io.ReadFull(br, header[1:]); err != nil {
+		return Frame{}, err
+	}
+	n := int(header[1])
+	rest := make([]byte, n+2)
+	if _, err := io.ReadFull(br, rest); err != nil {
+		return Frame{}, err
+	}
+	// signed frames have a 13 byte signature, which is not checked
+	if header[2]&0x01 != 0 {
+		if _, err := br.Discard(13); err != nil {
+			return Frame{}, err
+		}
+	}
+
+	f := Frame{
+		Seq:         header[4],
+		SystemID:    header[5],
+		ComponentID: header[6],
+		MessageID:   uint32(header[7]) | uint32(header[8])<<8 | uint32(header[9])<<16,
+	}
+	extra, ok := crcExtras[f.MessageID]
+	if !ok {
+		return f, fmt.Errorf("%w: unknown message %d", ErrInvalidFrame, f.MessageID)
+	}
+	crc := CRC(0xFFFF, header[1:])
+	crc = CRC(crc, rest[:n])
+	crc = CRC(crc, []byte{extra})
+	if binary.LittleEndian.Uint16(rest[n:]) != crc {
+		return f, fmt.Errorf("%w: bad checksum", ErrInvalidFrame)
+	}
+	f.Payload = rest[:n]
+	return f, nil
+}
+
+// pad returns the payload extended with the zeros that were dropped, to at least n bytes
+func pad(payload []byte, n int) []byte {
+	if len(payload) >= n {
+		return payload
+	}
+	p := make([]byte, n)
+	copy(p, payload)
+	return p
+}
@@ -0,0 +1,77 @@
+package mavlink
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// testMessage is a message with a payload that ends in zeros
+type testMessage struct{}
+
+func (m *testMessage) ID() uint32      { return MSG_HEARTBEAT }
+func (m *testMessage) CRCExtra() byte  { return crcExtras[MSG_HEARTBEAT] }
+func (m *testMessage) Marshal() []byte { return []byte{1, 2, 0, 0, 0, 0, 0, 0, 0} }
+
+func TestCRC(t *testing.T) {
+	// the check value of CRC-16/MCRF4XX
+	if crc := CRC(0xFFFF, []byte("123456789")); crc != 0x6F91 {
+		t.Errorf("Expected: 0x6F91, but got: 0x%04X", crc)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	e := Encoder{SystemID: 1, ComponentID: DEFAULT_COMPONENT_ID}
+	hb := &Heartbeat{Type: MAV_TYPE_ONBOARD_CONTROLLER, Autopilot: MAV_AUTOPILOT_INVALID, SystemStatus: MAV_STATE_ACTIVE}
+
+	first := e.Encode(hb)
+	second := e.Encode(&testMessage{})
+
+	expected := []byte{STX, 9, 0, 0, 0, 1, DEFAULT_COMPONENT_ID, 0, 0, 0, 0, 0, 0, 0, 18, 8, 0, 4, 3}
+	if !bytes.Equal(first[:len(expected)], expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, first[:len(expected)])
+	}
+	if len(first) != len(expected)+2 {
+		t.Errorf("Expected length: %d, but got: %d", len(expected)+2, len(first))
+	}
+	if second[1] != 2 {
+		t.Errorf("Expected trailing zeros to be dropped, but got a payload length of %d", second[1])
+	}
+	if second[4] != 1 {
+		t.Errorf("Expected sequence 1, but got: %d", second[4])
+	}
+
+	br := bufio.NewReader(bytes.NewReader(append(append([]byte("noise"), first...), second...)))
+	for i, m := range []Message{hb, &testMessage{}} {
+		f, err := ReadFrame(br)
+		if err != nil {
+			t.Fatalf("Frame %d: Expected no error, but got: %v", i, err)
+		}
+		if f.Seq != byte(i) || f.SystemID != 1 || f.ComponentID != DEFAULT_COMPONENT_ID || f.MessageID != m.ID() {
+			t.Errorf("Frame %d: unexpected header: %+v", i, f)
+		}
+		if p := pad(f.Payload, 9); !bytes.Equal(p, m.Marshal()) {
+			t.Errorf("Frame %d: Expected payload: % X, but got: % X", i, m.Marshal(), p)
+		}
+	}
+}
+
+func TestReadFrameInvalid(t *testing.T) {
+	e := Encoder{SystemID: 1, ComponentID: 1}
+	frame := e.Encode(&Heartbeat{})
+	frame[len(frame)-1] ^= 0xFF
+
+	_, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)))
+	if !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("Expected ErrInvalidFrame, but got: %v", err)
+	}
+}
+
+func TestUnmarshalHeartbeat(t *testing.T) {
+	expected := Heartbeat{CustomMode: 10, Type: 1, Autopilot: 3, BaseMode: 0x81, SystemStatus: MAV_STATE_ACTIVE}
+	result := UnmarshalHeartbeat(expected.Marshal())
+	if result != expected {
+		t.Errorf("Expected: %+v, but got: %+v", expected, result)
+	}
+}
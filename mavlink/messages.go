@@ -0,0 +1,176 @@
+package mavlink
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+)
+
+// MAVLink message IDs
+const (
+	MSG_HEARTBEAT = 0
+	MSG_HIL_GPS   = 113
+	MSG_GPS_INPUT = 232
+)
+
+// MAV_TYPE, MAV_AUTOPILOT and MAV_STATE values used in heartbeats
+const (
+	MAV_TYPE_ONBOARD_CONTROLLER = 18
+	MAV_AUTOPILOT_INVALID       = 8
+	MAV_STATE_ACTIVE            = 4
+)
+
+// GPS_FIX_TYPE values
+const (
+	GPS_FIX_TYPE_NO_GPS = 0
+	GPS_FIX_TYPE_NO_FIX = 1
+	GPS_FIX_TYPE_2D_FIX = 2
+	GPS_FIX_TYPE_3D_FIX = 3
+)
+
+// Heartbeat is a HEARTBEAT message, which every MAVLink component sends once a second
+type Heartbeat struct {
+	CustomMode   uint32
+	Type         uint8
+	Autopilot    uint8
+	BaseMode     uint8
+	SystemStatus uint8
+}
+
+// ID returns the message ID
+func (h *Heartbeat) ID() uint32 { return MSG_HEARTBEAT }
+
+// CRCExtra returns the checksum seed
+func (h *Heartbeat) CRCExtra() byte { return crcExtras[MSG_HEARTBEAT] }
+
+// Marshal returns the payload of the message
+func (h *Heartbeat) Marshal() []byte {
+	p := make([]byte, 9)
+	binary.LittleEndian.PutUint32(p[0:], h.CustomMode)
+	p[4] = h.Type
+	p[5] = h.Autopilot
+	p[6] = h.BaseMode
+	p[7] = h.SystemStatus
+	p[8] = 3 // MAVLink version
+	return p
+}
+
+// UnmarshalHeartbeat returns the heartbeat in a payload
+func UnmarshalHeartbeat(payload []byte) Heartbeat {
+	p := pad(payload, 9)
+	return Heartbeat{
+		CustomMode:   binary.LittleEndian.Uint32(p[0:]),
+		Type:         p[4],
+		Autopilot:    p[5],
+		BaseMode:     p[6],
+		SystemStatus: p[7],
+	}
+}
+
+// GPSInput is a GPS_INPUT message, which ArduPilot accepts as a GPS when GPS_TYPE is MAV
+type GPSInput struct {
+	Time          time.Time // UTC time of the fix
+	GPSID         uint8
+	IgnoreFlags   uint16
+	FixType       uint8
+	Lat, Lon      float64 // degrees
+	Alt           float64 // meters above mean sea level
+	HDOP, VDOP    float64
+	VN, VE, VD    float64 // velocity north, east and down in m/s
+	SpeedAccuracy float64 // m/s
+	HorizAccuracy float64 // meters
+	VertAccuracy  float64 // meters
+	Satellites    uint8
+	Yaw           float64 // true heading of the vehicle in degrees
+}
+
+// ID returns the message ID
+func (g *GPSInput) ID() uint32 { return MSG_GPS_INPUT }
+
+// CRCExtra returns the checksum seed
+func (g *GPSInput) CRCExtra() byte { return crcExtras[MSG_GPS_INPUT] }
+
+// Marshal returns the payload of the message
+func (g *GPSInput) Marshal() []byte {
+	p := make([]byte, 65)
+	le := binary.LittleEndian
+	week, tow := gnss.GPSTime(g.Time)
+	le.PutUint64(p[0:], uint64(g.Time.UnixMicro()))
+	le.PutUint32(p[8:], uint32(tow/time.Millisecond))
+	le.PutUint32(p[12:], uint32(int32(math.Round(g.Lat*1e7))))
+	le.PutUint32(p[16:], uint32(int32(math.Round(g.Lon*1e7))))
+	le.PutUint32(p[20:], math.Float32bits(float32(g.Alt)))
+	le.PutUint32(p[24:], math.Float32bits(float32(g.HDOP)))
+	le.PutUint32(p[28:], math.Float32bits(float32(g.VDOP)))
+	le.PutUint32(p[32:], math.Float32bits(float32(g.VN)))
+	le.PutUint32(p[36:], math.Float32bits(float32(g.VE)))
+	le.PutUint32(p[40:], math.Float32bits(float32(g.VD)))
+	le.PutUint32(p[44:], math.Float32bits(float32(g.SpeedAccuracy)))
+	le.PutUint32(p[48:], math.Float32bits(float32(g.HorizAccuracy)))
+	le.PutUint32(p[52:], math.Float32bits(float32(g.VertAccuracy)))
+	le.PutUint16(p[56:], g.IgnoreFlags)
+	le.PutUint16(p[58:], uint16(week))
+	p[60] = g.GPSID
+	p[61] = g.FixType
+	p[62] = g.Satellites
+	le.PutUint16(p[63:], yaw(g.Yaw))
+	return p
+}
+
+// HILGPS is a HIL_GPS message, which PX4 and ArduPilot SITL accept as a simulated GPS
+type HILGPS struct {
+	Time       time.Time // UTC time of the fix
+	FixType    uint8
+	Lat, Lon   float64 // degrees
+	Alt        float64 // meters above mean sea level
+	HDOP, VDOP float64
+	VN, VE, VD float64 // velocity north, east and down in m/s
+	COG        float64 // course over ground in degrees
+	Satellites uint8
+	GPSID      uint8
+	Yaw        float64 // true heading of the vehicle in degrees
+}
+
+// ID returns the message ID
+func (h *HILGPS) ID() uint32 { return MSG_HIL_GPS }
+
+// CRCExtra returns the checksum seed
+func (h *HILGPS) CRCExtra() byte { return crcExtras[MSG_HIL_GPS] }
+
+// Marshal returns the payload of the message
+func (h *HILGPS) Marshal() []byte {
+	p := make([]byte, 39)
+	le := binary.LittleEndian
+	le.PutUint64(p[0:], uint64(h.Time.UnixMicro()))
+	le.PutUint32(p[8:], uint32(int32(math.Round(h.Lat*1e7))))
+	le.PutUint32(p[12:], uint32(int32(math.Round(h.Lon*1e7))))
+	le.PutUint32(p[16:], uint32(int32(math.Round(h.Alt*1000))))
+	le.PutUint16(p[20:], uint16(math.Round(h.HDOP*100)))
+	le.PutUint16(p[22:], uint16(math.Round(h.VDOP*100)))
+	le.PutUint16(p[24:], uint16(math.Round(math.Hypot(h.VN, h.VE)*100)))
+	le.PutUint16(p[26:], uint16(int16(math.Round(h.VN*100))))
+	le.PutUint16(p[28:], uint16(int16(math.Round(h.VE*100))))
+	le.PutUint16(p[30:], uint16(int16(math.Round(h.VD*100))))
+	le.PutUint16(p[32:], uint16(math.Round(heading(h.COG)*100))%36000)
+	p[34] = h.FixType
+	p[35] = h.Satellites
+	p[36] = h.GPSID
+	le.PutUint16(p[37:], yaw(h.Yaw))
+	return p
+}
+
+// yaw returns a heading in centidegrees, where 0 means unknown so north is 36000
+func yaw(h float64) uint16 {
+	y := uint16(math.Round(heading(h)*100)) % 36000
+	if y == 0 {
+		return 36000
+	}
+	return y
+}
+
+// heading will limit a heading in degrees to [0, 360)
+func heading(h float64) float64 {
+	return math.Mod(math.Mod(h, 360)+360, 360)
+}
@@ -0,0 +1,127 @@
+package mavlink
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+var ts = time.Date(2022, time.January, 1, 0, 0, 1, 500000000, time.UTC)
+
+func TestGPSInput(t *testing.T) {
+	g := &GPSInput{
+		Time:          ts,
+		FixType:       GPS_FIX_TYPE_3D_FIX,
+		Lat:           45.1234567,
+		Lon:           -75.7654321,
+		Alt:           1234.5,
+		HDOP:          0.5,
+		VDOP:          0.8,
+		VN:            -10.25,
+		VE:            20.5,
+		VD:            1.5,
+		SpeedAccuracy: 0.1,
+		HorizAccuracy: 1,
+		VertAccuracy:  1.5,
+		Satellites:    12,
+		Yaw:           360,
+	}
+	p := g.Marshal()
+	le := binary.LittleEndian
+
+	if len(p) != 65 {
+		t.Fatalf("Expected length: 65, but got: %d", len(p))
+	}
+	if v := le.Uint64(p[0:]); v != uint64(ts.UnixMicro()) {
+		t.Errorf("Expected time_usec: %d, but got: %d", ts.UnixMicro(), v)
+	}
+	if v := le.Uint32(p[8:]); v != 6*24*3600000+19500 {
+		t.Errorf("Expected time_week_ms: %d, but got: %d", 6*24*3600000+19500, v)
+	}
+	if v := int32(le.Uint32(p[12:])); v != 451234567 {
+		t.Errorf("Expected lat: 451234567, but got: %d", v)
+	}
+	if v := int32(le.Uint32(p[16:])); v != -757654321 {
+		t.Errorf("Expected lon: -757654321, but got: %d", v)
+	}
+	floats := []struct {
+		name     string
+		offset   int
+		expected float32
+	}{
+		{"alt", 20, 1234.5}, {"hdop", 24, 0.5}, {"vdop", 28, 0.8}, {"vn", 32, -10.25}, {"ve", 36, 20.5},
+		{"vd", 40, 1.5}, {"speed_accuracy", 44, 0.1}, {"horiz_accuracy", 48, 1}, {"vert_accuracy", 52, 1.5},
+	}
+	for _, f := range floats {
+		if v := math.Float32frombits(le.Uint32(p[f.offset:])); v != f.expected {
+			t.Errorf("Expected %s: %f, but got: %f", f.name, f.expected, v)
+		}
+	}
+	if v := le.Uint16(p[58:]); v != 2190 {
+		t.Errorf("Expected time_week: 2190, but got: %d", v)
+	}
+	if p[61] != GPS_FIX_TYPE_3D_FIX || p[62] != 12 {
+		t.Errorf("Expected a 3D fix with 12 satellites, but got: %d with %d", p[61], p[62])
+	}
+	if v := le.Uint16(p[63:]); v != 36000 {
+		t.Errorf("Expected north yaw of 36000, but got: %d", v)
+	}
+}
+
+func TestHILGPS(t *testing.T) {
+	h := &HILGPS{
+		Time:       ts,
+		FixType:    GPS_FIX_TYPE_3D_FIX,
+		Lat:        45.1234567,
+		Lon:        -75.7654321,
+		Alt:        1234.5,
+		HDOP:       0.5,
+		VDOP:       0.8,
+		VN:         -10.25,
+		VE:         20.5,
+		VD:         1.5,
+		COG:        -45,
+		Satellites: 12,
+		Yaw:        90,
+	}
+	p := h.Marshal()
+	le := binary.LittleEndian
+
+	if len(p) != 39 {
+		t.Fatalf("Expected length: 39, but got: %d", len(p))
+	}
+	int32s := []struct {
+		name     string
+		offset   int
+		expected int32
+	}{
+		{"lat", 8, 451234567}, {"lon", 12, -757654321}, {"alt", 16, 1234500},
+	}
+	for _, f := range int32s {
+		if v := int32(le.Uint32(p[f.offset:])); v != f.expected {
+			t.Errorf("Expected %s: %d, but got: %d", f.name, f.expected, v)
+		}
+	}
+	int16s := []struct {
+		name     string
+		offset   int
+		expected int16
+	}{
+		{"eph", 20, 50}, {"epv", 22, 80}, {"vel", 24, 2292}, {"vn", 26, -1025}, {"ve", 28, 2050}, {"vd", 30, 150},
+	}
+	for _, f := range int16s {
+		if v := int16(le.Uint16(p[f.offset:])); v != f.expected {
+			t.Errorf("Expected %s: %d, but got: %d", f.name, f.expected, v)
+		}
+	}
+	if v := le.Uint16(p[32:]); v != 31500 {
+		t.Errorf("Expected cog: 31500, but got: %d", v)
+	}
+	if p[34] != GPS_FIX_TYPE_3D_FIX || p[35] != 12 {
+		t.Errorf("Expected a 3D fix with 12 satellites, but got: %d with %d", p[34], p[35])
+	}
+	if v := le.Uint16(p[37:]); v != 9000 {
+		t.Errorf("Expected yaw: 9000, but got: %d", v)
+	}
+}
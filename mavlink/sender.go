@@ -0,0 +1,232 @@
+package mavlink
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"go.bug.st/serial"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// DEFAULT_UDP_ADDR is the address MAVLink is sent to over UDP, where mavlink-router or MAVProxy usually listen
+const DEFAULT_UDP_ADDR = "127.0.0.1:14550"
+
+const (
+	// HEARTBEAT_INTERVAL is how often heartbeats are sent
+	HEARTBEAT_INTERVAL = time.Second
+	// HEARTBEAT_TIMEOUT is how long without an autopilot heartbeat before the autopilot is reported lost
+	HEARTBEAT_TIMEOUT = 3 * time.Second
+)
+
+// Logger is the default logger for the mavlink package
+var Logger = slog.Default()
+
+// Sender is an object that will send positions as MAVLink GPS messages over a serial port or UDP
+// It sends a heartbeat every second, and reports when an autopilot's heartbeats are seen or lost
+type Sender struct {
+	// Message is the GPS message to send, MSG_GPS_INPUT or MSG_HIL_GPS
+	Message uint32
+	// Encoder holds the system and component IDs of the connector
+	Encoder Encoder
+
+	name string
+	open func() (io.ReadWriteCloser, error)
+}
+
+// NewUDPSender returns a new Sender that sends to addr, eg "127.0.0.1:14550"
+// Heartbeats from the autopilot are read from the same socket
+func NewUDPSender(addr string) (*Sender, error) {
+	a, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve MAVLink address %s: %v", addr, err)
+	}
+	return newSender(a.String(), func() (io.ReadWriteCloser, error) {
+		conn, err := net.ListenUDP("udp", nil)
+		if err != nil {
+			return nil, err
+		}
+		return &udpConn{UDPConn: conn, addr: a}, nil
+	}), nil
+}
+
+// NewSerialSender returns a new Sender that sends to a serial port at baud
+func NewSerialSender(port string, baud int) *Sender {
+	return newSender(port, func() (io.ReadWriteCloser, error) {
+		return serial.Open(port, &serial.Mode{BaudRate: baud, Parity: serial.NoParity, DataBits: 8})
+	})
+}
+
+// newSender returns a new Sender of GPS_INPUT messages using open to connect
+func newSender(name string, open func() (io.ReadWriteCloser, error)) *Sender {
+	return &Sender{
+		Message: MSG_GPS_INPUT,
+		Encoder: Encoder{SystemID: DEFAULT_SYSTEM_ID, ComponentID: DEFAULT_COMPONENT_ID},
+		name:    name,
+		open:    open,
+	}
+}
+
+// SendPositions will send a GPS message for each position from the channel, and a heartbeat every second
+func (s *Sender) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
+	Logger.Debug("SendPositions Started", "to", s.name)
+
+	conn, err := s.open()
+	if err != nil {
+		Logger.Error("Failed to open MAVLink connection", "to", s.name, "err", err)
+		feedback <- "Failed to open MAVLink connection"
+		return err
+	}
+
+	// read heartbeats from the autopilot until the connection is closed
+	heartbeats := make(chan byte, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readHeartbeats(conn, heartbeats)
+	}()
+	defer func() {
+		conn.Close()
+		<-done
+		Logger.Debug("MAVLink connection closed")
+	}()
+
+	send := func(m Message) {
+		if _, err := conn.Write(s.Encoder.Encode(m)); err != nil {
+			Logger.Warn("Write failed", "err", err)
+			feedback <- "MAVLink write failed"
+		}
+	}
+	heartbeat := &Heartbeat{
+		Type:         MAV_TYPE_ONBOARD_CONTROLLER,
+		Autopilot:    MAV_AUTOPILOT_INVALID,
+		SystemStatus: MAV_STATE_ACTIVE,
+	}
+
+	ticker := time.NewTicker(HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	var lastSeen time.Time
+	send(heartbeat)
+	for {
+		select {
+		case <-ticker.C:
+			send(heartbeat)
+			if !lastSeen.IsZero() && time.Since(lastSeen) > HEARTBEAT_TIMEOUT {
+				Logger.Warn("Autopilot lost")
+				feedback <- "Autopilot lost"
+				lastSeen = time.Time{}
+			}
+		case sysid := <-heartbeats:
+			if lastSeen.IsZero() {
+				Logger.Info("Autopilot connected", "system", sysid)
+				feedback <- fmt.Sprintf("Autopilot %d connected", sysid)
+			}
+			lastSeen = time.Now()
+		case pos, ok := <-c:
+			if !ok {
+				return nil
+			}
+			send(s.gpsMessage(pos, pos.FixTime()))
+		}
+	}
+}
+
+// gpsMessage returns the GPS message for a position at t
+func (s *Sender) gpsMessage(p xplane.Position, t time.Time) Message {
+	q := p.Quality.OrNominal()
+	sats := uint8(gnss.Used(p.Sky(t)))
+	// MAVLink has no dead reckoning fix, so the autopilot is told there is no fix and does its own
+	fix := uint8(GPS_FIX_TYPE_3D_FIX)
+	switch q.Fix {
+	case gnss.FIX_2D:
+		fix = GPS_FIX_TYPE_2D_FIX
+	case gnss.FIX_DR, gnss.FIX_NONE:
+		fix = GPS_FIX_TYPE_NO_FIX
+	}
+	// X-Plane's velocities are east, up and south
+	vn, ve, vd := -float64(p.Vz_wrl), float64(p.Vx_wrl), -float64(p.Vy_wrl)
+
+	if s.Message == MSG_HIL_GPS {
+		return &HILGPS{
+			Time:       t,
+			FixType:    fix,
+			Lat:        p.Dat_lat,
+			Lon:        p.Dat_lon,
+			Alt:        p.Dat_ele,
+			HDOP:       q.HDOP,
+			VDOP:       q.VDOP,
+			VN:         vn,
+			VE:         ve,
+			VD:         vd,
+			COG:        p.Track(),
+			Satellites: sats,
+			Yaw:        float64(p.Veh_psi_loc),
+		}
+	}
+	return &GPSInput{
+		Time:          t,
+		FixType:       fix,
+		Lat:           p.Dat_lat,
+		Lon:           p.Dat_lon,
+		Alt:           p.Dat_ele,
+		HDOP:          q.HDOP,
+		VDOP:          q.VDOP,
+		VN:            vn,
+		VE:            ve,
+		VD:            vd,
+		SpeedAccuracy: q.SAcc,
+		HorizAccuracy: q.HAcc,
+		VertAccuracy:  q.VAcc,
+		Satellites:    sats,
+		Yaw:           float64(p.Veh_psi_loc),
+	}
+}
+
+// readHeartbeats will read frames from r until it fails, and send the system ID of autopilot heartbeats to c
+func readHeartbeats(r io.Reader, c chan<- byte) {
+	br := bufio.NewReader(r)
+	for {
+		f, err := ReadFrame(br)
+		if errors.Is(err, ErrInvalidFrame) {
+			continue
+		}
+		if err != nil {
+			Logger.Debug("Heartbeat reader stopped", "err", err)
+			return
+		}
+		if f.MessageID != MSG_HEARTBEAT {
+			continue
+		}
+		if hb := UnmarshalHeartbeat(f.Payload); hb.Autopilot == MAV_AUTOPILOT_INVALID {
+			continue
+		}
+		select {
+		case c <- f.SystemID:
+		default:
+		}
+	}
+}
+
+// udpConn is a UDP socket that writes to, and reads from, a single remote address
+type udpConn struct {
+	*net.UDPConn
+	addr *net.UDPAddr
+}
+
+// Write will write a datagram to the remote address
+func (u *udpConn) Write(b []byte) (int, error) {
+	return u.WriteToUDP(b, u.addr)
+}
+
+// Read will read a datagram
+func (u *udpConn) Read(b []byte) (int, error) {
+	n, _, err := u.ReadFromUDP(b)
+	return n, err
+}
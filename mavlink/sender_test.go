@@ -0,0 +1,97 @@
+package mavlink
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestSendPositions(t *testing.T) {
+	// pretend to be an autopilot
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+	defer conn.Close()
+
+	s, err := NewUDPSender(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+
+	// the connector's heartbeat comes first, reply with an autopilot heartbeat
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, from, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Expected a heartbeat, but got: %v", err)
+	}
+	f, err := ReadFrame(bufio.NewReader(bytes.NewReader(buf[:n])))
+	if err != nil || f.MessageID != MSG_HEARTBEAT {
+		t.Fatalf("Expected a heartbeat, but got: %+v %v", f, err)
+	}
+	autopilot := Encoder{SystemID: 1, ComponentID: 1}
+	conn.WriteToUDP(autopilot.Encode(&Heartbeat{Type: 1, Autopilot: 3}), from)
+
+	select {
+	case msg := <-feedback:
+		if msg != "Autopilot 1 connected" {
+			t.Errorf("Expected: Autopilot 1 connected, but got: %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the autopilot to be connected")
+	}
+
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 100}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		n, _, err = conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("Expected GPS_INPUT, but got: %v", err)
+		}
+		f, err = ReadFrame(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil {
+			t.Fatalf("Expected a valid frame, but got: %v", err)
+		}
+		if f.MessageID != MSG_HEARTBEAT {
+			break
+		}
+	}
+	if f.MessageID != MSG_GPS_INPUT || f.ComponentID != DEFAULT_COMPONENT_ID {
+		t.Errorf("Expected GPS_INPUT from the GPS component, but got: %+v", f)
+	}
+
+	close(c)
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+}
+
+func TestGPSMessage(t *testing.T) {
+	s := newSender("test", nil)
+	pos := xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 100, Vx_wrl: 10, Vy_wrl: -1, Vz_wrl: -20, Veh_psi_loc: 30}
+
+	g, ok := s.gpsMessage(pos, ts).(*GPSInput)
+	if !ok {
+		t.Fatalf("Expected GPS_INPUT by default")
+	}
+	if g.VN != 20 || g.VE != 10 || g.VD != 1 {
+		t.Errorf("Expected NED velocity 20, 10, 1, but got: %f, %f, %f", g.VN, g.VE, g.VD)
+	}
+	if g.Yaw != 30 || g.Satellites < 4 {
+		t.Errorf("Expected yaw 30 with at least 4 satellites, but got: %f with %d", g.Yaw, g.Satellites)
+	}
+
+	s.Message = MSG_HIL_GPS
+	if _, ok := s.gpsMessage(pos, ts).(*HILGPS); !ok {
+		t.Errorf("Expected HIL_GPS")
+	}
+}
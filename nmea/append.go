@@ -0,0 +1,395 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// The Append functions are the same as the To functions, but append the message to a buffer instead of returning a
+// string. They produce exactly the same bytes, and don't allocate when the buffer has room, so the same buffer
+// can be reused for every message at high rates.
+
+// pow10 and ipow10 are the powers of ten for the fixed point formatting, up to the most decimal places of any value
+var (
+	pow10  = [...]float64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+	ipow10 = [...]uint64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+)
+
+// hexDigits are the digits of the checksum
+const hexDigits = "0123456789ABCDEF"
+
+// AppendGGAFix will append a NMEA GGA message to b like ToGGAFix, and return the extended buffer
+func AppendGGAFix(b []byte, talker TalkerID, t time.Time, lat float64, lon float64, alt float64, sep float64, quality uint, satellites uint, hdop float64) []byte {
+	start := len(b)
+	prec := CurrentFormats().prec
+	b = appendGGA(b, talker, t, lat, lon, alt, sep, quality, satellites, hdop, prec)
+	if len(b)-start > MAX_SENTENCE_LENGTH && prec.lat != DEFAULTS.prec.lat {
+		// the extra decimal places don't fit, so the position is sent with the default precision
+		prec.lat, prec.lon = DEFAULTS.prec.lat, DEFAULTS.prec.lon
+		b = appendGGA(b[:start], talker, t, lat, lon, alt, sep, quality, satellites, hdop, prec)
+	}
+	return b
+}
+
+// appendGGA will append a GGA message with the precisions prec
+func appendGGA(b []byte, talker TalkerID, t time.Time, lat float64, lon float64, alt float64, sep float64, quality uint, satellites uint, hdop float64, prec precisions) []byte {
+	b, start := startSentence(b, talker, "GGA")
+	b = append(b, ',')
+	b = appendTime(b, t.UTC())
+	b = append(b, ',')
+	b = appendLL(b, lat, LAT_RUNES, 2, prec.lat)
+	b = append(b, ',')
+	b = appendLL(b, lon, LON_RUNES, 3, prec.lon)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(quality), 10)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, uint64(satellites), 10)
+	b = append(b, ',')
+	b = appendFloat(b, hdop, 1)
+	b = append(b, ',')
+	b = appendFloat(b, alt, prec.alt)
+	b = append(b, ",M,"...)
+	b = appendFloat(b, sep, SEP_PRECISION)
+	// the DGPS age and station are empty
+	b = append(b, ",M,,"...)
+	return endSentence(b, start)
+}
+
+// AppendRMCFix will append a NMEA RMC message to b like ToRMCFix, and return the extended buffer
+func AppendRMCFix(b []byte, talker TalkerID, t time.Time, lat float64, lon float64, sog float64, course float64, variation float64, status string, mode string) []byte {
+	start := len(b)
+	prec := CurrentFormats().prec
+	b = appendRMC(b, talker, t, lat, lon, sog, course, variation, status, mode, prec)
+	if len(b)-start > MAX_SENTENCE_LENGTH && prec.lat != DEFAULTS.prec.lat {
+		// the extra decimal places don't fit, so the position is sent with the default precision
+		prec.lat, prec.lon = DEFAULTS.prec.lat, DEFAULTS.prec.lon
+		b = appendRMC(b[:start], talker, t, lat, lon, sog, course, variation, status, mode, prec)
+	}
+	return b
+}
+
+// appendRMC will append a RMC message with the precisions prec
+func appendRMC(b []byte, talker TalkerID, t time.Time, lat float64, lon float64, sog float64, course float64, variation float64, status string, mode string, prec precisions) []byte {
+	t = t.UTC()
+	b, start := startSentence(b, talker, "RMC")
+	b = append(b, ',')
+	b = appendTime(b, t)
+	b = append(b, ',')
+	b = append(b, status...)
+	b = append(b, ',')
+	b = appendLL(b, lat, LAT_RUNES, 2, prec.lat)
+	b = append(b, ',')
+	b = appendLL(b, lon, LON_RUNES, 3, prec.lon)
+	b = append(b, ',')
+	b = appendFloat(b, sog*1.943845249221964, prec.spd)
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(course), prec.hdg)
+	b = append(b, ',')
+	b = appendDate(b, t)
+	b = append(b, ',')
+	b = appendVariation(b, variation)
+	b = append(b, ',')
+	b = append(b, mode...)
+	return endSentence(b, start)
+}
+
+// AppendVTGMode will append a NMEA VTG message to b like ToVTGMode, and return the extended buffer
+func AppendVTGMode(b []byte, talker TalkerID, heading float64, variation float64, sog float64, mode string) []byte {
+	prec := CurrentFormats().prec
+	b, start := startSentence(b, talker, "VTG")
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading), prec.hdg)
+	b = append(b, ",T,"...)
+	b = appendFloat(b, normaliseHeading(heading-variation), prec.hdg)
+	b = append(b, ",M,"...)
+	b = appendFloat(b, sog*1.943845249221964, prec.sog)
+	b = append(b, ",N,"...)
+	b = appendFloat(b, sog*3.6, prec.sog)
+	b = append(b, ",K,"...)
+	b = append(b, mode...)
+	return endSentence(b, start)
+}
+
+// AppendGSA will append a NMEA GSA message to b like ToGSA, and return the extended buffer
+func AppendGSA(b []byte, talker TalkerID, fixType int, prns []int, pdop float64, hdop float64, vdop float64) []byte {
+	b, start := startSentence(b, talker, "GSA")
+	b = append(b, ",A,"...)
+	b = strconv.AppendInt(b, int64(fixType), 10)
+	for i := 0; i < GSA_SATELLITES; i++ {
+		b = append(b, ',')
+		if i < len(prns) {
+			b = appendIntPad(b, int64(prns[i]), 2)
+		}
+	}
+	b = append(b, ',')
+	b = appendFloat(b, pdop, 1)
+	b = append(b, ',')
+	b = appendFloat(b, hdop, 1)
+	b = append(b, ',')
+	b = appendFloat(b, vdop, 1)
+	return endSentence(b, start)
+}
+
+// AppendHDT will append a NMEA HDT message to b like ToHDT, and return the extended buffer
+func AppendHDT(b []byte, talker TalkerID, heading float64) []byte {
+	b, start := startSentence(b, talker, "HDT")
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading), CurrentFormats().prec.hdg)
+	b = append(b, ",T"...)
+	return endSentence(b, start)
+}
+
+// AppendHDM will append a NMEA HDM message to b like ToHDM, and return the extended buffer
+func AppendHDM(b []byte, talker TalkerID, heading float64, variation float64) []byte {
+	b, start := startSentence(b, talker, "HDM")
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading-variation), CurrentFormats().prec.hdg)
+	b = append(b, ",M"...)
+	return endSentence(b, start)
+}
+
+// AppendHDG will append a NMEA HDG message to b like ToHDG, and return the extended buffer
+func AppendHDG(b []byte, talker TalkerID, heading float64, deviation float64, variation float64) []byte {
+	b, start := startSentence(b, talker, "HDG")
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading-variation-deviation), CurrentFormats().prec.hdg)
+	b = append(b, ',')
+	b = appendVariation(b, deviation)
+	b = append(b, ',')
+	b = appendVariation(b, variation)
+	return endSentence(b, start)
+}
+
+// AppendTHS will append a NMEA THS message to b like ToTHS, and return the extended buffer
+func AppendTHS(b []byte, talker TalkerID, heading float64, mode string) []byte {
+	if mode == "" {
+		mode = THS_AUTONOMOUS
+	}
+	b, start := startSentence(b, talker, "THS")
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading), CurrentFormats().prec.hdg)
+	b = append(b, ',')
+	b = append(b, mode...)
+	return endSentence(b, start)
+}
+
+// AppendROT will append a NMEA ROT message to b like ToROT, and return the extended buffer
+func AppendROT(b []byte, talker TalkerID, rate float64) []byte {
+	b, start := startSentence(b, talker, "ROT")
+	b = append(b, ',')
+	b = appendFloat(b, rate*60, 1)
+	// A is for valid data
+	b = append(b, ",A"...)
+	return endSentence(b, start)
+}
+
+// AppendPASHRTime will append a proprietary PASHR message to b like ToPASHRTime, and return the extended buffer
+func AppendPASHRTime(b []byte, t time.Time, heading float64, roll float64, pitch float64) []byte {
+	start := len(b)
+	b = append(b, "$PASHR,"...)
+	b = appendTime(b, t.UTC())
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading), 2)
+	b = append(b, ",T,"...)
+	b = appendSignedFloatPad(b, roll, 6, 2)
+	b = append(b, ',')
+	b = appendSignedFloatPad(b, pitch, 6, 2)
+	// heave is not simulated, the attitude is perfect, GPS aided and the IMU is satisfactory
+	b = append(b, ",+00.00,0.010,0.010,0.010,1,1"...)
+	return endSentence(b, start)
+}
+
+// AppendPSATHPRTime will append a proprietary PSAT,HPR message to b like ToPSATHPRTime, and return the extended
+// buffer
+func AppendPSATHPRTime(b []byte, t time.Time, heading float64, pitch float64, roll float64) []byte {
+	start := len(b)
+	b = append(b, "$PSAT,HPR,"...)
+	// the time is to hundredths of a second, so the last digit of the milliseconds is truncated
+	b = appendTime(b, t.UTC())
+	b = b[:len(b)-1]
+	b = append(b, ',')
+	b = appendFloat(b, normaliseHeading(heading), 2)
+	b = append(b, ',')
+	b = appendFloat(b, pitch, 2)
+	b = append(b, ',')
+	b = appendFloat(b, roll, 2)
+	// N is for GNSS derived attitude
+	b = append(b, ",N"...)
+	return endSentence(b, start)
+}
+
+// AppendSentence will append the sentence with the body to b, framed with the $, checksum and CR LF
+// The body is everything between the $ and the checksum, eg "PXYZ,1,2".
+func AppendSentence(b []byte, body string) []byte {
+	start := len(b)
+	b = append(b, '$')
+	b = append(b, body...)
+	return endSentence(b, start)
+}
+
+// startSentence will append the $, talker ID and sentence type (eg "GGA") to b, and return the extended buffer
+// and where the sentence starts in it
+func startSentence(b []byte, talker TalkerID, kind string) ([]byte, int) {
+	start := len(b)
+	b = append(b, '$')
+	b = append(b, talkerOrDefault(talker)...)
+	b = append(b, kind...)
+	return b, start
+}
+
+// endSentence will append the checksum of the sentence that starts at start in b, and the CR LF
+// The checksum is of the bytes, which is the same as calculateChecksum for the ASCII of NMEA sentences
+func endSentence(b []byte, start int) []byte {
+	cs := byte(0)
+	for _, c := range b[start+1:] {
+		cs ^= c
+	}
+	return append(b, '*', hexDigits[cs>>4], hexDigits[cs&0x0F], '\r', '\n')
+}
+
+// appendTime will append the time of day like t.Format("150405.000")
+func appendTime(b []byte, t time.Time) []byte {
+	h, m, s := t.Clock()
+	b = appendUintPad(b, uint64(h), 2)
+	b = appendUintPad(b, uint64(m), 2)
+	b = appendUintPad(b, uint64(s), 2)
+	b = append(b, '.')
+	// the milliseconds are truncated, like time.Format
+	return appendUintPad(b, uint64(t.Nanosecond()/int(time.Millisecond)), 3)
+}
+
+// appendDate will append the date like t.Format("020106")
+func appendDate(b []byte, t time.Time) []byte {
+	y, m, d := t.Date()
+	b = appendUintPad(b, uint64(d), 2)
+	b = appendUintPad(b, uint64(m), 2)
+	return appendUintPad(b, uint64(y%100), 2)
+}
+
+// appendLL will append a latitude or longitude like calculateLL, with the degrees zero padded to width digits and
+// the minutes to prec decimal places
+// Like calculateLL, minutes that round up are not carried into the degrees, so 59.99999 minutes is 60.0000
+func appendLL(b []byte, v float64, ds [2]rune, width int, prec int) []byte {
+	vA := math.Abs(v)
+	if !(vA < 1e9) {
+		// not a real position, so leave the formatting of NaN and infinities to calculateLL
+		return append(b, calculateLL(v, ds, fmt.Sprintf("%%0%dd%%0%d.%df", width, prec+3, prec))...)
+	}
+	vDegrees := math.Floor(vA)
+	vMinutes := (vA - vDegrees) * 60
+
+	b = appendUintPad(b, uint64(vDegrees), width)
+	b = appendFloatPad(b, vMinutes, prec+3, prec)
+	b = append(b, ',')
+	if v < 0 {
+		return append(b, byte(ds[1]))
+	}
+	return append(b, byte(ds[0]))
+}
+
+// appendVariation will append a magnetic variation like calculateVariation
+func appendVariation(b []byte, v float64) []byte {
+	b = appendFloat(b, math.Abs(v), 1)
+	if v < 0 {
+		return append(b, ",W"...)
+	}
+	return append(b, ",E"...)
+}
+
+// appendFloat will append v with prec decimal places, like fmt's %0.<prec>f
+// Most values are rounded to fixed point directly. Values that are too close to half way for the rounding of
+// the float to be certain, too large, or not numbers are left to strconv, which fmt uses.
+func appendFloat(b []byte, v float64, prec int) []byte {
+	if prec < 0 || prec >= len(pow10) {
+		return strconv.AppendFloat(b, v, 'f', prec, 64)
+	}
+	// x has a rounding error of up to x * 2^-53
+	x := math.Abs(v) * pow10[prec]
+	if !(x < 1e15) {
+		return strconv.AppendFloat(b, v, 'f', prec, 64)
+	}
+	n := math.Floor(x)
+	f := x - n
+	if math.Abs(f-0.5) <= x*1e-15 {
+		return strconv.AppendFloat(b, v, 'f', prec, 64)
+	}
+	r := uint64(n)
+	if f > 0.5 {
+		r++
+	}
+
+	// like fmt, negative values that round to zero keep their sign
+	if math.Signbit(v) {
+		b = append(b, '-')
+	}
+	b = strconv.AppendUint(b, r/ipow10[prec], 10)
+	if prec > 0 {
+		b = append(b, '.')
+		b = appendUintPad(b, r%ipow10[prec], prec)
+	}
+	return b
+}
+
+// appendSignedFloatPad will append v like appendFloatPad, but always with a sign like fmt's %+0<width>.<prec>f
+func appendSignedFloatPad(b []byte, v float64, width int, prec int) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		// fmt pads these with spaces, so leave them to it
+		return append(b, fmt.Sprintf("%+0*.*f", width, prec, v)...)
+	}
+	if math.Signbit(v) {
+		return appendFloatPad(b, v, width, prec)
+	}
+	b = append(b, '+')
+	return appendFloatPad(b, v, width-1, prec)
+}
+
+// appendFloatPad will append v like appendFloat, zero padded to width characters like fmt's %0<width>.<prec>f
+func appendFloatPad(b []byte, v float64, width int, prec int) []byte {
+	start := len(b)
+	b = appendFloat(b, v, prec)
+	pad := width - (len(b) - start)
+	if pad <= 0 {
+		return b
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		// fmt pads these with spaces, and at the front
+		return insert(b, start, ' ', pad)
+	}
+	if b[start] == '-' {
+		start++
+	}
+	return insert(b, start, '0', pad)
+}
+
+// appendUintPad will append v zero padded to width digits, like fmt's %0<width>d
+func appendUintPad(b []byte, v uint64, width int) []byte {
+	start := len(b)
+	b = strconv.AppendUint(b, v, 10)
+	if pad := width - (len(b) - start); pad > 0 {
+		b = insert(b, start, '0', pad)
+	}
+	return b
+}
+
+// appendIntPad will append v zero padded to width characters, including any sign, like fmt's %0<width>d
+func appendIntPad(b []byte, v int64, width int) []byte {
+	if v >= 0 {
+		return appendUintPad(b, uint64(v), width)
+	}
+	b = append(b, '-')
+	return appendUintPad(b, uint64(-v), width-1)
+}
+
+// insert will insert n copies of c into b at i
+func insert(b []byte, i int, c byte, n int) []byte {
+	for j := 0; j < n; j++ {
+		b = append(b, 0)
+	}
+	copy(b[i+n:], b[i:len(b)-n])
+	for j := i; j < i+n; j++ {
+		b[j] = c
+	}
+	return b
+}
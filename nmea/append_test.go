@@ -0,0 +1,186 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// specialValues are values that are hard to format, as fixed point rounding or padding can go wrong with them
+var specialValues = []float64{
+	0, math.Copysign(0, -1), -0.001, 0.125, 0.375, 1.005, 2.675, 0.05, 0.15, 0.25, 0.45, 9.95, 99.95, 359.9996,
+	359.9999, 45.9999999, 45.99999999, -75.49999999, 1234.5, 1e14, 1e15, 1e20, -1e20, 5e-324,
+	math.MaxFloat64, math.Inf(1), math.Inf(-1), math.NaN(),
+}
+
+// testTime is a time with milliseconds to truncate
+var testTime = time.Date(2025, time.March, 7, 9, 5, 3, 987654321, time.UTC)
+
+func TestAppendFloat(t *testing.T) {
+	for prec := 0; prec <= 9; prec++ {
+		for _, v := range specialValues {
+			expected := formatFloat(v, prec)
+			if got := string(appendFloat(nil, v, prec)); got != expected {
+				t.Errorf("Expected: %s, but got: %s (%v to %d places)", expected, got, v, prec)
+			}
+		}
+	}
+}
+
+func TestAppendEquivalence(t *testing.T) {
+	defer SetFormats(DEFAULTS)
+	for _, f := range []formats{DEFAULTS, ENHANCED} {
+		SetFormats(f)
+		for _, v := range specialValues {
+			checkEquivalence(t, testTime, v, v, v, GP, "A")
+		}
+		checkEquivalence(t, testTime, 45.123456, -75.654321, 123.4, "", "D")
+		// too long for the enhanced precision of the position
+		checkEquivalence(t, testTime, -89.999999, -179.999999, 999.999, GP, "D")
+		checkEquivalence(t, time.Date(1999, time.December, 31, 23, 59, 59, 999999999, time.FixedZone("X", 3600)), -33.9, 151.2, 18.5, GN, "E")
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	buf := make([]byte, 0, 512)
+	prns := []int{2, 5, 12, 25, 29}
+	allocs := testing.AllocsPerRun(100, func() {
+		b := AppendGGAFix(buf[:0], GP, testTime, 45.123456, -75.654321, 1234.5, -34.2, GGA_SIMULATED, 12, 0.9)
+		b = AppendRMCFix(b, GP, testTime, 45.123456, -75.654321, 61.7, 271.3, -13.1, STATUS_VALID, MODE_DIFFERENTIAL)
+		b = AppendVTGMode(b, GP, 271.3, -13.1, 61.7, MODE_DIFFERENTIAL)
+		b = AppendGSA(b, GP, GSA_3D, prns, 1.8, 0.9, 1.5)
+		b = AppendHDT(b, GP, 271.3)
+		b = AppendHDM(b, GP, 271.3, -13.1)
+		b = AppendHDG(b, GP, 271.3, 1.5, -13.1)
+		b = AppendTHS(b, GP, 271.3, "")
+		b = AppendROT(b, GP, 1.5)
+		b = AppendPASHRTime(b, testTime, 271.3, -1.26, 0.83)
+		AppendPSATHPRTime(b, testTime, 271.3, 0.83, -1.26)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected: %d allocations, but got: %v", 0, allocs)
+	}
+}
+
+// checkEquivalence will check that the Append functions produce the same bytes as the To functions
+func checkEquivalence(t *testing.T, ts time.Time, lat float64, lon float64, v float64, talker TalkerID, mode string) {
+	t.Helper()
+	check := func(name string, expected string, got []byte) {
+		t.Helper()
+		if string(got) != expected {
+			t.Errorf("%s: Expected: %q, but got: %q", name, expected, got)
+		}
+	}
+	// appending to a buffer with something in it must leave it alone
+	prefix := []byte("prefix")
+	after := func(b []byte) []byte {
+		if string(b[:len(prefix)]) != string(prefix) {
+			t.Errorf("Expected the buffer to start with: %q, but got: %q", prefix, b)
+		}
+		return b[len(prefix):]
+	}
+	prns := []int{int(v) % 100, 7, -3, 32}
+
+	check("GGA", ToGGAFix(talker, ts, lat, lon, v, -v, uint(math.Abs(v))%10, 12, v),
+		after(AppendGGAFix(append([]byte(nil), prefix...), talker, ts, lat, lon, v, -v, uint(math.Abs(v))%10, 12, v)))
+	check("RMC", ToRMCFix(talker, ts, lat, lon, v, v, -v, STATUS_VALID, mode),
+		after(AppendRMCFix(append([]byte(nil), prefix...), talker, ts, lat, lon, v, v, -v, STATUS_VALID, mode)))
+	check("VTG", ToVTGMode(talker, v, lat, lon, mode), after(AppendVTGMode(append([]byte(nil), prefix...), talker, v, lat, lon, mode)))
+	check("GSA", ToGSA(talker, GSA_3D, prns, v, lat, lon), after(AppendGSA(append([]byte(nil), prefix...), talker, GSA_3D, prns, v, lat, lon)))
+	check("HDT", ToHDT(talker, v), after(AppendHDT(append([]byte(nil), prefix...), talker, v)))
+	check("HDM", ToHDM(talker, v, lat), after(AppendHDM(append([]byte(nil), prefix...), talker, v, lat)))
+	check("HDG", ToHDG(talker, v, lat, lon), after(AppendHDG(append([]byte(nil), prefix...), talker, v, lat, lon)))
+	check("THS", ToTHS(talker, v, mode), after(AppendTHS(append([]byte(nil), prefix...), talker, v, mode)))
+	check("ROT", ToROT(talker, v), after(AppendROT(append([]byte(nil), prefix...), talker, v)))
+	check("PASHR", ToPASHRTime(ts, v, lat, lon), after(AppendPASHRTime(append([]byte(nil), prefix...), ts, v, lat, lon)))
+	check("PSAT,HPR", ToPSATHPRTime(ts, v, lat, lon), after(AppendPSATHPRTime(append([]byte(nil), prefix...), ts, v, lat, lon)))
+}
+
+// formatFloat returns v formatted like the To functions do
+func formatFloat(v float64, prec int) string {
+	return fmt.Sprintf("%0.*f", prec, v)
+}
+
+func FuzzAppendFloat(f *testing.F) {
+	for _, v := range specialValues {
+		f.Add(v, uint8(2))
+	}
+	f.Fuzz(func(t *testing.T, v float64, prec uint8) {
+		p := int(prec % 10)
+		if got, expected := string(appendFloat(nil, v, p)), formatFloat(v, p); got != expected {
+			t.Errorf("Expected: %s, but got: %s (%v to %d places)", expected, got, v, p)
+		}
+	})
+}
+
+func FuzzAppendSentences(f *testing.F) {
+	f.Add(int64(1741338303987), 45.123456, -75.654321, 123.4, false)
+	f.Add(int64(0), 89.99999999, 179.99999999, 0.125, true)
+	f.Add(int64(946684799999), -0.0000001, -0.0000001, -0.001, false)
+	f.Fuzz(func(t *testing.T, ms int64, lat float64, lon float64, v float64, enhanced bool) {
+		defer SetFormats(DEFAULTS)
+		if enhanced {
+			SetFormats(ENHANCED)
+		}
+		// times from 1970 to 2099, which NMEA's two digit years can tell apart
+		ts := time.UnixMilli(ms % 4102444800000).Add(time.Duration(ms%1000) * time.Microsecond)
+		checkEquivalence(t, ts, lat, lon, v, GP, MODE_AUTONOMOUS)
+	})
+}
+
+func BenchmarkGGA(b *testing.B) {
+	b.Run("To", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = []byte(ToGGAFix(GP, testTime, 45.123456, -75.654321, 1234.5, -34.2, GGA_SIMULATED, 12, 0.9))
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		buf := make([]byte, 0, 128)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = AppendGGAFix(buf[:0], GP, testTime, 45.123456, -75.654321, 1234.5, -34.2, GGA_SIMULATED, 12, 0.9)
+		}
+	})
+}
+
+func BenchmarkRMC(b *testing.B) {
+	b.Run("To", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = []byte(ToRMCFix(GP, testTime, 45.123456, -75.654321, 61.7, 271.3, -13.1, STATUS_VALID, MODE_DIFFERENTIAL))
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		buf := make([]byte, 0, 128)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = AppendRMCFix(buf[:0], GP, testTime, 45.123456, -75.654321, 61.7, 271.3, -13.1, STATUS_VALID, MODE_DIFFERENTIAL)
+		}
+	})
+}
+
+func BenchmarkVTG(b *testing.B) {
+	b.Run("To", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = []byte(ToVTGMode(GP, 271.3, -13.1, 61.7, MODE_DIFFERENTIAL))
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		buf := make([]byte, 0, 128)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = AppendVTGMode(buf[:0], GP, 271.3, -13.1, 61.7, MODE_DIFFERENTIAL)
+		}
+	})
+}
+
+func TestAppendSentence(t *testing.T) {
+	expected := "$PMTK001,220,3*30\r\n"
+	result := string(AppendSentence(nil, "PMTK001,220,3"))
+	if result != expected {
+		t.Errorf("Expected: %q, but got: %q", expected, result)
+	}
+}
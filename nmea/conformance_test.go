@@ -0,0 +1,216 @@
+package nmea
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentenceFields is the number of data fields (after the address field) each sentence type must have
+var sentenceFields = map[string]int{
+	"GGA": 14,
+	"GSA": 17,
+	"HDG": 5,
+	"HDM": 2,
+	"HDT": 2,
+	"RMC": 12,
+	"ROT": 2,
+	"THS": 2,
+	"VTG": 9,
+
+	// proprietary sentences are keyed by their whole address field
+	"PASHR":   11,
+	"PMTK001": 2,
+	"PSAT":    6,
+}
+
+// repeatingFields is the number of fixed fields, and the size of the repeated group of fields after them, for
+// sentences with a variable number of fields
+var repeatingFields = map[string]struct{ fixed, group int }{
+	"GSV": {3, 4},
+	"XDR": {0, 4},
+}
+
+// reservedChars may not appear in the data fields of a sentence
+const reservedChars = "\r\n$*!\\^~"
+
+// checkSentence will check that s is a valid NMEA 0183 sentence
+func checkSentence(s string) error {
+	if len(s) > MAX_SENTENCE_LENGTH {
+		return fmt.Errorf("sentence is %d characters long, max is %d", len(s), MAX_SENTENCE_LENGTH)
+	}
+	if !strings.HasPrefix(s, "$") {
+		return fmt.Errorf("sentence does not start with $")
+	}
+	if !strings.HasSuffix(s, "\r\n") {
+		return fmt.Errorf("sentence is not terminated with CRLF")
+	}
+
+	body, cs, ok := strings.Cut(strings.TrimSuffix(s[1:], "\r\n"), "*")
+	if !ok {
+		return fmt.Errorf("sentence has no checksum delimiter")
+	}
+	if len(cs) != 2 || strings.ToUpper(cs) != cs {
+		return fmt.Errorf("checksum %q is not 2 upper case hex digits", cs)
+	}
+	v, err := strconv.ParseUint(cs, 16, 8)
+	if err != nil {
+		return fmt.Errorf("checksum %q is not hex: %v", cs, err)
+	}
+	if byte(v) != calculateChecksum(body) {
+		return fmt.Errorf("checksum is %02X, expected %02X", v, calculateChecksum(body))
+	}
+
+	for _, c := range body {
+		if c < 0x20 || c > 0x7E {
+			return fmt.Errorf("invalid character %q", c)
+		}
+		if strings.ContainsRune(reservedChars, c) {
+			return fmt.Errorf("reserved character %q", c)
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	address := fields[0]
+	for _, c := range address {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return fmt.Errorf("invalid character %q in address field %q", c, address)
+		}
+	}
+
+	// proprietary sentences start with P and are followed by a manufacturer code, approved sentences have a 2
+	// character talker ID and a 3 character formatter
+	formatter := address
+	if !strings.HasPrefix(address, "P") {
+		if len(address) != 5 {
+			return fmt.Errorf("address field %q is not 5 characters", address)
+		}
+		formatter = address[2:]
+	}
+
+	if r, ok := repeatingFields[formatter]; ok {
+		if n := len(fields) - 1; n < r.fixed || (n-r.fixed)%r.group != 0 {
+			return fmt.Errorf("%s has %d fields, expected %d and a multiple of %d", formatter, n, r.fixed, r.group)
+		}
+		return nil
+	}
+	n, ok := sentenceFields[formatter]
+	if !ok {
+		return fmt.Errorf("unknown sentence type %q", formatter)
+	}
+	if len(fields)-1 != n {
+		return fmt.Errorf("%s has %d fields, expected %d", formatter, len(fields)-1, n)
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	defer SetFormats(DEFAULTS)
+	ts := time.Date(2022, time.January, 1, 23, 59, 59, 999000000, time.UTC)
+	// the widest values, and a satellite that isn't tracked in the last partial group
+	sats := []GSVSatellite{
+		{32, 90, 359, 99}, {31, 89, 358, 98}, {30, 88, 357, 97}, {29, 87, 356, 96}, {28, 86, 355, 95}, {27, 85, 354, 0},
+	}
+
+	testCases := []struct {
+		name      string
+		generator func() string
+	}{
+		{"GGA Zeros", func() string { return generateGGA(GP, ts, 0, 0, 0, 0, 0, 0, 0) }},
+		{"GGA North East", func() string { return generateGGA(GP, ts, 89.999999, 179.999999, 8, 12, 0.5, 12000, 50) }},
+		{"GGA South West", func() string { return generateGGA(GP, ts, -89.999999, -179.999999, 8, 12, 99.9, -400, -100) }},
+		{"GGA Worst Case", func() string {
+			return generateGGA(GP, ts, -89.999999, -179.999999, 8, 12, 0.5, 12000.5, -100.25)
+		}},
+		{"ToGPGGA", func() string { return ToGPGGA(45.123456, -75.654321, 1234.5) }},
+		{"VTG Zeros", func() string { return ToGPVTG(0, 0) }},
+		{"VTG Negative Heading", func() string { return ToGPVTG(-179.999, 12.3) }},
+		{"VTG Fast", func() string { return ToGPVTG(359.999, 999.999) }},
+		{"VTG Variation", func() string { return ToVTG(GP, 10, -179.999, 123.4) }},
+		{"RMC Zeros", func() string { return generateRMC(GP, ts, 0, 0, 0, 0, 0, STATUS_VALID, MODE_DIFFERENTIAL) }},
+		{"RMC Worst Case", func() string {
+			return generateRMC(GP, ts, -89.999999, -179.999999, 999.999, 359.999, -179.99, STATUS_VALID, MODE_DIFFERENTIAL)
+		}},
+		{"HDT", func() string { return ToHDT(GP, -0.001) }},
+		{"HDM", func() string { return ToHDM(GP, 359.999, -179.999) }},
+		{"HDG", func() string { return ToHDG(GP, -179.999, -179.999, -179.999) }},
+		{"THS", func() string { return ToTHS(GP, 359.999, THS_SIMULATOR) }},
+		{"ROT", func() string { return ToROT(GP, -999.9) }},
+		{"XDR Attitude", func() string {
+			return ToXDR(II, Transducer{XDR_ANGULAR, -90, XDR_DEGREES, "PTCH"}, Transducer{XDR_ANGULAR, -180, XDR_DEGREES, "ROLL"})
+		}},
+		{"XDR Rates", func() string {
+			return ToXDR(II, Transducer{XDR_GENERIC, -999.9, "", "RRTE"}, Transducer{XDR_GENERIC, -999.9, "", "PRTE"}, Transducer{XDR_GENERIC, -999.9, "", "YRTE"})
+		}},
+		{"PASHR", func() string { return generatePASHR(ts, 359.999, -180, -90) }},
+		{"ToPASHR", func() string { return ToPASHR(-0.001, 12.3, -4.5) }},
+		{"ToPASHRTime", func() string { return ToPASHRTime(ts, -0.001, 12.3, -4.5) }},
+		{"PSAT,HPR", func() string { return generatePSATHPR(ts, 359.999, -90, -180) }},
+		{"ToPSATHPR", func() string { return ToPSATHPR(-0.001, -4.5, 12.3) }},
+		{"ToPSATHPRTime", func() string { return ToPSATHPRTime(ts, -0.001, -4.5, 12.3) }},
+		{"PMTK001", func() string { return ToPMTK001(314, PMTK_ACK_UNSUPPORTED) }},
+		{"ToRMC", func() string { return ToRMC(GP, 45.123456, -75.654321, 123.4, -12.3, -12.3) }},
+		{"RMC Void", func() string { return ToRMCFix(GP, ts, 45.123456, -75.654321, 0, 0, 0, STATUS_VOID, MODE_NOT_VALID) }},
+		{"VTG Estimated", func() string { return ToVTGMode(GP, 10, -1, 12.3, MODE_ESTIMATED) }},
+		{"GSA Full", func() string {
+			return ToGSA(GP, GSA_3D, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}, 99.9, 99.9, 99.9)
+		}},
+		{"GSA No Fix", func() string { return ToGSA(GP, GSA_NO_FIX, nil, 99.9, 99.9, 99.9) }},
+		{"GSV None In View", func() string { return string(AppendGSV(nil, GP, 1, 1, 0, nil)) }},
+		{"GSV Four", func() string { return string(AppendGSV(nil, GP, 1, 1, 4, sats[:4])) }},
+		{"GSV First Of Two", func() string { return string(AppendGSV(nil, GP, 2, 1, 6, sats[:4])) }},
+		{"GSV Last Partial", func() string { return string(AppendGSV(nil, GP, 2, 2, 6, sats[4:])) }},
+		{"GSV Last Single", func() string { return string(AppendGSV(nil, GP, 2, 2, 5, sats[4:5])) }},
+		{"GSV Too Many", func() string { return string(AppendGSV(nil, GP, 1, 1, 6, sats)) }},
+	}
+
+	for _, f := range []struct {
+		name   string
+		format formats
+	}{{"Default", DEFAULTS}, {"Enhanced", ENHANCED}} {
+		for _, tc := range testCases {
+			t.Run(f.name+" "+tc.name, func(t *testing.T) {
+				SetFormats(f.format)
+				s := tc.generator()
+				if err := checkSentence(s); err != nil {
+					t.Errorf("Invalid sentence %q: %v", s, err)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckSentence(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sentence string
+		valid    bool
+	}{
+		{"Valid", "$GPGGA,000000.000,0000.0000,N,00000.0000,E,0,0,0.0,0.00,M,0.00,M,,*5D\r\n", true},
+		{"Double Framed", "$$GPGGA,000000.000,0000.0000,N,00000.0000,E,0,0,0.0,0.00,M,0.00,M,,*5D\r\n*1C\r\n", false},
+		{"Bad Checksum", "$GPGGA,000000.000,0000.0000,N,00000.0000,E,0,0,0.0,0.00,M,0.00,M,,*5E\r\n", false},
+		{"Lower Case Checksum", "$GPVTG,45.123,T,45.123,M,23.998089,N,44.444444,K,D*2e\r\n", false},
+		{"No CRLF", "$GPGGA,000000.000,0000.0000,N,00000.0000,E,0,0,0.0,0.00,M,0.00,M,,*5D", false},
+		{"Missing Field", "$GPVTG,0.000,T,0.000,M,0.000000,N,0.000000,K*4E\r\n", false},
+		{"Short Address", "$GPGG,000000.000*25\r\n", false},
+		{"Proprietary", "$PASHR,085335.000,224.19,T,-01.26,+00.83,+00.00,0.010,0.010,0.010,1,1*06\r\n", true},
+		{"XDR Partial Group", "$IIXDR,A,2.5,D*4E\r\n", false},
+		{"GSV", "$GPGSV,2,2,06,15,55,005,,29,81,120,48*73\r\n", true},
+		{"GSV Partial Satellite", "$GPGSV,2,2,06,15,55,005*62\r\n", false},
+		{"Too Long", "$GPGGA,000000.000,0000.0000000000,N,00000.0000000000,E,0,0,0.0,0.0000000000,M,0.00,M,,*5D\r\n", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSentence(tc.sentence)
+			if tc.valid && err != nil {
+				t.Errorf("Expected valid, but got: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected invalid, but got no error")
+			}
+		})
+	}
+}
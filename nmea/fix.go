@@ -0,0 +1,32 @@
+package nmea
+
+// GGA fix quality indicators
+const (
+	GGA_NO_FIX    = 0
+	GGA_GPS       = 1
+	GGA_DGPS      = 2
+	GGA_ESTIMATED = 6 // dead reckoning
+	GGA_SIMULATED = 8
+)
+
+// RMC status
+const (
+	STATUS_VALID = "A"
+	STATUS_VOID  = "V"
+)
+
+// Mode indicators of RMC and VTG sentences
+const (
+	MODE_AUTONOMOUS   = "A"
+	MODE_DIFFERENTIAL = "D"
+	MODE_ESTIMATED    = "E" // dead reckoning
+	MODE_NOT_VALID    = "N"
+	MODE_SIMULATOR    = "S"
+)
+
+// GSA fix types
+const (
+	GSA_NO_FIX = 1
+	GSA_2D     = 2
+	GSA_3D     = 3
+)
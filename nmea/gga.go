@@ -3,13 +3,23 @@ package nmea
 import (
 	"fmt"
 	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
 )
 
-func generateGGA(t time.Time, lat float64, lon float64, quality uint, satellites uint, hdop float64, alt float64, sep float64) string {
-	tS := t.Format("150405.000")
+func generateGGA(talker TalkerID, t time.Time, lat float64, lon float64, quality uint, satellites uint, hdop float64, alt float64, sep float64) string {
+	fm := CurrentFormats()
+	s := formatGGA(talker, t, calculateLL(lat, LAT_RUNES, fm.lat), calculateLL(lon, LON_RUNES, fm.lon), quality, satellites, hdop, alt, sep, fm)
+	if len(s) > MAX_SENTENCE_LENGTH && fm.lat != DEFAULTS.lat {
+		// the extra decimal places don't fit, so the position is sent with the default precision
+		s = formatGGA(talker, t, calculateLL(lat, LAT_RUNES, DEFAULTS.lat), calculateLL(lon, LON_RUNES, DEFAULTS.lon), quality, satellites, hdop, alt, sep, fm)
+	}
+	return s
+}
 
-	laS := calculateLat(lat)
-	loS := calculateLon(lon)
+// formatGGA returns a GGA message with the formatted latitude and longitude, and the rest in the formats fm
+func formatGGA(talker TalkerID, t time.Time, laS string, loS string, quality uint, satellites uint, hdop float64, alt float64, sep float64, fm formats) string {
+	tS := t.Format("150405.000")
 
 	qualS := fmt.Sprintf("%d", quality)
 
@@ -18,21 +28,31 @@ func generateGGA(t time.Time, lat float64, lon float64, quality uint, satellites
 	hdopS := fmt.Sprintf("%0.1f", hdop)
 
 	// altUnit set to "M" for meters
-	altS := fmt.Sprintf(Formats.alt+",M", alt)
+	altS := fmt.Sprintf(fm.alt+",M", alt)
 
 	// sepUnit set to "M" for meters
-	sepS := fmt.Sprintf(Formats.alt+",M", sep)
+	sepS := fmt.Sprintf("%0.*f,M", SEP_PRECISION, sep)
 
 	diffAgeS := ""
 	diffStationS := ""
 
-	bs := fmt.Sprintf("GPGGA,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s", tS, laS, loS, qualS, satS, hdopS, altS, sepS, diffAgeS, diffStationS)
+	bs := fmt.Sprintf("%sGGA,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s", talkerOrDefault(talker), tS, laS, loS, qualS, satS, hdopS, altS, sepS, diffAgeS, diffStationS)
 
 	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
 }
 
-// ToGPGGA will convert a latitude, longitude and altitude to a NMEA GPGGA message
+// ToGPGGA will convert a latitude, longitude and altitude above mean sea level to a NMEA GPGGA message
+// The geoid separation is calculated from the position
 func ToGPGGA(lat float64, lon float64, alt float64) string {
+	return ToGGA(GP, lat, lon, alt, geoid.Separation(lat, lon))
+}
+
+// ToGGA will convert a latitude, longitude, altitude and geoid separation to a NMEA GGA message with the given
+// talker ID
+// alt is the altitude above mean sea level and sep is the height of the geoid above the WGS84 ellipsoid, both
+// in meters
+// If talker is empty, the DefaultTalker is used
+func ToGGA(talker TalkerID, lat float64, lon float64, alt float64, sep float64) string {
 	// Example GPGGA message:
 	// $GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
 	// 123519       Fix taken at 12:35:19 UTC
@@ -47,9 +67,6 @@ func ToGPGGA(lat float64, lon float64, alt float64) string {
 	// (empty field) DGPS station ID number
 	// *47          the checksum data, always begins with *
 
-	// time is not supplied, so we will use the current time
-	t := time.Now().UTC()
-
 	// quality set to 8 for a simulated fix (see https://docs.novatel.com/OEM7/Content/Logs/GPGGA.htm#GPSQualityIndicators)
 	quality := uint(8)
 	// numSV is the number of satellites in view and is set to 12 for a simulated fix
@@ -57,16 +74,16 @@ func ToGPGGA(lat float64, lon float64, alt float64) string {
 	// HDOP is the horizontal dilution of presicion. lower values are better. normal range is 1-2, but set to 0.5 for a simulated fix
 	HDOP := 0.5
 
-	// sep is the height of the geoid above the WGS84 ellipsoid. 0.0 is a simulated fix. We could consider calculating this later if required
-	// sepUnit set to "M" for meters
-	sep := 0.0
-	// sepS := fmt.Sprintf(Formats.alt+",M", sep)
-
 	// diffAge and diffStation are not set, so they will default to empty strings
 	// diffAge := ""
 	// diffStation := ""
 
-	bs := generateGGA(t, lat, lon, quality, numSV, HDOP, alt, sep)
+	// time is not supplied, so we will use the current time
+	return ToGGAFix(talker, time.Now().UTC(), lat, lon, alt, sep, quality, numSV, HDOP)
+}
 
-	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+// ToGGAFix will convert a fix at t to a NMEA GGA message like ToGGA, with the fix quality indicator, number of
+// satellites and HDOP given, eg when the fix has been degraded
+func ToGGAFix(talker TalkerID, t time.Time, lat float64, lon float64, alt float64, sep float64, quality uint, satellites uint, hdop float64) string {
+	return generateGGA(talker, t.UTC(), lat, lon, quality, satellites, hdop, alt, sep)
 }
@@ -109,7 +109,7 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        0.0,
 			sep:        0.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,000000.000,0000.0000000,N,00000.0000000,E,0,0,0.0,0.0000,M,0.0000,M,,*5D\r\n",
+			expected:   "$GPGGA,000000.000,0000.00000,N,00000.00000,E,0,0,0.0,0.0000,M,0.00,M,,*5D\r\n",
 		},
 		{
 			name:       "Test 7-Enhanced Format",
@@ -122,7 +122,7 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        100.5,
 			sep:        50.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,123456.789,1220.7360000,N,09845.9240000,E,1,10,1.2,100.5000,M,50.0000,M,,*52\r\n",
+			expected:   "$GPGGA,123456.789,1220.73600,N,09845.92400,E,1,10,1.2,100.5000,M,50.00,M,,*52\r\n",
 		},
 		{
 			name:       "Test 8-Very Precise-Enhanced Format",
@@ -135,7 +135,7 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        100.9876543210,
 			sep:        50.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,123456.789,1220.7407341,N,09845.9259266,E,1,10,1.2,100.9877,M,50.0000,M,,*5C\r\n",
+			expected:   "$GPGGA,123456.789,1220.74073,N,09845.92593,E,1,10,1.2,100.9877,M,50.00,M,,*58\r\n",
 		},
 		{
 			name:       "Test 9-South and West-Enhanced Format",
@@ -148,7 +148,7 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        100.5,
 			sep:        50.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,123456.789,1220.7360000,S,09845.9240000,W,1,10,1.2,100.5000,M,50.0000,M,,*5D\r\n",
+			expected:   "$GPGGA,123456.789,1220.73600,S,09845.92400,W,1,10,1.2,100.5000,M,50.00,M,,*5D\r\n",
 		},
 		{
 			name:       "Test 10-South and East-Enhanced Format",
@@ -161,7 +161,7 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        100.5,
 			sep:        50.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,123456.789,1220.7360000,S,09845.9240000,E,1,10,1.2,100.5000,M,50.0000,M,,*4F\r\n",
+			expected:   "$GPGGA,123456.789,1220.73600,S,09845.92400,E,1,10,1.2,100.5000,M,50.00,M,,*4F\r\n",
 		},
 		{
 			name:       "Test 11-North and West-Enhanced Format",
@@ -174,14 +174,14 @@ func TestGenerateGGA(t *testing.T) {
 			alt:        100.5,
 			sep:        50.0,
 			format:     ENHANCED,
-			expected:   "$GPGGA,123456.789,1220.7360000,N,09845.9240000,W,1,10,1.2,100.5000,M,50.0000,M,,*40\r\n",
+			expected:   "$GPGGA,123456.789,1220.73600,N,09845.92400,W,1,10,1.2,100.5000,M,50.00,M,,*40\r\n",
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			Formats = tc.format
-			result := generateGGA(tc.timestamp, tc.lat, tc.lon, tc.quality, tc.satellites, tc.hdop, tc.alt, tc.sep)
+			SetFormats(tc.format)
+			result := generateGGA(GP, tc.timestamp, tc.lat, tc.lon, tc.quality, tc.satellites, tc.hdop, tc.alt, tc.sep)
 			if result != tc.expected {
 				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
 			}
@@ -0,0 +1,36 @@
+package nmea
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GSA_SATELLITES is how many satellites a GSA sentence has room for
+const GSA_SATELLITES = 12
+
+// ToGSA will convert a fix type, the PRNs of the satellites used in the fix and the dilutions of precision to a
+// NMEA GSA message with the given talker ID
+// fixType is one of the GSA fix types. Only the first GSA_SATELLITES PRNs are sent
+// If talker is empty, the DefaultTalker is used
+func ToGSA(talker TalkerID, fixType int, prns []int, pdop float64, hdop float64, vdop float64) string {
+	// Example GPGSA message:
+	// $GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39
+	// A            Auto selection of 2D or 3D fix (M = manual)
+	// 3            Fix type: 1 = no fix, 2 = 2D fix, 3 = 3D fix
+	// 04,05...     PRNs of the satellites used for the fix, 12 fields
+	// 2.5          PDOP (dilution of precision)
+	// 1.3          Horizontal dilution of precision (HDOP)
+	// 2.1          Vertical dilution of precision (VDOP)
+	// *39          the checksum data, always begins with *
+	fields := make([]string, GSA_SATELLITES)
+	for i, prn := range prns {
+		if i >= GSA_SATELLITES {
+			break
+		}
+		fields[i] = fmt.Sprintf("%02d", prn)
+	}
+
+	bs := fmt.Sprintf("%sGSA,A,%d,%s,%0.1f,%0.1f,%0.1f", talkerOrDefault(talker), fixType, strings.Join(fields, ","), pdop, hdop, vdop)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
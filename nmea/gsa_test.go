@@ -0,0 +1,29 @@
+package nmea
+
+import "testing"
+
+func TestToGSA(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fixType  int
+		prns     []int
+		pdop     float64
+		hdop     float64
+		vdop     float64
+		expected string
+	}{
+		{"3D", GSA_3D, []int{4, 5, 9, 12, 24}, 2.5, 1.3, 2.1, "$GPGSA,A,3,04,05,09,12,24,,,,,,,,2.5,1.3,2.1*39\r\n"},
+		{"2D", GSA_2D, []int{4, 5, 9}, 3.2, 2.9, 1.4, "$GPGSA,A,2,04,05,09,,,,,,,,,,3.2,2.9,1.4*34\r\n"},
+		{"No Fix", GSA_NO_FIX, nil, 99.9, 99.9, 99.9, "$GPGSA,A,1,,,,,,,,,,,,,99.9,99.9,99.9*09\r\n"},
+		{"Too Many", GSA_3D, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}, 1, 0.5, 0.8, "$GPGSA,A,3,01,02,03,04,05,06,07,08,09,10,11,12,1.0,0.5,0.8*3D\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ToGSA(GP, tc.fixType, tc.prns, tc.pdop, tc.hdop, tc.vdop)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package nmea
+
+import "strconv"
+
+// GSV_SATELLITES is how many satellites a GSV sentence has room for
+const GSV_SATELLITES = 4
+
+// GSVSatellite is a satellite in view in a GSV sentence
+type GSVSatellite struct {
+	PRN int
+	// Elevation is in degrees above the horizon, and Azimuth in degrees true
+	Elevation int
+	Azimuth   int
+	// SNR is in dB-Hz. A satellite that isn't tracked has an SNR of 0, which is sent as an empty field
+	SNR int
+}
+
+// ToGSV will convert the satellites in view to a group of NMEA GSV messages with the given talker ID, with
+// GSV_SATELLITES satellites in each
+// With no satellites in view, there is one message that says so
+// If talker is empty, the DefaultTalker is used
+func ToGSV(talker TalkerID, sats []GSVSatellite) []string {
+	// Example GPGSV message:
+	// $GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00*74
+	// 3            Number of messages in the group
+	// 1            Number of this message
+	// 11           Number of satellites in view
+	// 03,03,111,00 PRN, elevation in degrees, azimuth in degrees true and SNR in dB-Hz (empty when not
+	//              tracking) of up to 4 satellites
+	total := max((len(sats)+GSV_SATELLITES-1)/GSV_SATELLITES, 1)
+	msgs := make([]string, total)
+	for i := range msgs {
+		group := sats[min(i*GSV_SATELLITES, len(sats)):min((i+1)*GSV_SATELLITES, len(sats))]
+		msgs[i] = string(AppendGSV(nil, talker, total, i+1, len(sats), group))
+	}
+	return msgs
+}
+
+// AppendGSV will append message num of a group of total NMEA GSV messages to b, with inView satellites in view
+// and the satellites of this message, and return the extended buffer
+// Only the first GSV_SATELLITES satellites are sent
+func AppendGSV(b []byte, talker TalkerID, total int, num int, inView int, sats []GSVSatellite) []byte {
+	b, start := startSentence(b, talker, "GSV")
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(total), 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(num), 10)
+	b = append(b, ',')
+	b = appendIntPad(b, int64(inView), 2)
+	for i, s := range sats {
+		if i >= GSV_SATELLITES {
+			break
+		}
+		b = append(b, ',')
+		b = appendIntPad(b, int64(s.PRN), 2)
+		b = append(b, ',')
+		b = appendIntPad(b, int64(s.Elevation), 2)
+		b = append(b, ',')
+		b = appendIntPad(b, int64(s.Azimuth), 3)
+		b = append(b, ',')
+		if s.SNR > 0 {
+			b = appendIntPad(b, int64(s.SNR), 2)
+		}
+	}
+	return endSentence(b, start)
+}
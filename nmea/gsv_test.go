@@ -0,0 +1,37 @@
+package nmea
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToGSV(t *testing.T) {
+	sats := []GSVSatellite{
+		{1, 40, 83, 46}, {2, 17, 308, 41}, {12, 7, 344, 39}, {14, 22, 228, 45},
+		{15, 55, 5, 0}, {29, 81, 120, 48},
+	}
+
+	testCases := []struct {
+		name     string
+		sats     []GSVSatellite
+		expected []string
+	}{
+		{"Two Messages", sats, []string{
+			"$GPGSV,2,1,06,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*7B\r\n",
+			"$GPGSV,2,2,06,15,55,005,,29,81,120,48*73\r\n",
+		}},
+		{"One Message", sats[:4], []string{
+			"$GPGSV,1,1,04,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*7A\r\n",
+		}},
+		{"None In View", nil, []string{"$GPGSV,1,1,00*79\r\n"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ToGSV(GP, tc.sats)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected: %q, but got: %q", tc.expected, result)
+			}
+		})
+	}
+}
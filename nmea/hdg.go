@@ -0,0 +1,28 @@
+package nmea
+
+import "fmt"
+
+// ToHDG will convert a true heading, magnetic deviation and magnetic variation to a NMEA HDG message with the
+// given talker ID
+// deviation and variation are in degrees, positive east
+// If talker is empty, the DefaultTalker is used
+func ToHDG(talker TalkerID, heading float64, deviation float64, variation float64) string {
+	// Example HCHDG message:
+	// $HCHDG,98.3,0.0,E,12.6,W*57
+	// 98.3         Magnetic sensor heading in degrees
+	// 0.0,E        Magnetic deviation in degrees, E or W
+	// 12.6,W       Magnetic variation in degrees, E or W
+	//
+	// The magnetic heading is the sensor heading plus the deviation, and the true heading is the magnetic
+	// heading plus the variation
+
+	// heading is sometimes negative
+	headingS := fmt.Sprintf(CurrentFormats().hdg, normaliseHeading(heading-variation-deviation))
+
+	devS := calculateVariation(deviation)
+	varS := calculateVariation(variation)
+
+	bs := fmt.Sprintf("%sHDG,%s,%s,%s", talkerOrDefault(talker), headingS, devS, varS)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
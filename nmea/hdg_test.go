@@ -0,0 +1,29 @@
+package nmea
+
+import "testing"
+
+func TestToHDG(t *testing.T) {
+	testCases := []struct {
+		name      string
+		heading   float64
+		deviation float64
+		variation float64
+		expected  string
+	}{
+		{"Zero", 0, 0, 0, "$GPHDG,0.000,0.0,E,0.0,E*5E\r\n"},
+		{"West Variation", 85.7, 0, -12.6, "$GPHDG,98.300,0.0,E,12.6,W*4B\r\n"},
+		{"Deviation and Variation", 90, 2.5, 10, "$GPHDG,77.500,2.5,E,10.0,E*5D\r\n"},
+		{"West Deviation", 90, -2.5, 10, "$GPHDG,82.500,2.5,W,10.0,E*45\r\n"},
+		{"Negative Heading", -45.123, 0, -10, "$GPHDG,324.877,0.0,E,10.0,W*70\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result := ToHDG(GP, tc.heading, tc.deviation, tc.variation)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
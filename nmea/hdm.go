@@ -0,0 +1,19 @@
+package nmea
+
+import "fmt"
+
+// ToHDM will convert a true heading and magnetic variation to a NMEA HDM message with the given talker ID
+// variation is the magnetic declination in degrees, positive east
+// If talker is empty, the DefaultTalker is used
+func ToHDM(talker TalkerID, heading float64, variation float64) string {
+	// Example HCHDM message:
+	// $HCHDM,238.5,M*29
+	// 238.5,M      Heading in degrees Magnetic
+
+	// heading is sometimes negative
+	headingS := fmt.Sprintf(CurrentFormats().hdg, normaliseHeading(heading-variation))
+
+	bs := fmt.Sprintf("%sHDM,%s,M", talkerOrDefault(talker), headingS)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
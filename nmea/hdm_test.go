@@ -0,0 +1,28 @@
+package nmea
+
+import "testing"
+
+func TestToHDM(t *testing.T) {
+	testCases := []struct {
+		name      string
+		heading   float64
+		variation float64
+		expected  string
+	}{
+		{"Zero", 0, 0, "$GPHDM,0.000,M*35\r\n"},
+		{"East Variation", 90, 10.5, "$GPHDM,79.500,M*0E\r\n"},
+		{"West Variation", 90, -12.6, "$GPHDM,102.600,M*30\r\n"},
+		{"Negative Heading", -45.123, -10, "$GPHDM,324.877,M*38\r\n"},
+		{"Variation Wraps", 5, 10, "$GPHDM,355.000,M*36\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result := ToHDM(GP, tc.heading, tc.variation)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package nmea
+
+import "fmt"
+
+// ToHDT will convert a true heading to a NMEA HDT message with the given talker ID
+// If talker is empty, the DefaultTalker is used
+func ToHDT(talker TalkerID, heading float64) string {
+	// Example HEHDT message:
+	// $HEHDT,274.07,T*03
+	// 274.07,T     Heading in degrees True
+
+	// heading is sometimes negative
+	headingS := fmt.Sprintf(CurrentFormats().hdg, normaliseHeading(heading))
+
+	bs := fmt.Sprintf("%sHDT,%s,T", talkerOrDefault(talker), headingS)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
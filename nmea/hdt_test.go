@@ -0,0 +1,26 @@
+package nmea
+
+import "testing"
+
+func TestToHDT(t *testing.T) {
+	testCases := []struct {
+		name     string
+		heading  float64
+		expected string
+	}{
+		{"Zero", 0, "$GPHDT,0.000,T*35\r\n"},
+		{"Normal", 274.07, "$GPHDT,274.070,T*33\r\n"},
+		{"Negative Heading", -45.123, "$GPHDT,314.877,T*3B\r\n"},
+		{"2 Heading Rotations", 720.123, "$GPHDT,0.123,T*35\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result := ToHDT(GP, tc.heading)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package nmea
 import (
 	"fmt"
 	"math"
+	"sync/atomic"
 )
 
 type formats struct {
@@ -10,22 +11,47 @@ type formats struct {
 	lon string
 	alt string
 	sog string
+	spd string
 	hdg string
+
+	// prec is the number of decimal places of each format, for the append style generators
+	prec precisions
+}
+
+// precisions is the number of decimal places of each value in NMEA messages
+type precisions struct {
+	lat int
+	lon int
+	alt int
+	sog int
+	spd int
+	hdg int
 }
 
+// MAX_SENTENCE_LENGTH is the most characters NMEA 0183 allows in a sentence, including the $ and the CR LF
+const MAX_SENTENCE_LENGTH = 82
+
+// SEP_PRECISION is the number of decimal places of the geoid separation in GGA messages in both formats, as the
+// geoid model is no more accurate than that
+const SEP_PRECISION = 2
+
 const (
 	// DEFAULT Presicions for NMEA messages (number of decimal places)
 	DEFAULT_LAT_PRECISION = 4
 	DEFAULT_LON_PRECISION = 4
 	DEFAULT_ALT_PRECISION = 2
 	DEFAULT_SOG_PRECISION = 6
+	DEFAULT_SPD_PRECISION = 2 // speed in sentences with less room than VTG (eg RMC)
 	DEFAULT_HDG_PRECISION = 3
 
 	// ENHANCED Presicions for NMEA messages (number of decimal places)
-	ENHANCED_LAT_PRECISION = 7
-	ENHANCED_LON_PRECISION = 7
+	// The latitude, longitude and speed are limited so RMC sentences still fit in the 82 characters NMEA allows.
+	// Where GGA and RMC sentences with extreme values still don't fit, the position has the DEFAULT precision.
+	ENHANCED_LAT_PRECISION = 5
+	ENHANCED_LON_PRECISION = 5
 	ENHANCED_ALT_PRECISION = 4
 	ENHANCED_SOG_PRECISION = 7
+	ENHANCED_SPD_PRECISION = 2
 	ENHANCED_HDG_PRECISION = 3
 )
 
@@ -35,7 +61,12 @@ var (
 		lon: fmt.Sprintf("%%03d%%0%d.%df", DEFAULT_LON_PRECISION+3, DEFAULT_LON_PRECISION),
 		alt: fmt.Sprintf("%%0.%df", DEFAULT_ALT_PRECISION),
 		sog: fmt.Sprintf("%%0.%df", DEFAULT_SOG_PRECISION),
+		spd: fmt.Sprintf("%%0.%df", DEFAULT_SPD_PRECISION),
 		hdg: fmt.Sprintf("%%0.%df", DEFAULT_HDG_PRECISION),
+		prec: precisions{
+			DEFAULT_LAT_PRECISION, DEFAULT_LON_PRECISION, DEFAULT_ALT_PRECISION,
+			DEFAULT_SOG_PRECISION, DEFAULT_SPD_PRECISION, DEFAULT_HDG_PRECISION,
+		},
 	}
 
 	ENHANCED = formats{
@@ -43,16 +74,37 @@ var (
 		lon: fmt.Sprintf("%%03d%%0%d.%df", ENHANCED_LON_PRECISION+3, ENHANCED_LON_PRECISION),
 		alt: fmt.Sprintf("%%0.%df", ENHANCED_ALT_PRECISION),
 		sog: fmt.Sprintf("%%0.%df", ENHANCED_SOG_PRECISION),
+		spd: fmt.Sprintf("%%0.%df", ENHANCED_SPD_PRECISION),
 		hdg: fmt.Sprintf("%%0.%df", ENHANCED_HDG_PRECISION),
+		prec: precisions{
+			ENHANCED_LAT_PRECISION, ENHANCED_LON_PRECISION, ENHANCED_ALT_PRECISION,
+			ENHANCED_SOG_PRECISION, ENHANCED_SPD_PRECISION, ENHANCED_HDG_PRECISION,
+		},
 	}
 
-	// Formats is the formats used for the NMEA messages
-	Formats = DEFAULTS
-
 	LAT_RUNES = [2]rune{'N', 'S'}
 	LON_RUNES = [2]rune{'E', 'W'}
 )
 
+// current is the formats used for the NMEA messages, DEFAULTS or ENHANCED
+// It is atomic, as it can be changed, eg from the UI, while sentences are being generated. Generators take one
+// copy for each sentence, so a change never mixes the precisions of a sentence.
+var current atomic.Value
+
+func init() {
+	current.Store(DEFAULTS)
+}
+
+// CurrentFormats returns the formats used for the NMEA messages
+func CurrentFormats() formats {
+	return current.Load().(formats)
+}
+
+// SetFormats will set the formats used for the NMEA messages, DEFAULTS or ENHANCED
+func SetFormats(f formats) {
+	current.Store(f)
+}
+
 // calculateChecksum will calculate the checksum for a NMEA message
 func calculateChecksum(s string) byte {
 	cs := 0
@@ -82,14 +134,43 @@ func calculateLL(v float64, ds [2]rune, fm string) string {
 	return vS
 }
 
+// normaliseHeading will limit a heading in degrees to [0, 360)
+func normaliseHeading(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// calculateVariation will convert a magnetic variation for a NMEA message
+// the format is "x.x,a" where a is E for positive (easterly) variation and W for negative (westerly) variation
+func calculateVariation(v float64) string {
+	d := 'E'
+	if v < 0 {
+		d = 'W'
+	}
+	return fmt.Sprintf("%0.1f,%s", math.Abs(v), string(d))
+}
+
 // calculateLat will convert the latitude for a NMEA message
 func calculateLat(lat float64) string {
 	// lat needs 4 leading digits and 4 decimal places
-	return calculateLL(lat, LAT_RUNES, Formats.lat)
+	return calculateLL(lat, LAT_RUNES, CurrentFormats().lat)
 }
 
 // calculateLon will convert the longitude for a NMEA message
 func calculateLon(lon float64) string {
 	// lon needs 5 leading digits and 4 decimal places
-	return calculateLL(lon, LON_RUNES, Formats.lon)
+	return calculateLL(lon, LON_RUNES, CurrentFormats().lon)
+}
+
+// FormatLat returns the latitude as the two fields of a NMEA sentence, eg "4525.2000,N"
+func FormatLat(lat float64) string {
+	return calculateLat(lat)
+}
+
+// FormatLon returns the longitude as the two fields of a NMEA sentence, eg "07542.0000,W"
+func FormatLon(lon float64) string {
+	return calculateLon(lon)
 }
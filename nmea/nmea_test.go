@@ -3,6 +3,7 @@ package nmea
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestCalculateChecksum(t *testing.T) {
@@ -59,6 +60,7 @@ func TestCalculateLL(t *testing.T) {
 }
 
 func TestCalculateLat(t *testing.T) {
+	SetFormats(DEFAULTS)
 	testCases := []struct {
 		lat      float64
 		expected string
@@ -81,6 +83,7 @@ func TestCalculateLat(t *testing.T) {
 }
 
 func TestCalculateLon(t *testing.T) {
+	SetFormats(DEFAULTS)
 	testCases := []struct {
 		lon      float64
 		expected string
@@ -103,3 +106,30 @@ func TestCalculateLon(t *testing.T) {
 		})
 	}
 }
+
+func TestSetFormats(t *testing.T) {
+	defer SetFormats(DEFAULTS)
+	gga := func() string {
+		return string(AppendGGAFix(nil, GP, time.Time{}, 45.5, -75.5, 100, -34, GGA_SIMULATED, 12, 0.9))
+	}
+	SetFormats(ENHANCED)
+	enhanced := gga()
+	SetFormats(DEFAULTS)
+	standard := gga()
+
+	// the formats can be changed while sentences are generated, and each sentence has one precision
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			SetFormats(ENHANCED)
+			SetFormats(DEFAULTS)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if s := gga(); s != standard && s != enhanced {
+			t.Errorf("Expected the sentence in one format, but got: %s", s)
+		}
+	}
+	<-done
+}
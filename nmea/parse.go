@@ -0,0 +1,37 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSentence is returned when a sentence can not be parsed
+var ErrInvalidSentence = errors.New("invalid NMEA sentence")
+
+// Parse will split a sentence into its fields, starting with the address field (eg "GPGGA" or "PMTK220")
+// The checksum is checked if there is one, and the trailing CRLF is optional
+func Parse(s string) ([]string, error) {
+	s = strings.TrimRight(s, "\r\n")
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: does not start with $", ErrInvalidSentence)
+	}
+
+	body, cs, ok := strings.Cut(s[1:], "*")
+	if ok {
+		v, err := strconv.ParseUint(cs, 16, 8)
+		if err != nil || len(cs) != 2 {
+			return nil, fmt.Errorf("%w: bad checksum %q", ErrInvalidSentence, cs)
+		}
+		if byte(v) != calculateChecksum(body) {
+			return nil, fmt.Errorf("%w: checksum is %02X, expected %02X", ErrInvalidSentence, v, calculateChecksum(body))
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	if fields[0] == "" {
+		return nil, fmt.Errorf("%w: no address field", ErrInvalidSentence)
+	}
+	return fields, nil
+}
@@ -0,0 +1,65 @@
+package nmea
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sentence string
+		expected []string
+		err      bool
+	}{
+		{"PMTK", "$PMTK220,200*2C\r\n", []string{"PMTK220", "200"}, false},
+		{"PUBX", "$PUBX,41,1,0007,0003,19200,0*25\r\n", []string{"PUBX", "41", "1", "0007", "0003", "19200", "0"}, false},
+		{"No Checksum", "$PMTK220,1000", []string{"PMTK220", "1000"}, false},
+		{"Round Trip", ToHDT(GP, 45), []string{"GPHDT", "45.000", "T"}, false},
+		{"Bad Checksum", "$PMTK220,200*2D\r\n", nil, true},
+		{"Short Checksum", "$PMTK220,200*2\r\n", nil, true},
+		{"No Dollar", "PMTK220,200*2C\r\n", nil, true},
+		{"No Address", "$,200", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result, err := Parse(tc.sentence)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidSentence) {
+					t.Errorf("Expected ErrInvalidSentence, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected: %q, but got: %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestToPMTK001(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cmd      int
+		flag     int
+		expected string
+	}{
+		{"Succeeded", 220, PMTK_ACK_SUCCEEDED, "$PMTK001,220,3*30\r\n"},
+		{"Unsupported", 999, PMTK_ACK_UNSUPPORTED, "$PMTK001,999,1*3B\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ToPMTK001(tc.cmd, tc.flag)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package nmea
+
+import (
+	"fmt"
+	"time"
+)
+
+func generatePASHR(t time.Time, heading float64, roll float64, pitch float64) string {
+	tS := t.Format("150405.000")
+
+	headingS := fmt.Sprintf("%0.2f", normaliseHeading(heading))
+	rollS := fmt.Sprintf("%+06.2f", roll)
+	pitchS := fmt.Sprintf("%+06.2f", pitch)
+
+	// heave is not simulated
+	heaveS := "+00.00"
+
+	// accuracies in degrees, simulated attitude is perfect
+	accS := "0.010"
+
+	// 1 is for GPS aided
+	aiding := "1"
+	// 1 is for IMU satisfactory
+	imu := "1"
+
+	bs := fmt.Sprintf("PASHR,%s,%s,T,%s,%s,%s,%s,%s,%s,%s,%s", tS, headingS, rollS, pitchS, heaveS, accS, accS, accS, aiding, imu)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
+
+// ToPASHR will convert a true heading, roll and pitch to a proprietary PASHR attitude message
+// All angles are in degrees. roll is positive right wing down and pitch is positive nose up
+func ToPASHR(heading float64, roll float64, pitch float64) string {
+	// Example PASHR message:
+	// $PASHR,085335.000,224.19,T,-01.26,+00.83,+00.00,0.101,0.113,0.267,1,0*07
+	// 085335.000   UTC time
+	// 224.19,T     Heading in degrees True
+	// -01.26       Roll in degrees
+	// +00.83       Pitch in degrees
+	// +00.00       Heave in meters
+	// 0.101        Roll accuracy in degrees
+	// 0.113        Pitch accuracy in degrees
+	// 0.267        Heading accuracy in degrees
+	// 1            Aiding status: 0=no aiding, 1=GPS aiding, 2=GPS and GAMS aiding
+	// 0            IMU status
+
+	// time is not supplied, so we will use the current time
+	return ToPASHRTime(time.Now(), heading, roll, pitch)
+}
+
+// ToPASHRTime will convert a true heading, roll and pitch at the time t to a proprietary PASHR attitude message
+func ToPASHRTime(t time.Time, heading float64, roll float64, pitch float64) string {
+	return generatePASHR(t.UTC(), heading, roll, pitch)
+}
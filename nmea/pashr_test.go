@@ -0,0 +1,31 @@
+package nmea
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGeneratePASHR(t *testing.T) {
+	ts := time.Date(2022, time.January, 1, 8, 53, 35, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		heading  float64
+		roll     float64
+		pitch    float64
+		expected string
+	}{
+		{"Zeros", 0, 0, 0, "$PASHR,085335.000,0.00,T,+00.00,+00.00,+00.00,0.010,0.010,0.010,1,1*02\r\n"},
+		{"Normal", 224.19, -1.26, 0.83, "$PASHR,085335.000,224.19,T,-01.26,+00.83,+00.00,0.010,0.010,0.010,1,1*06\r\n"},
+		{"Negative Heading", -45.123, 30, -10, "$PASHR,085335.000,314.88,T,+30.00,-10.00,+00.00,0.010,0.010,0.010,1,1*00\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := generatePASHR(ts, tc.heading, tc.roll, tc.pitch)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
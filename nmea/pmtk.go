@@ -0,0 +1,23 @@
+package nmea
+
+import "fmt"
+
+// PMTK001 acknowledgement flags
+const (
+	PMTK_ACK_INVALID     = 0
+	PMTK_ACK_UNSUPPORTED = 1
+	PMTK_ACK_FAILED      = 2
+	PMTK_ACK_SUCCEEDED   = 3
+)
+
+// ToPMTK001 will return a MediaTek PMTK001 acknowledgement of the command cmd
+func ToPMTK001(cmd int, flag int) string {
+	// Example PMTK001 message:
+	// $PMTK001,220,3*30
+	// 220          Command being acknowledged
+	// 3            Flag, 3 is succeeded
+
+	bs := fmt.Sprintf("PMTK001,%d,%d", cmd, flag)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
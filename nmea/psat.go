@@ -0,0 +1,41 @@
+package nmea
+
+import (
+	"fmt"
+	"time"
+)
+
+func generatePSATHPR(t time.Time, heading float64, pitch float64, roll float64) string {
+	tS := t.Format("150405.00")
+
+	headingS := fmt.Sprintf("%0.2f", normaliseHeading(heading))
+	pitchS := fmt.Sprintf("%0.2f", pitch)
+	rollS := fmt.Sprintf("%0.2f", roll)
+
+	// N is for GNSS derived attitude. G=Gyro
+	source := "N"
+
+	bs := fmt.Sprintf("PSAT,HPR,%s,%s,%s,%s,%s", tS, headingS, pitchS, rollS, source)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
+
+// ToPSATHPR will convert a true heading, pitch and roll to a proprietary Hemisphere PSAT,HPR attitude message
+// All angles are in degrees. pitch is positive nose up and roll is positive right wing down
+func ToPSATHPR(heading float64, pitch float64, roll float64) string {
+	// Example PSAT,HPR message:
+	// $PSAT,HPR,130214.00,224.19,0.83,-1.26,N*31
+	// 130214.00    UTC time
+	// 224.19       Heading in degrees True
+	// 0.83         Pitch in degrees
+	// -1.26        Roll in degrees
+	// N            Source: N=GNSS, G=Gyro
+
+	// time is not supplied, so we will use the current time
+	return ToPSATHPRTime(time.Now(), heading, pitch, roll)
+}
+
+// ToPSATHPRTime will convert a true heading, pitch and roll at the time t to a proprietary PSAT,HPR attitude message
+func ToPSATHPRTime(t time.Time, heading float64, pitch float64, roll float64) string {
+	return generatePSATHPR(t.UTC(), heading, pitch, roll)
+}
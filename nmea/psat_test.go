@@ -0,0 +1,31 @@
+package nmea
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGeneratePSATHPR(t *testing.T) {
+	ts := time.Date(2022, time.January, 1, 13, 2, 14, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		heading  float64
+		pitch    float64
+		roll     float64
+		expected string
+	}{
+		{"Zeros", 0, 0, 0, "$PSAT,HPR,130214.00,0.00,0.00,0.00,N*27\r\n"},
+		{"Normal", 224.19, 0.83, -1.26, "$PSAT,HPR,130214.00,224.19,0.83,-1.26,N*08\r\n"},
+		{"Negative Heading", -45.123, -10, 30, "$PSAT,HPR,130214.00,314.88,-10.00,30.00,N*0E\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := generatePSATHPR(ts, tc.heading, tc.pitch, tc.roll)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
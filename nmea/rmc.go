@@ -0,0 +1,64 @@
+package nmea
+
+import (
+	"fmt"
+	"time"
+)
+
+func generateRMC(talker TalkerID, t time.Time, lat float64, lon float64, sog float64, course float64, variation float64, status string, mode string) string {
+	fm := CurrentFormats()
+	s := formatRMC(talker, t, calculateLL(lat, LAT_RUNES, fm.lat), calculateLL(lon, LON_RUNES, fm.lon), sog, course, variation, status, mode, fm)
+	if len(s) > MAX_SENTENCE_LENGTH && fm.lat != DEFAULTS.lat {
+		// the extra decimal places don't fit, so the position is sent with the default precision
+		s = formatRMC(talker, t, calculateLL(lat, LAT_RUNES, DEFAULTS.lat), calculateLL(lon, LON_RUNES, DEFAULTS.lon), sog, course, variation, status, mode, fm)
+	}
+	return s
+}
+
+// formatRMC returns a RMC message with the formatted latitude and longitude, and the rest in the formats fm
+func formatRMC(talker TalkerID, t time.Time, laS string, loS string, sog float64, course float64, variation float64, status string, mode string, fm formats) string {
+	tS := t.Format("150405.000")
+	dS := t.Format("020106")
+
+	// knots = 1.94384 * m/s
+	sogS := fmt.Sprintf(fm.spd, sog*1.943845249221964)
+
+	courseS := fmt.Sprintf(fm.hdg, normaliseHeading(course))
+
+	varS := calculateVariation(variation)
+
+	bs := fmt.Sprintf("%sRMC,%s,%s,%s,%s,%s,%s,%s,%s,%s", talkerOrDefault(talker), tS, status, laS, loS, sogS, courseS, dS, varS, mode)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
+
+// ToRMC will convert a position, speed over ground, course and magnetic variation to a NMEA RMC message with
+// the given talker ID
+// sog is in m/s, course is the true course in degrees and variation is the magnetic declination in degrees,
+// positive east
+// If talker is empty, the DefaultTalker is used
+func ToRMC(talker TalkerID, lat float64, lon float64, sog float64, course float64, variation float64) string {
+	// Example GPRMC message:
+	// $GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W,A*6A
+	// 123519       Fix taken at 12:35:19 UTC
+	// A            Status A=active or V=Void
+	// 4807.038,N   Latitude 48 deg 07.038' N
+	// 01131.000,E  Longitude 11 deg 31.000' E
+	// 022.4        Speed over the ground in knots
+	// 084.4        Track angle in degrees True
+	// 230394       Date - 23rd of March 1994
+	// 003.1,W      Magnetic Variation
+	// A            Mode indicator: D=Diff, A=Autonomous, E=Estimated, N=Data not valid
+	// *6A          The checksum data, always begins with *
+
+	// time is not supplied, so we will use the current time
+	// the status is A for Active (valid), and the mode is D for Differential
+	return ToRMCFix(talker, time.Now().UTC(), lat, lon, sog, course, variation, STATUS_VALID, MODE_DIFFERENTIAL)
+}
+
+// ToRMCFix will convert a fix at t to a NMEA RMC message like ToRMC, with the status and mode indicator given,
+// eg when the fix has been lost
+// status is STATUS_VALID or STATUS_VOID and mode is one of the mode indicators
+func ToRMCFix(talker TalkerID, t time.Time, lat float64, lon float64, sog float64, course float64, variation float64, status string, mode string) string {
+	return generateRMC(talker, t.UTC(), lat, lon, sog, course, variation, status, mode)
+}
@@ -0,0 +1,81 @@
+package nmea
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateRMC(t *testing.T) {
+	testCases := []struct {
+		name      string
+		timestamp time.Time
+		lat       float64
+		lon       float64
+		sog       float64
+		course    float64
+		variation float64
+		format    formats
+		expected  string
+	}{
+		{
+			name:      "Zeros",
+			timestamp: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+			format:    DEFAULTS,
+			expected:  "$GPRMC,000000.000,A,0000.0000,N,00000.0000,E,0.00,0.000,010122,0.0,E,D*30\r\n",
+		},
+		{
+			name:      "North East, West Variation",
+			timestamp: time.Date(1994, time.March, 23, 12, 35, 19, 0, time.UTC),
+			lat:       48.1173,
+			lon:       11.516666667,
+			sog:       11.523,
+			course:    84.4,
+			variation: -3.1,
+			format:    DEFAULTS,
+			expected:  "$GPRMC,123519.000,A,4807.0380,N,01131.0000,E,22.40,84.400,230394,3.1,W,D*2C\r\n",
+		},
+		{
+			name:      "South West, East Variation, Negative Course",
+			timestamp: time.Date(2022, time.January, 1, 12, 34, 56, 789000000, time.UTC),
+			lat:       -12.3456,
+			lon:       -98.7654,
+			sog:       1,
+			course:    -45.123,
+			variation: 12.34,
+			format:    DEFAULTS,
+			expected:  "$GPRMC,123456.789,A,1220.7360,S,09845.9240,W,1.94,314.877,010122,12.3,E,D*00\r\n",
+		},
+		{
+			name:      "Enhanced",
+			timestamp: time.Date(2022, time.January, 1, 12, 34, 56, 789000000, time.UTC),
+			lat:       12.3456,
+			lon:       98.7654,
+			sog:       1,
+			course:    45.123,
+			variation: -0.04,
+			format:    ENHANCED,
+			expected:  "$GPRMC,123456.789,A,1220.73600,N,09845.92400,E,1.94,45.123,010122,0.0,W,D*12\r\n",
+		},
+		{
+			name:      "Enhanced Too Long",
+			timestamp: time.Date(2022, time.January, 1, 23, 59, 59, 999000000, time.UTC),
+			lat:       -89.999999,
+			lon:       -179.999999,
+			sog:       999.999,
+			course:    359.999,
+			variation: -179.99,
+			format:    ENHANCED,
+			expected:  "$GPRMC,235959.999,A,8959.9999,S,17959.9999,W,1943.84,359.999,010122,180.0,W,D*17\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(tc.format)
+			result := generateRMC(GP, tc.timestamp, tc.lat, tc.lon, tc.sog, tc.course, tc.variation, STATUS_VALID, MODE_DIFFERENTIAL)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package nmea
+
+import "fmt"
+
+// ToROT will convert a rate of turn to a NMEA ROT message with the given talker ID
+// rate is in degrees per second, negative to port (left)
+// If talker is empty, the DefaultTalker is used
+func ToROT(talker TalkerID, rate float64) string {
+	// Example GPROT message:
+	// $GPROT,35.6,A*01
+	// 35.6         Rate of turn in degrees per minute, negative to port
+	// A            Status A=data valid, V=data invalid
+
+	rotS := fmt.Sprintf("%0.1f", rate*60)
+
+	// A is for valid data. V=invalid
+	status := "A"
+
+	bs := fmt.Sprintf("%sROT,%s,%s", talkerOrDefault(talker), rotS, status)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
@@ -0,0 +1,25 @@
+package nmea
+
+import "testing"
+
+func TestToROT(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rate     float64
+		expected string
+	}{
+		{"Zero", 0, "$GPROT,0.0,A*31\r\n"},
+		{"Standard Rate Right", 3, "$GPROT,180.0,A*38\r\n"},
+		{"Port", -0.5, "$GPROT,-30.0,A*2F\r\n"},
+		{"Fractional", 0.123456, "$GPROT,7.4,A*32\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ToROT(GP, tc.rate)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package nmea
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// TalkerID is the two character prefix of a NMEA sentence that identifies the sending device
+type TalkerID string
+
+// Supported talker IDs
+const (
+	// GP is a GPS receiver
+	GP TalkerID = "GP"
+	// GN is a multi-GNSS receiver
+	GN TalkerID = "GN"
+	// GL is a GLONASS receiver
+	GL TalkerID = "GL"
+	// GA is a Galileo receiver
+	GA TalkerID = "GA"
+	// II is integrated instrumentation
+	II TalkerID = "II"
+)
+
+// Talkers is the list of supported talker IDs
+var Talkers = [...]TalkerID{GP, GN, GL, GA, II}
+
+// talker is the TalkerID used when a generator is given an empty talker ID
+// It is atomic, as it can be changed, eg from the UI, while sentences are being generated.
+var talker atomic.Value
+
+func init() {
+	talker.Store(GP)
+}
+
+// DefaultTalker returns the talker ID used when a generator is given an empty talker ID
+func DefaultTalker() TalkerID {
+	return talker.Load().(TalkerID)
+}
+
+// SetDefaultTalker will set the talker ID used when a generator is given an empty talker ID
+func SetDefaultTalker(t TalkerID) {
+	talker.Store(t)
+}
+
+// ParseTalker will return the TalkerID for s, or an error if s is not a supported talker ID
+func ParseTalker(s string) (TalkerID, error) {
+	for _, t := range Talkers {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported talker ID: %q", s)
+}
+
+// talkerOrDefault will return t, or the default talker if t is empty
+func talkerOrDefault(t TalkerID) TalkerID {
+	if t == "" {
+		return DefaultTalker()
+	}
+	return t
+}
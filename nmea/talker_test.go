@@ -0,0 +1,96 @@
+package nmea
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTalker(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected TalkerID
+		valid    bool
+	}{
+		{"GP", GP, true},
+		{"GN", GN, true},
+		{"GL", GL, true},
+		{"GA", GA, true},
+		{"II", II, true},
+		{"", "", false},
+		{"gp", "", false},
+		{"XX", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := ParseTalker(tc.input)
+			if tc.valid && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected an error, but got none")
+			}
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestTalkers(t *testing.T) {
+	SetFormats(DEFAULTS)
+	defer SetDefaultTalker(GP)
+
+	for _, talker := range Talkers {
+		t.Run(string(talker), func(t *testing.T) {
+			for _, s := range []string{
+				ToGGA(talker, 12.3456, 98.7654, 100.5, -20.1),
+				ToVTG(talker, 45.123, -10, 1),
+				ToRMC(talker, 12.3456, 98.7654, 1, 45.123, -10),
+			} {
+				if !strings.HasPrefix(s, "$"+string(talker)) {
+					t.Errorf("Expected talker %s, but got: %s", talker, s)
+				}
+				if err := checkSentence(s); err != nil {
+					t.Errorf("Invalid sentence %q: %v", s, err)
+				}
+			}
+		})
+
+		t.Run(string(talker)+"-Default", func(t *testing.T) {
+			SetDefaultTalker(talker)
+			for _, s := range []string{
+				ToGGA("", 12.3456, 98.7654, 100.5, -20.1),
+				ToVTG("", 45.123, -10, 1),
+				ToRMC("", 12.3456, 98.7654, 1, 45.123, -10),
+			} {
+				if !strings.HasPrefix(s, "$"+string(talker)) {
+					t.Errorf("Expected talker %s, but got: %s", talker, s)
+				}
+			}
+		})
+	}
+}
+
+func TestSetDefaultTalker(t *testing.T) {
+	defer SetDefaultTalker(GP)
+
+	// the talker can be changed while sentences are generated
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, talker := range Talkers {
+			SetDefaultTalker(talker)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := checkSentence(ToHDT("", 45)); err != nil {
+			t.Errorf("Invalid sentence: %v", err)
+		}
+	}
+	<-done
+
+	if DefaultTalker() != II {
+		t.Errorf("Expected: %s, but got: %s", II, DefaultTalker())
+	}
+}
@@ -0,0 +1,42 @@
+package nmea
+
+import "fmt"
+
+// THS mode indicators
+const (
+	THS_AUTONOMOUS = "A"
+	THS_ESTIMATED  = "E"
+	THS_MANUAL     = "M"
+	THS_SIMULATOR  = "S"
+	THS_INVALID    = "V"
+)
+
+// ParseTHSMode will return the THS mode indicator s, or an error if s is not one of the THS mode indicators
+func ParseTHSMode(s string) (string, error) {
+	switch s {
+	case THS_AUTONOMOUS, THS_ESTIMATED, THS_MANUAL, THS_SIMULATOR, THS_INVALID:
+		return s, nil
+	}
+	return "", fmt.Errorf("unsupported THS mode: %q", s)
+}
+
+// ToTHS will convert a true heading and mode indicator to a NMEA THS message with the given talker ID
+// mode is one of the THS mode indicators. If empty, THS_AUTONOMOUS is used
+// If talker is empty, the DefaultTalker is used
+func ToTHS(talker TalkerID, heading float64, mode string) string {
+	// Example GPTHS message:
+	// $GPTHS,77.52,E*32
+	// 77.52        Heading in degrees True
+	// E            Mode indicator: A=Autonomous, E=Estimated, M=Manual, S=Simulator, V=Data not valid
+
+	// heading is sometimes negative
+	headingS := fmt.Sprintf(CurrentFormats().hdg, normaliseHeading(heading))
+
+	if mode == "" {
+		mode = THS_AUTONOMOUS
+	}
+
+	bs := fmt.Sprintf("%sTHS,%s,%s", talkerOrDefault(talker), headingS, mode)
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
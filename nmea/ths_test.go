@@ -0,0 +1,54 @@
+package nmea
+
+import "testing"
+
+func TestToTHS(t *testing.T) {
+	testCases := []struct {
+		name     string
+		heading  float64
+		mode     string
+		expected string
+	}{
+		{"Default Mode", 77.52, "", "$GPTHS,77.520,A*00\r\n"},
+		{"Estimated", 77.52, THS_ESTIMATED, "$GPTHS,77.520,E*04\r\n"},
+		{"Simulator", 77.52, THS_SIMULATOR, "$GPTHS,77.520,S*12\r\n"},
+		{"Invalid", 0, THS_INVALID, "$GPTHS,0.000,V*20\r\n"},
+		{"Negative Heading", -45.123, THS_AUTONOMOUS, "$GPTHS,314.877,A*39\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result := ToTHS(GP, tc.heading, tc.mode)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseTHSMode(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mode  string
+		valid bool
+	}{
+		{"Autonomous", "A", true},
+		{"Simulator", "S", true},
+		{"Lower Case", "s", false},
+		{"Unknown", "X", false},
+		{"Too Long", "SS", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, err := ParseTHSMode(tc.mode)
+			if tc.valid && (err != nil || mode != tc.mode) {
+				t.Errorf("Expected: %s, but got: %s, %v", tc.mode, mode, err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected an error, but got: %s", mode)
+			}
+		})
+	}
+}
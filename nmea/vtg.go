@@ -2,11 +2,26 @@ package nmea
 
 import (
 	"fmt"
-	"math"
 )
 
 // ToGPVTG will convert a heading, speedEast and speedSouth to a NMEA GPVTG message
+// No magnetic variation is applied, so the magnetic course is the same as the true course
 func ToGPVTG(heading float64, sog float64) string {
+	return ToVTG(GP, heading, 0, sog)
+}
+
+// ToVTG will convert a heading, magnetic variation and speed over ground to a NMEA VTG message with the given
+// talker ID
+// variation is the magnetic declination in degrees, positive east
+// If talker is empty, the DefaultTalker is used
+func ToVTG(talker TalkerID, heading float64, variation float64, sog float64) string {
+	// D is for Differential. A=Autonomous, D=Differential, E=Estimated, M=Manual input, N=Data not valid
+	return ToVTGMode(talker, heading, variation, sog, MODE_DIFFERENTIAL)
+}
+
+// ToVTGMode will convert a heading, magnetic variation and speed over ground to a NMEA VTG message like ToVTG,
+// with the mode indicator given, eg when the fix has been lost
+func ToVTGMode(talker TalkerID, heading float64, variation float64, sog float64, mode string) string {
 	// Example GPVTG message:
 	// $GPVTG,224.592,T,224.592,M,0.003,N,0.005,K,D*20
 	// 224.592,T      True course made good over ground, in degrees
@@ -18,19 +33,16 @@ func ToGPVTG(heading float64, sog float64) string {
 	// heading is sometimes negative
 	// limit heading to 3 decimal places
 	// first heading is true (T), second is magnetic (M)
-	headingS := fmt.Sprintf(Formats.hdg, math.Mod(heading+360, 360))
+	fm := CurrentFormats()
+	headingS := fmt.Sprintf(fm.hdg, normaliseHeading(heading))
+	magneticS := fmt.Sprintf(fm.hdg, normaliseHeading(heading-variation))
 
 	// knots (N) = 1.94384 * m/s
-	sogKnots := fmt.Sprintf(Formats.sog+",N", sog*1.943845249221964)
+	sogKnots := fmt.Sprintf(fm.sog+",N", sog*1.943845249221964)
 	// km/h (K) = 3.6 * m/s
-	sogKmh := fmt.Sprintf(Formats.sog+",K", sog*3.6)
-
-	// D is for Differential. A=Autonomous, D=Differential, E=Estimated, M=Manual input, N=Data not valid
-	mode := "D"
+	sogKmh := fmt.Sprintf(fm.sog+",K", sog*3.6)
 
-	// limit heading to 3 decimal places
-	// true heading (T) and magnetic heading (M) are the same
-	bs := fmt.Sprintf("GPVTG,%s,T,%s,M,%s,%s,%s", headingS, headingS, sogKnots, sogKmh, mode)
+	bs := fmt.Sprintf("%sVTG,%s,T,%s,M,%s,%s,%s", talkerOrDefault(talker), headingS, magneticS, sogKnots, sogKmh, mode)
 
 	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
 }
@@ -29,7 +29,7 @@ func TestToGPVTG(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			Formats = tc.format
+			SetFormats(tc.format)
 			result := ToGPVTG(tc.heading, tc.sog)
 			if result != tc.expected {
 				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
@@ -37,3 +37,31 @@ func TestToGPVTG(t *testing.T) {
 		})
 	}
 }
+
+func TestToVTG(t *testing.T) {
+	testCases := []struct {
+		name      string
+		talker    TalkerID
+		heading   float64
+		variation float64
+		sog       float64
+		expected  string
+	}{
+		{"No Variation", GP, 45.123, 0, 1, "$GPVTG,45.123,T,45.123,M,1.943845,N,3.600000,K,D*25\r\n"},
+		{"East Variation", GP, 45.123, 10.5, 1, "$GPVTG,45.123,T,34.623,M,1.943845,N,3.600000,K,D*24\r\n"},
+		{"West Variation", GP, 45.123, -10.5, 1, "$GPVTG,45.123,T,55.623,M,1.943845,N,3.600000,K,D*23\r\n"},
+		{"Variation Wraps", GP, 5, 10, 1, "$GPVTG,5.000,T,355.000,M,1.943845,N,3.600000,K,D*23\r\n"},
+		{"Negative Heading", GP, -45.123, -10, 10, "$GPVTG,314.877,T,324.877,M,19.438452,N,36.000000,K,D*24\r\n"},
+		{"GN Talker", GN, 45.123, 0, 1, "$GNVTG,45.123,T,45.123,M,1.943845,N,3.600000,K,D*3B\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetFormats(DEFAULTS)
+			result := ToVTG(tc.talker, tc.heading, tc.variation, tc.sog)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
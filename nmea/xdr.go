@@ -0,0 +1,43 @@
+package nmea
+
+import (
+	"fmt"
+	"strings"
+)
+
+// XDR transducer types and units
+const (
+	XDR_ANGULAR = "A" // angular displacement
+	XDR_GENERIC = "G" // generic, with no standard units
+
+	XDR_DEGREES = "D"
+)
+
+// Transducer is a single measurement in a XDR message
+type Transducer struct {
+	Type  string // the transducer type, eg XDR_ANGULAR
+	Value float64
+	Units string // the units of the value, eg XDR_DEGREES
+	Name  string // the name of the transducer, eg "PTCH"
+}
+
+// ToXDR will convert transducer measurements to a NMEA XDR message with the given talker ID
+// If talker is empty, the DefaultTalker is used
+func ToXDR(talker TalkerID, measurements ...Transducer) string {
+	// Example IIXDR message:
+	// $IIXDR,A,2.5,D,PTCH,A,-10.1,D,ROLL*50
+	// A            Transducer type, A=Angular displacement
+	// 2.5          Measurement
+	// D            Units, D=Degrees
+	// PTCH         Transducer name
+	// ...          The next transducer
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%sXDR", talkerOrDefault(talker))
+	for _, m := range measurements {
+		fmt.Fprintf(&sb, ",%s,%0.1f,%s,%s", m.Type, m.Value, m.Units, m.Name)
+	}
+	bs := sb.String()
+
+	return fmt.Sprintf("$%s*%02X\r\n", bs, calculateChecksum(bs))
+}
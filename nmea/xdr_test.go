@@ -0,0 +1,39 @@
+package nmea
+
+import "testing"
+
+func TestToXDR(t *testing.T) {
+	testCases := []struct {
+		name         string
+		measurements []Transducer
+		expected     string
+	}{
+		{"Empty", nil, "$IIXDR*4E\r\n"},
+		{
+			"Attitude",
+			[]Transducer{
+				{XDR_ANGULAR, 2.5, XDR_DEGREES, "PTCH"},
+				{XDR_ANGULAR, -10.14, XDR_DEGREES, "ROLL"},
+			},
+			"$IIXDR,A,2.5,D,PTCH,A,-10.1,D,ROLL*46\r\n",
+		},
+		{
+			"Rates",
+			[]Transducer{
+				{XDR_GENERIC, 1.25, "", "RRTE"},
+				{XDR_GENERIC, -0.5, "", "PRTE"},
+				{XDR_GENERIC, 3, "", "YRTE"},
+			},
+			"$IIXDR,G,1.2,,RRTE,G,-0.5,,PRTE,G,3.0,,YRTE*17\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ToXDR(II, tc.measurements...)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package noise adds the errors of a real GPS receiver to the positions from X-Plane, which are otherwise
+// perfectly smooth sim truth
+package noise
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Typical errors of a consumer GPS receiver without augmentation
+const (
+	DEFAULT_HORIZONTAL_DRIFT   = 2.0
+	DEFAULT_VERTICAL_DRIFT     = 3.5
+	DEFAULT_CORRELATION_TIME   = 60 * time.Second
+	DEFAULT_HORIZONTAL_NOISE   = 0.3
+	DEFAULT_VERTICAL_NOISE     = 0.5
+	DEFAULT_VELOCITY_NOISE     = 0.05
+	DEFAULT_MULTIPATH_INTERVAL = 5 * time.Minute
+	DEFAULT_MULTIPATH_SIZE     = 10.0
+	DEFAULT_MULTIPATH_DURATION = 5 * time.Second
+)
+
+// earthRadius is the mean radius of the earth in meters, which is accurate enough for errors of a few meters
+const earthRadius = 6371000.0
+
+// Model is a GPS error model
+// The position wanders slowly, as first order Gauss-Markov processes, with white noise on each fix and
+// occasional multipath jumps. The quality of each fix is set to match the errors. Models with the same seed
+// and parameters add the same errors to the same positions.
+type Model struct {
+	// HorizontalDrift and VerticalDrift are the steady state 1 sigma of the slowly wandering error on each
+	// axis in meters
+	HorizontalDrift float64
+	VerticalDrift   float64
+	// CorrelationTime is how long the drift takes to wander, the time constant of the Gauss-Markov processes
+	CorrelationTime time.Duration
+	// HorizontalNoise and VerticalNoise are the 1 sigma of the white noise on each fix in meters
+	HorizontalNoise float64
+	VerticalNoise   float64
+	// VelocityNoise is the 1 sigma of the white noise on each velocity component in m/s
+	VelocityNoise float64
+	// MultipathInterval is the mean time between multipath jumps. 0 turns them off
+	MultipathInterval time.Duration
+	// MultipathSize is the 1 sigma size of a multipath jump in meters
+	MultipathSize float64
+	// MultipathDuration is how long a multipath jump lasts
+	MultipathDuration time.Duration
+
+	rnd  *rand.Rand
+	last time.Time
+	// drift is the north, east and up drift in meters
+	drift [3]float64
+	// multipath is the north and east multipath jump in meters, until multipathEnd
+	multipath    [2]float64
+	multipathEnd time.Time
+}
+
+// NewModel returns a new Model with the typical errors of a consumer receiver, seeded with seed
+func NewModel(seed int64) *Model {
+	m := &Model{
+		HorizontalDrift:   DEFAULT_HORIZONTAL_DRIFT,
+		VerticalDrift:     DEFAULT_VERTICAL_DRIFT,
+		CorrelationTime:   DEFAULT_CORRELATION_TIME,
+		HorizontalNoise:   DEFAULT_HORIZONTAL_NOISE,
+		VerticalNoise:     DEFAULT_VERTICAL_NOISE,
+		VelocityNoise:     DEFAULT_VELOCITY_NOISE,
+		MultipathInterval: DEFAULT_MULTIPATH_INTERVAL,
+		MultipathSize:     DEFAULT_MULTIPATH_SIZE,
+		MultipathDuration: DEFAULT_MULTIPATH_DURATION,
+	}
+	m.Seed(seed)
+	return m
+}
+
+// Seed will restart the model with a new seed
+func (m *Model) Seed(seed int64) {
+	m.rnd = rand.New(rand.NewSource(seed))
+	m.last = time.Time{}
+	m.drift = [3]float64{}
+	m.multipath = [2]float64{}
+	m.multipathEnd = time.Time{}
+}
+
+// Apply returns the position at t with the errors added, and its quality set to match
+func (m *Model) Apply(p xplane.Position, t time.Time) xplane.Position {
+	if m.rnd == nil {
+		m.Seed(0)
+	}
+	m.step(t)
+
+	north := m.drift[0] + m.HorizontalNoise*m.rnd.NormFloat64()
+	east := m.drift[1] + m.HorizontalNoise*m.rnd.NormFloat64()
+	up := m.drift[2] + m.VerticalNoise*m.rnd.NormFloat64()
+	multipath := 0.0
+	if t.Before(m.multipathEnd) {
+		north += m.multipath[0]
+		east += m.multipath[1]
+		multipath = m.MultipathSize
+	}
+
+	p.Dat_lat += north / earthRadius * 180 / math.Pi
+	p.Dat_lon += east / (earthRadius * math.Cos(p.Dat_lat*math.Pi/180)) * 180 / math.Pi
+	p.Dat_ele += up
+	p.Y_agl_mtr += float32(up)
+
+	p.Vx_wrl += float32(m.VelocityNoise * m.rnd.NormFloat64())
+	p.Vy_wrl += float32(m.VelocityNoise * m.rnd.NormFloat64())
+	p.Vz_wrl += float32(m.VelocityNoise * m.rnd.NormFloat64())
+
+	// a receiver sees the multipath in its residuals, so it reports a worse accuracy while it lasts
+	hAcc := math.Sqrt(m.HorizontalDrift*m.HorizontalDrift + m.HorizontalNoise*m.HorizontalNoise + multipath*multipath)
+	vAcc := math.Hypot(m.VerticalDrift, m.VerticalNoise)
+	p.Quality = gnss.QualityFor(hAcc, vAcc, m.VelocityNoise)
+	return p
+}
+
+// step will advance the drift and multipath to t
+func (m *Model) step(t time.Time) {
+	sigmas := [3]float64{m.HorizontalDrift, m.HorizontalDrift, m.VerticalDrift}
+
+	if m.last.IsZero() {
+		// start from the steady state, as if the receiver had been running
+		for i, s := range sigmas {
+			m.drift[i] = s * m.rnd.NormFloat64()
+		}
+		m.last = t
+		return
+	}
+	dt := t.Sub(m.last)
+	if dt <= 0 {
+		// a repeated or out of order time keeps the drift it has
+		return
+	}
+	m.last = t
+
+	phi := 0.0
+	if m.CorrelationTime > 0 {
+		phi = math.Exp(-dt.Seconds() / m.CorrelationTime.Seconds())
+	}
+	q := math.Sqrt(1 - phi*phi)
+	for i, s := range sigmas {
+		m.drift[i] = phi*m.drift[i] + s*q*m.rnd.NormFloat64()
+	}
+
+	// jumps start as a Poisson process
+	if m.MultipathInterval > 0 && !t.Before(m.multipathEnd) {
+		if m.rnd.Float64() < 1-math.Exp(-dt.Seconds()/m.MultipathInterval.Seconds()) {
+			size := m.MultipathSize * math.Abs(m.rnd.NormFloat64())
+			dir := 2 * math.Pi * m.rnd.Float64()
+			m.multipath = [2]float64{size * math.Cos(dir), size * math.Sin(dir)}
+			m.multipathEnd = t.Add(m.MultipathDuration)
+		}
+	}
+}
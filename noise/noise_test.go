@@ -0,0 +1,172 @@
+package noise
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var start = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// offsets returns the north, east and up errors in meters the model adds to n positions at rate
+func offsets(m *Model, n int, rate time.Duration) (north, east, up []float64) {
+	p := xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 100}
+	for i := 0; i < n; i++ {
+		q := m.Apply(p, start.Add(time.Duration(i)*rate))
+		north = append(north, (q.Dat_lat-p.Dat_lat)*math.Pi/180*earthRadius)
+		east = append(east, (q.Dat_lon-p.Dat_lon)*math.Pi/180*earthRadius*math.Cos(q.Dat_lat*math.Pi/180))
+		up = append(up, q.Dat_ele-p.Dat_ele)
+	}
+	return north, east, up
+}
+
+// stddev returns the standard deviation of xs about zero
+func stddev(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x * x
+	}
+	return math.Sqrt(sum / float64(len(xs)))
+}
+
+func TestReproducible(t *testing.T) {
+	a, _, _ := offsets(NewModel(42), 100, 200*time.Millisecond)
+	b, _, _ := offsets(NewModel(42), 100, 200*time.Millisecond)
+	c, _, _ := offsets(NewModel(43), 100, 200*time.Millisecond)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected: %f at %d with the same seed, but got: %f", a[i], i, b[i])
+		}
+	}
+	if a[0] == c[0] {
+		t.Errorf("Expected different errors with a different seed, but got: %f", c[0])
+	}
+
+	m := NewModel(42)
+	offsets(m, 10, time.Second)
+	m.Seed(42)
+	d, _, _ := offsets(m, 100, 200*time.Millisecond)
+	if a[0] != d[0] {
+		t.Errorf("Expected: %f after reseeding, but got: %f", a[0], d[0])
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	testCases := []struct {
+		name  string
+		model Model
+	}{
+		{"Drift", Model{HorizontalDrift: 2, VerticalDrift: 3, CorrelationTime: time.Second}},
+		{"Noise", Model{HorizontalNoise: 1, VerticalNoise: 1.5}},
+		{"Both", Model{HorizontalDrift: 2, VerticalDrift: 3, CorrelationTime: time.Second, HorizontalNoise: 1, VerticalNoise: 1.5}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.model
+			m.Seed(1)
+			// samples far apart compared to the correlation time are close to independent
+			north, east, up := offsets(&m, 20000, 10*time.Second)
+
+			wantH := math.Hypot(m.HorizontalDrift, m.HorizontalNoise)
+			wantV := math.Hypot(m.VerticalDrift, m.VerticalNoise)
+			for _, c := range []struct {
+				name string
+				xs   []float64
+				want float64
+			}{{"north", north, wantH}, {"east", east, wantH}, {"up", up, wantV}} {
+				if got := stddev(c.xs); math.Abs(got-c.want) > 0.05*c.want {
+					t.Errorf("Expected: %s sigma %f, but got: %f", c.name, c.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	m := Model{HorizontalDrift: 2, CorrelationTime: time.Minute}
+	m.Seed(1)
+	north, _, _ := offsets(&m, 600, 100*time.Millisecond)
+
+	// over a minute the drift wanders, but from one fix to the next it barely moves
+	maxStep := 0.0
+	for i := 1; i < len(north); i++ {
+		maxStep = math.Max(maxStep, math.Abs(north[i]-north[i-1]))
+	}
+	if maxStep > 0.5 {
+		t.Errorf("Expected steps of at most 0.5m, but got: %f", maxStep)
+	}
+}
+
+func TestRepeatedTime(t *testing.T) {
+	m := Model{HorizontalDrift: 2, VerticalDrift: 3, CorrelationTime: time.Minute}
+	m.Seed(1)
+	m.step(start.Add(time.Second))
+	drift := m.drift
+
+	// a repeated or earlier time must not start the drift again
+	for _, ts := range []time.Time{start.Add(time.Second), start} {
+		m.step(ts)
+		if m.drift != drift {
+			t.Errorf("Expected the drift to stay: %v, but got: %v", drift, m.drift)
+		}
+	}
+}
+
+func TestMultipath(t *testing.T) {
+	m := Model{MultipathInterval: time.Minute, MultipathSize: 20, MultipathDuration: 5 * time.Second}
+	m.Seed(1)
+
+	p := xplane.Position{Dat_lat: 45, Dat_lon: -75}
+	jumps, jumped, worst := 0, false, 0.0
+	for i := 0; i < 3600; i++ {
+		q := m.Apply(p, start.Add(time.Duration(i)*time.Second))
+		moved := q.Dat_lat != p.Dat_lat || q.Dat_lon != p.Dat_lon
+		if moved && !jumped {
+			jumps++
+		}
+		if moved {
+			worst = math.Max(worst, q.Quality.HAcc)
+		} else if q.Quality.HAcc > 1 {
+			t.Errorf("Expected: a good accuracy without multipath, but got: %f", q.Quality.HAcc)
+		}
+		jumped = moved
+	}
+
+	// an hour with a jump a minute on average, but each lasts 5 seconds
+	if jumps < 30 || jumps > 90 {
+		t.Errorf("Expected: about 55 jumps, but got: %d", jumps)
+	}
+	if worst < m.MultipathSize {
+		t.Errorf("Expected: an accuracy of at least %f during multipath, but got: %f", m.MultipathSize, worst)
+	}
+}
+
+func TestApply(t *testing.T) {
+	p := xplane.Position{Dat_lat: 45, Dat_lon: -75, Dat_ele: 100, Vx_wrl: 10, Vy_wrl: 1, Vz_wrl: -20}
+
+	t.Run("Off", func(t *testing.T) {
+		m := Model{}
+		q := m.Apply(p, start)
+		q.Quality = p.Quality
+		if q != p {
+			t.Errorf("Expected: %+v, but got: %+v", p, q)
+		}
+	})
+
+	t.Run("Quality", func(t *testing.T) {
+		m := NewModel(1)
+		q := m.Apply(p, start)
+		if q.Quality.HAcc < DEFAULT_HORIZONTAL_DRIFT || q.Quality.VAcc < DEFAULT_VERTICAL_DRIFT {
+			t.Errorf("Expected: accuracies worse than the drift, but got: %+v", q.Quality)
+		}
+		if q.Quality.HDOP <= 1 || q.Quality.PDOP < q.Quality.HDOP {
+			t.Errorf("Expected: dilutions of precision matching the accuracy, but got: %+v", q.Quality)
+		}
+		if q.Vx_wrl == p.Vx_wrl || q.Vz_wrl == p.Vz_wrl {
+			t.Errorf("Expected: noise on the velocities, but got: %+v", q)
+		}
+	})
+}
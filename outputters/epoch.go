@@ -0,0 +1,169 @@
+package outputters
+
+import (
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/wmm"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Epoch is a navigation solution a sink sends, with the context its outputters share
+type Epoch struct {
+	// Position is the position of the solution
+	Position xplane.Position
+	// Previous is the position of the previous solution the sink sent, or nil for the first
+	Previous *xplane.Position
+	// Count is how many solutions the sink sent before this one
+	Count uint64
+	// Time is the time of the fix in UTC
+	Time time.Time
+	// Quality is the quality of the fix, with nominal values for the fields that aren't known
+	Quality gnss.Quality
+	// SOG is the speed over ground in m/s, and Track is the true track over the ground in degrees
+	SOG   float64
+	Track float64
+
+	// the derived values below are only worked out if an outputter needs them
+	variation    float64
+	hasVariation bool
+	sky          []gnss.Satellite
+	hasSky       bool
+}
+
+// NewEpoch returns the epoch of a position, after the previous position, or nil for the first
+func NewEpoch(p xplane.Position, previous *xplane.Position, count uint64) *Epoch {
+	e := &Epoch{}
+	e.set(p, previous, count)
+	return e
+}
+
+// set will set the epoch to the solution of p
+func (e *Epoch) set(p xplane.Position, previous *xplane.Position, count uint64) {
+	*e = Epoch{
+		Position: p,
+		Previous: previous,
+		Count:    count,
+		Time:     p.FixTime(),
+		Quality:  p.Quality.OrNominal(),
+		SOG:      p.SOG(),
+		Track:    p.Track(),
+	}
+}
+
+// Variation returns the magnetic variation at the position at the time of the fix, in degrees positive east
+func (e *Epoch) Variation() float64 {
+	if !e.hasVariation {
+		p := &e.Position
+		e.variation = wmm.Declination(p.Dat_lat, p.Dat_lon, p.Dat_ele, e.Time)
+		e.hasVariation = true
+	}
+	return e.variation
+}
+
+// Sky returns the satellites in view, as the receiver sees them with the quality of the fix
+func (e *Epoch) Sky() []gnss.Satellite {
+	if !e.hasSky {
+		e.sky = e.Position.Sky(e.Time)
+		e.hasSky = true
+	}
+	return e.sky
+}
+
+// Epochs makes the epochs of the solutions a sink sends, remembering the previous one
+type Epochs struct {
+	epoch    Epoch
+	previous xplane.Position
+	count    uint64
+}
+
+// Next returns the epoch of the next solution the sink sends
+// The epoch is reused, so it is only valid until Next is called again.
+func (es *Epochs) Next(p xplane.Position) *Epoch {
+	var previous *xplane.Position
+	if es.count > 0 {
+		es.previous = es.epoch.Position
+		previous = &es.previous
+	}
+	es.epoch.set(p, previous, es.count)
+	es.count++
+	return &es.epoch
+}
+
+// Frames are the sentences or binary frames output for an epoch, in order
+// They share one buffer that is reused for every epoch, so outputting doesn't allocate once it has grown.
+type Frames struct {
+	buf  []byte
+	ends []int
+}
+
+// Reset will remove all the frames, keeping the buffer
+func (f *Frames) Reset() {
+	f.buf = f.buf[:0]
+	f.ends = f.ends[:0]
+}
+
+// Next returns the buffer to append the next frame to, which is then added with Add
+func (f *Frames) Next() []byte {
+	return f.buf
+}
+
+// Add will add the frame appended to the buffer from Next
+func (f *Frames) Add(b []byte) {
+	f.buf = b
+	f.ends = append(f.ends, len(b))
+}
+
+// Len returns the number of frames
+func (f *Frames) Len() int {
+	return len(f.ends)
+}
+
+// Frame returns frame i, which is only valid until the frames are reset
+func (f *Frames) Frame(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = f.ends[i-1]
+	}
+	return f.buf[start:f.ends[i]]
+}
+
+// Simple is an outputter of one sentence or frame for each position, which doesn't need the context of the
+// epoch
+type Simple interface {
+	Output(xplane.Position) (string, error)
+}
+
+// Appender is a Simple outputter that can append its output to a buffer, instead of allocating a string
+type Appender interface {
+	Simple
+	AppendOutput(b []byte, p xplane.Position) ([]byte, error)
+}
+
+// Adapt returns an Outputter for a Simple outputter, which outputs one frame with each epoch
+func Adapt(s Simple) Outputter {
+	return adapter{s}
+}
+
+// adapter is an Outputter that outputs a Simple outputter
+type adapter struct {
+	Simple
+}
+
+// Outputs will add the output of the Simple outputter for the position of the epoch to f
+func (a adapter) Outputs(e *Epoch, f *Frames) error {
+	if ap, ok := a.Simple.(Appender); ok {
+		b, err := ap.AppendOutput(f.Next(), e.Position)
+		if err != nil {
+			return err
+		}
+		f.Add(b)
+		return nil
+	}
+	msg, err := a.Output(e.Position)
+	if err != nil {
+		return err
+	}
+	f.Add(append(f.Next(), msg...))
+	return nil
+}
@@ -0,0 +1,105 @@
+package outputters
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestEpochs(t *testing.T) {
+	var es Epochs
+	first := xplane.Position{Dat_lat: 1}
+	second := xplane.Position{Dat_lat: 2}
+
+	e := es.Next(first)
+	if e.Previous != nil || e.Count != 0 {
+		t.Errorf("Expected no previous position and a count of 0, but got: %v, %d", e.Previous, e.Count)
+	}
+	e = es.Next(second)
+	if e.Previous == nil || e.Previous.Dat_lat != 1 || e.Count != 1 {
+		t.Errorf("Expected the previous position and a count of 1, but got: %v, %d", e.Previous, e.Count)
+	}
+	if e.Position.Dat_lat != 2 {
+		t.Errorf("Expected: %v, but got: %v", 2, e.Position.Dat_lat)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var f Frames
+	for i := 0; i < 2; i++ {
+		f.Reset()
+		f.Add(append(f.Next(), "first"...))
+		f.Add(append(f.Next(), "second"...))
+		f.Add(f.Next())
+
+		expected := []string{"first", "second", ""}
+		if f.Len() != len(expected) {
+			t.Fatalf("Expected: %d frames, but got: %d", len(expected), f.Len())
+		}
+		for j, e := range expected {
+			if string(f.Frame(j)) != e {
+				t.Errorf("Expected: %s, but got: %s", e, f.Frame(j))
+			}
+		}
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	pos := xplane.Position{Dat_lat: 45, Dat_lon: -75, Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	for _, s := range []Simple{&RMC{}, &PASHR{}} {
+		expected, err := s.Output(pos)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		result, err := output(Adapt(s), pos)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if result != expected {
+			t.Errorf("Expected: %s, but got: %s", expected, result)
+		}
+	}
+}
+
+func TestGSV(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		offset time.Duration
+		sent   bool
+	}{
+		{"First", 0, true},
+		{"Same Second", 200 * time.Millisecond, false},
+		{"Next Second", time.Second, true},
+		{"Late In Second", 1800 * time.Millisecond, false},
+	}
+
+	var es Epochs
+	var f Frames
+	g := &GSV{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := es.Next(xplane.Position{Dat_lat: 45, Dat_lon: -75, Time: start.Add(tc.offset)})
+			f.Reset()
+			if err := g.Outputs(e, &f); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !tc.sent {
+				if f.Len() != 0 {
+					t.Errorf("Expected no sentences, but got: %d", f.Len())
+				}
+				return
+			}
+			if f.Len() == 0 {
+				t.Fatalf("Expected GSV sentences, but got none")
+			}
+			for i := 0; i < f.Len(); i++ {
+				if !strings.HasPrefix(string(f.Frame(i)), "$GPGSV,") {
+					t.Errorf("Expected a GSV sentence, but got: %s", f.Frame(i))
+				}
+			}
+		})
+	}
+}
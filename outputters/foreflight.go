@@ -0,0 +1,63 @@
+package outputters
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// FOREFLIGHT_ADDR is the address ForeFlight listens for XGPS and XATT messages on, broadcast to the local
+// network
+const FOREFLIGHT_ADDR = "255.255.255.255:49002"
+
+// DEFAULT_SIM_NAME is the simulator name shown in ForeFlight if none is set
+const DEFAULT_SIM_NAME = "X-Plane GPS"
+
+// XGPS is an Outputter that returns a ForeFlight XGPS position message
+type XGPS struct {
+	// Name is the simulator name shown in ForeFlight. If empty, DEFAULT_SIM_NAME is used
+	Name string
+}
+
+// Output returns a XGPS message
+// eg XGPSX-Plane GPS,-75.654321,45.123456,1234.5,123.45,56.7
+// The fields are longitude, latitude, altitude above MSL in meters, true track and ground speed in m/s
+func (x *XGPS) Output(p xplane.Position) (string, error) {
+	return fmt.Sprintf("XGPS%s,%0.6f,%0.6f,%0.1f,%0.2f,%0.1f",
+		simName(x.Name),
+		p.Dat_lon,
+		p.Dat_lat,
+		p.Dat_ele,
+		p.Track(),
+		p.SOG(),
+	), nil
+}
+
+// XATT is an Outputter that returns a ForeFlight XATT attitude message
+type XATT struct {
+	// Name is the simulator name shown in ForeFlight. If empty, DEFAULT_SIM_NAME is used
+	Name string
+}
+
+// Output returns a XATT message
+// eg XATTX-Plane GPS,123.4,-2.5,10.2
+// The fields are true heading, pitch (up is positive) and roll (right is positive), in degrees
+func (x *XATT) Output(p xplane.Position) (string, error) {
+	return fmt.Sprintf("XATT%s,%0.1f,%0.1f,%0.1f",
+		simName(x.Name),
+		math.Mod(float64(p.Veh_psi_loc)+360, 360),
+		p.Veh_the_loc,
+		p.Veh_phi_loc,
+	), nil
+}
+
+// simName returns the name to use in ForeFlight messages
+// Commas would break the message, so they are removed
+func simName(name string) string {
+	if name == "" {
+		return DEFAULT_SIM_NAME
+	}
+	return strings.ReplaceAll(name, ",", "")
+}
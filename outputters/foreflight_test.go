@@ -0,0 +1,43 @@
+package outputters
+
+import (
+	"testing"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestForeFlight(t *testing.T) {
+	pos := xplane.Position{
+		Dat_lat:     45.123456,
+		Dat_lon:     -75.654321,
+		Dat_ele:     1234.5,
+		Veh_psi_loc: -10,
+		Veh_the_loc: -2.5,
+		Veh_phi_loc: 10.25,
+		Vx_wrl:      -30,
+		Vz_wrl:      -40,
+	}
+
+	testCases := []struct {
+		name      string
+		outputter Simple
+		expected  string
+	}{
+		{"XGPS", &XGPS{Name: "Sim"}, "XGPSSim,-75.654321,45.123456,1234.5,323.13,50.0"},
+		{"XGPS Default Name", &XGPS{}, "XGPSX-Plane GPS,-75.654321,45.123456,1234.5,323.13,50.0"},
+		{"XATT", &XATT{Name: "Sim"}, "XATTSim,350.0,-2.5,10.2"},
+		{"XATT Comma Name", &XATT{Name: "My,Sim"}, "XATTMySim,350.0,-2.5,10.2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.outputter.Output(pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
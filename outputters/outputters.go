@@ -1,28 +1,330 @@
 package outputters
 
 import (
+	"fmt"
+	"math"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/wmm"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
 )
 
-// Outputter is the interface that takes a xplane Position and returns a string for use as output
-// eg NMEA sentences
+// Outputter is the interface that takes the epoch of a navigation solution and outputs it, eg as NMEA sentences
+// or UBX messages
+// An outputter can output any number of frames for an epoch, eg a group of GSV sentences or nothing between
+// them. Simple outputters of one frame for each position can be adapted with Adapt.
 type Outputter interface {
-	Output(xplane.Position) (string, error)
+	// Outputs will add the frames for the epoch to f
+	Outputs(e *Epoch, f *Frames) error
+}
+
+// AltitudeReference selects how the altitude and geoid separation are reported in a GGA sentence
+type AltitudeReference uint8
+
+const (
+	// MSL reports the altitude above mean sea level and the geoid separation, as the NMEA standard requires
+	MSL AltitudeReference = iota
+	// Ellipsoid reports the height above the WGS84 ellipsoid and a geoid separation of zero, as some receivers do
+	Ellipsoid
+)
+
+// ParseAltitudeReference returns the AltitudeReference for "msl" or "ellipsoid"
+func ParseAltitudeReference(s string) (AltitudeReference, error) {
+	switch s {
+	case "msl":
+		return MSL, nil
+	case "ellipsoid":
+		return Ellipsoid, nil
+	default:
+		return MSL, fmt.Errorf("unsupported altitude reference: %q", s)
+	}
+}
+
+// GGA is an Outputter that returns a GGA NMEA sentence
+type GGA struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+	// Altitude is how the altitude is reported. Defaults to MSL
+	Altitude AltitudeReference
+}
+
+// Outputs will add a GGA NMEA sentence to f
+func (g *GGA) Outputs(e *Epoch, f *Frames) error {
+	p := &e.Position
+	// X-Plane reports the altitude above mean sea level
+	sep := geoid.Separation(p.Dat_lat, p.Dat_lon)
+	alt := p.Dat_ele
+	if g.Altitude == Ellipsoid {
+		alt, sep = alt+sep, 0
+	}
+	q := e.Quality
+	quality, _, _ := nmeaFix(q.Fix)
+	// the satellites used are those of the sky, so GGA agrees with GSA and GSV
+	sats := gnss.Used(e.Sky())
+	f.Add(nmea.AppendGGAFix(f.Next(), g.Talker, e.Time, p.Dat_lat, p.Dat_lon, alt, sep, quality, uint(sats), q.HDOP))
+	return nil
+}
+
+// VTG is an Outputter that returns a VTG NMEA sentence
+type VTG struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Outputs will add a VTG NMEA sentence to f
+func (v *VTG) Outputs(e *Epoch, f *Frames) error {
+	_, _, mode := nmeaFix(e.Quality.Fix)
+	f.Add(nmea.AppendVTGMode(f.Next(), v.Talker, e.Track, e.Variation(), e.SOG, mode))
+	return nil
+}
+
+// GSV is an Outputter that returns the satellites in view as a group of GSV NMEA sentences
+// Like a receiver, the group is only sent once a second, with the first solution of each second.
+type GSV struct {
+	// Talker is the talker ID of the sentences. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Outputs will add the GSV NMEA sentences to f, if it is the first epoch of a second
+func (g *GSV) Outputs(e *Epoch, f *Frames) error {
+	if e.Previous != nil && e.Previous.FixTime().Unix() == e.Time.Unix() {
+		return nil
+	}
+
+	var group [nmea.GSV_SATELLITES]nmea.GSVSatellite
+	sky := e.Sky()
+	total := max((len(sky)+nmea.GSV_SATELLITES-1)/nmea.GSV_SATELLITES, 1)
+	for i := 0; i < total; i++ {
+		n := 0
+		for _, s := range sky[min(i*nmea.GSV_SATELLITES, len(sky)):min((i+1)*nmea.GSV_SATELLITES, len(sky))] {
+			group[n] = nmea.GSVSatellite{
+				PRN:       int(s.PRN),
+				Elevation: int(math.Round(s.Elevation)),
+				Azimuth:   int(math.Round(s.Azimuth)) % 360,
+				SNR:       int(math.Round(s.SNR)),
+			}
+			n++
+		}
+		f.Add(nmea.AppendGSV(f.Next(), g.Talker, total, i+1, len(sky), group[:n]))
+	}
+	return nil
+}
+
+// RMC is an Outputter that returns a RMC NMEA sentence
+type RMC struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Output returns a RMC NMEA sentence
+func (r *RMC) Output(p xplane.Position) (string, error) {
+	b, err := r.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a RMC NMEA sentence to b
+func (r *RMC) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	_, status, mode := nmeaFix(p.Quality.Fix)
+	return nmea.AppendRMCFix(b, r.Talker, p.FixTime(), p.Dat_lat, p.Dat_lon, p.SOG(), p.Track(), variation(p), status, mode), nil
+}
+
+// GSA is an Outputter that returns a GSA (fix type, satellites used and DOP) NMEA sentence
+type GSA struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Outputs will add a GSA NMEA sentence to f
+func (g *GSA) Outputs(e *Epoch, f *Frames) error {
+	q := e.Quality
+	fixType := nmea.GSA_3D
+	switch q.Fix {
+	case gnss.FIX_2D:
+		fixType = nmea.GSA_2D
+	case gnss.FIX_DR, gnss.FIX_NONE:
+		// no satellites are used, so there is no satellite fix
+		fixType = nmea.GSA_NO_FIX
+	}
+
+	var prns [gnss.MAX_USED]int
+	n := 0
+	for _, s := range e.Sky() {
+		if s.Used && n < len(prns) {
+			prns[n] = int(s.PRN)
+			n++
+		}
+	}
+	f.Add(nmea.AppendGSA(f.Next(), g.Talker, fixType, prns[:n], q.PDOP, q.HDOP, q.VDOP))
+	return nil
+}
+
+// nmeaFix returns the GGA fix quality, RMC status and mode indicator for a fix type
+// A 3D or 2D fix is reported as a simulated fix, as NMEA has no way to tell them apart in these sentences
+func nmeaFix(f gnss.Fix) (quality uint, status string, mode string) {
+	switch f {
+	case gnss.FIX_DR:
+		return nmea.GGA_ESTIMATED, nmea.STATUS_VALID, nmea.MODE_ESTIMATED
+	case gnss.FIX_NONE:
+		return nmea.GGA_NO_FIX, nmea.STATUS_VOID, nmea.MODE_NOT_VALID
+	}
+	return nmea.GGA_SIMULATED, nmea.STATUS_VALID, nmea.MODE_DIFFERENTIAL
+}
+
+// variation returns the magnetic variation at the position at the time of the fix, in degrees positive east
+func variation(p xplane.Position) float64 {
+	return wmm.Declination(p.Dat_lat, p.Dat_lon, p.Dat_ele, p.FixTime())
+}
+
+// HDT is an Outputter that returns a HDT (true heading) NMEA sentence
+type HDT struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Output returns a HDT NMEA sentence
+func (h *HDT) Output(p xplane.Position) (string, error) {
+	b, err := h.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a HDT NMEA sentence to b
+func (h *HDT) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendHDT(b, h.Talker, float64(p.Veh_psi_loc)), nil
+}
+
+// HDM is an Outputter that returns a HDM (magnetic heading) NMEA sentence
+type HDM struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Output returns a HDM NMEA sentence
+func (h *HDM) Output(p xplane.Position) (string, error) {
+	b, err := h.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a HDM NMEA sentence to b
+func (h *HDM) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendHDM(b, h.Talker, float64(p.Veh_psi_loc), variation(p)), nil
+}
+
+// HDG is an Outputter that returns a HDG (heading, deviation and variation) NMEA sentence
+type HDG struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+	// Deviation is the magnetic deviation of the simulated compass in degrees, positive east
+	Deviation float64
+}
+
+// Output returns a HDG NMEA sentence
+func (h *HDG) Output(p xplane.Position) (string, error) {
+	b, err := h.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a HDG NMEA sentence to b
+func (h *HDG) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendHDG(b, h.Talker, float64(p.Veh_psi_loc), h.Deviation, variation(p)), nil
+}
+
+// THS is an Outputter that returns a THS (true heading and status) NMEA sentence
+type THS struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+	// Mode is the mode indicator of the sentence. If empty, nmea.THS_AUTONOMOUS is used
+	Mode string
 }
 
-// GGA is an Outputter that returns a GPGGA NMEA sentence
-type GGA struct{}
+// Output returns a THS NMEA sentence
+func (h *THS) Output(p xplane.Position) (string, error) {
+	b, err := h.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a THS NMEA sentence to b
+func (h *THS) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendTHS(b, h.Talker, float64(p.Veh_psi_loc), h.Mode), nil
+}
+
+// ROT is an Outputter that returns a ROT (rate of turn) NMEA sentence
+type ROT struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
 
-// Output returns a GPGGA NMEA sentence
-func (g *GGA) Output(p xplane.Position) (string, error) {
-	return nmea.ToGPGGA(p.Dat_lat, p.Dat_lon, p.Dat_ele), nil
+// Output returns a ROT NMEA sentence
+func (r *ROT) Output(p xplane.Position) (string, error) {
+	b, err := r.AppendOutput(nil, p)
+	return string(b), err
 }
 
-// VTG is an Outputter that returns a GPVTG NMEA sentence
-type VTG struct{}
+// AppendOutput appends a ROT NMEA sentence to b
+func (r *ROT) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendROT(b, r.Talker, degrees(p.Rrad)), nil
+}
+
+// XDRAttitude is an Outputter that returns a XDR NMEA sentence with the pitch (PTCH) and roll (ROLL) in degrees
+type XDRAttitude struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Output returns a XDR NMEA sentence
+func (x *XDRAttitude) Output(p xplane.Position) (string, error) {
+	return nmea.ToXDR(x.Talker,
+		nmea.Transducer{Type: nmea.XDR_ANGULAR, Value: float64(p.Veh_the_loc), Units: nmea.XDR_DEGREES, Name: "PTCH"},
+		nmea.Transducer{Type: nmea.XDR_ANGULAR, Value: float64(p.Veh_phi_loc), Units: nmea.XDR_DEGREES, Name: "ROLL"},
+	), nil
+}
+
+// XDRRates is an Outputter that returns a XDR NMEA sentence with the roll (RRTE), pitch (PRTE) and yaw (YRTE)
+// rates in degrees per second
+type XDRRates struct {
+	// Talker is the talker ID of the sentence. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+}
+
+// Output returns a XDR NMEA sentence
+func (x *XDRRates) Output(p xplane.Position) (string, error) {
+	return nmea.ToXDR(x.Talker,
+		nmea.Transducer{Type: nmea.XDR_GENERIC, Value: degrees(p.Prad), Name: "RRTE"},
+		nmea.Transducer{Type: nmea.XDR_GENERIC, Value: degrees(p.Qrad), Name: "PRTE"},
+		nmea.Transducer{Type: nmea.XDR_GENERIC, Value: degrees(p.Rrad), Name: "YRTE"},
+	), nil
+}
+
+// PASHR is an Outputter that returns a proprietary PASHR attitude sentence
+type PASHR struct{}
+
+// Output returns a PASHR sentence
+func (a *PASHR) Output(p xplane.Position) (string, error) {
+	b, err := a.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a PASHR sentence to b
+func (a *PASHR) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendPASHRTime(b, p.FixTime(), float64(p.Veh_psi_loc), float64(p.Veh_phi_loc), float64(p.Veh_the_loc)), nil
+}
+
+// PSATHPR is an Outputter that returns a proprietary Hemisphere PSAT,HPR attitude sentence
+type PSATHPR struct{}
+
+// Output returns a PSAT,HPR sentence
+func (a *PSATHPR) Output(p xplane.Position) (string, error) {
+	b, err := a.AppendOutput(nil, p)
+	return string(b), err
+}
+
+// AppendOutput appends a PSAT,HPR sentence to b
+func (a *PSATHPR) AppendOutput(b []byte, p xplane.Position) ([]byte, error) {
+	return nmea.AppendPSATHPRTime(b, p.FixTime(), float64(p.Veh_psi_loc), float64(p.Veh_the_loc), float64(p.Veh_phi_loc)), nil
+}
 
-// Output returns a GPVTG NMEA sentence
-func (v *VTG) Output(p xplane.Position) (string, error) {
-	return nmea.ToGPVTG(float64(p.Veh_psi_loc), p.SOG()), nil
+// degrees converts an angle or rate in radians to degrees
+func degrees(r float32) float64 {
+	return float64(r) * 180 / math.Pi
 }
@@ -0,0 +1,290 @@
+package outputters
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// output returns the frames an Outputter, or a Simple outputter, outputs for the first epoch of p
+func output(o any, p xplane.Position) (string, error) {
+	if s, ok := o.(Simple); ok {
+		o = Adapt(s)
+	}
+	var f Frames
+	if err := o.(Outputter).Outputs(NewEpoch(p, nil, 0), &f); err != nil {
+		return "", err
+	}
+	return string(f.buf), nil
+}
+
+func TestTalker(t *testing.T) {
+	defer nmea.SetDefaultTalker(nmea.GP)
+	pos := xplane.Position{Dat_lat: 12.3456, Dat_lon: 98.7654, Dat_ele: 100.5, Veh_psi_loc: 45, Vx_wrl: 1}
+
+	testCases := []struct {
+		name      string
+		global    nmea.TalkerID
+		outputter any
+		expected  string
+	}{
+		{"GGA Default", nmea.GP, &GGA{}, "$GPGGA,"},
+		{"GGA Global", nmea.GN, &GGA{}, "$GNGGA,"},
+		{"GGA Override", nmea.GN, &GGA{Talker: nmea.II}, "$IIGGA,"},
+		{"VTG Default", nmea.GP, &VTG{}, "$GPVTG,"},
+		{"VTG Global", nmea.GL, &VTG{}, "$GLVTG,"},
+		{"VTG Override", nmea.GL, &VTG{Talker: nmea.GA}, "$GAVTG,"},
+		{"RMC Default", nmea.GP, &RMC{}, "$GPRMC,"},
+		{"RMC Override", nmea.GN, &RMC{Talker: nmea.II}, "$IIRMC,"},
+		{"HDT Default", nmea.GP, &HDT{}, "$GPHDT,"},
+		{"HDM Override", nmea.GP, &HDM{Talker: nmea.II}, "$IIHDM,"},
+		{"HDG Global", nmea.II, &HDG{}, "$IIHDG,"},
+		{"THS Override", nmea.GP, &THS{Talker: nmea.GN}, "$GNTHS,"},
+		{"ROT Default", nmea.GP, &ROT{}, "$GPROT,"},
+		{"XDRAttitude Override", nmea.GP, &XDRAttitude{Talker: nmea.II}, "$IIXDR,"},
+		{"XDRRates Global", nmea.II, &XDRRates{}, "$IIXDR,"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nmea.SetDefaultTalker(tc.global)
+			result, err := output(tc.outputter, pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !strings.HasPrefix(result, tc.expected) {
+				t.Errorf("Expected prefix: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestMagneticVariation(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+	// Ottawa has a westerly variation of about 12 degrees
+	pos := xplane.Position{Dat_lat: 45.42, Dat_lon: -75.70, Veh_psi_loc: 90, Vx_wrl: 1}
+
+	vtg, err := output(&VTG{}, pos)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	fields := strings.Split(vtg, ",")
+	magnetic, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		t.Fatalf("Invalid magnetic course in %q: %v", vtg, err)
+	}
+	if magnetic < 100 || magnetic > 104 {
+		t.Errorf("Expected magnetic course of about 102, but got: %s", vtg)
+	}
+
+	rmc, err := output(&RMC{}, pos)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	fields = strings.Split(rmc, ",")
+	v, err := strconv.ParseFloat(fields[10], 64)
+	if err != nil {
+		t.Fatalf("Invalid variation in %q: %v", rmc, err)
+	}
+	if v < 10 || v > 14 || fields[11] != "W" {
+		t.Errorf("Expected variation of about 12 W, but got: %s", rmc)
+	}
+}
+
+func TestCourseAgreement(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+	// heading east in a crosswind while tracking north east, at a fix time long ago, when the variation was different
+	pos := xplane.Position{Dat_lat: 45.42, Dat_lon: -75.70, Veh_psi_loc: 90, Vx_wrl: 20, Vz_wrl: -20,
+		Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	vtg, err := output(&VTG{}, pos)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	rmc, err := output(&RMC{}, pos)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	hdg, err := output(&HDG{}, pos)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	v, r, h := strings.Split(vtg, ","), strings.Split(rmc, ","), strings.Split(hdg, ",")
+
+	track, _ := strconv.ParseFloat(v[1], 64)
+	if v[1] != r[8] || math.Abs(track-45) > 1 {
+		t.Errorf("Expected RMC course %s like VTG, but got: %s", v[1], r[8])
+	}
+	magnetic, _ := strconv.ParseFloat(v[3], 64)
+	// the variation in Ottawa is westerly
+	if variation := strconv.FormatFloat(magnetic-track, 'f', 1, 64); r[10] != variation || h[4] != variation {
+		t.Errorf("Expected RMC and HDG variation %s like VTG, but got: %s and %s", variation, r[10], h[4])
+	}
+}
+
+func TestGGAAltitude(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+	// the geoid is about 100m below the ellipsoid south of India
+	pos := xplane.Position{Dat_lat: 0, Dat_lon: 80, Dat_ele: 1000}
+
+	testCases := []struct {
+		name     string
+		altitude AltitudeReference
+		alt      string
+		sep      string
+	}{
+		{"MSL", MSL, "1000.00", "-102.00"},
+		{"Ellipsoid", Ellipsoid, "898.00", "0.00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := output(&GGA{Altitude: tc.altitude}, pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			fields := strings.Split(result, ",")
+			if fields[9] != tc.alt || fields[11] != tc.sep {
+				t.Errorf("Expected altitude %s and separation %s, but got: %s", tc.alt, tc.sep, result)
+			}
+		})
+	}
+}
+
+func TestHeading(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+	// Ottawa has a westerly variation of about 12 degrees
+	pos := xplane.Position{Dat_lat: 45.42, Dat_lon: -75.70, Veh_psi_loc: -90}
+
+	testCases := []struct {
+		name      string
+		outputter any
+		field     int
+		min, max  float64
+	}{
+		{"HDT", &HDT{}, 1, 270, 270},
+		{"THS", &THS{}, 1, 270, 270},
+		{"HDM", &HDM{}, 1, 281, 284},
+		{"HDG", &HDG{}, 1, 281, 284},
+		{"HDG Deviation", &HDG{Deviation: 2}, 1, 279, 282},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := output(tc.outputter, pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			v, err := strconv.ParseFloat(strings.Split(result, ",")[tc.field], 64)
+			if err != nil {
+				t.Fatalf("Invalid heading in %q: %v", result, err)
+			}
+			if v < tc.min || v > tc.max {
+				t.Errorf("Expected heading between %.0f and %.0f, but got: %s", tc.min, tc.max, result)
+			}
+		})
+	}
+}
+
+func TestAttitude(t *testing.T) {
+	pos := xplane.Position{
+		Time:        time.Date(2024, 3, 1, 12, 34, 56, 789000000, time.UTC),
+		Veh_the_loc: 5.5,
+		Veh_psi_loc: 270,
+		Veh_phi_loc: -20.25,
+		Prad:        0.1,
+		Qrad:        -0.05,
+		Rrad:        float32(3 * math.Pi / 180),
+	}
+
+	testCases := []struct {
+		name      string
+		outputter any
+		expected  string
+	}{
+		{"ROT", &ROT{Talker: nmea.GP}, "$GPROT,180.0,A*"},
+		{"XDRAttitude", &XDRAttitude{Talker: nmea.II}, "$IIXDR,A,5.5,D,PTCH,A,-20.2,D,ROLL*"},
+		{"XDRRates", &XDRRates{Talker: nmea.II}, "$IIXDR,G,5.7,,RRTE,G,-2.9,,PRTE,G,3.0,,YRTE*"},
+		{"PASHR", &PASHR{}, "$PASHR,123456.789,270.00,T,-20.25,+05.50,"},
+		{"PSATHPR", &PSATHPR{}, "$PSAT,HPR,123456.78,270.00,5.50,-20.25,N*"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := output(tc.outputter, pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !strings.Contains(result, tc.expected) {
+				t.Errorf("Expected to contain: %s, but got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFix(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+
+	testCases := []struct {
+		name    string
+		quality gnss.Quality
+		gga     string // fix quality
+		rmc     string // status and mode
+		vtg     string
+		gsa     string
+	}{
+		{"Sim Truth", gnss.Quality{}, "8", "A,D", "D", "3"},
+		{"3D", gnss.QualityFor(3, 5, 0.1), "8", "A,D", "D", "3"},
+		{"2D", gnss.Quality{Fix: gnss.FIX_2D}, "8", "A,D", "D", "2"},
+		{"Dead Reckoning", gnss.Quality{Fix: gnss.FIX_DR}, "6", "A,E", "E", "1"},
+		{"No Fix", gnss.Quality{Fix: gnss.FIX_NONE}, "0", "V,N", "N", "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pos := xplane.Position{Dat_lat: 45, Dat_lon: -75, Quality: tc.quality}
+			fields := func(o any) []string {
+				s, err := output(o, pos)
+				if err != nil {
+					t.Fatalf("Expected no error, but got: %v", err)
+				}
+				return strings.Split(strings.Split(s, "*")[0], ",")
+			}
+
+			gga := fields(&GGA{})
+			if gga[6] != tc.gga {
+				t.Errorf("Expected GGA quality %s, but got: %v", tc.gga, gga)
+			}
+			if rmc := fields(&RMC{}); rmc[2]+","+rmc[len(rmc)-1] != tc.rmc {
+				t.Errorf("Expected RMC status and mode %s, but got: %v", tc.rmc, rmc)
+			}
+			if vtg := fields(&VTG{}); vtg[len(vtg)-1] != tc.vtg {
+				t.Errorf("Expected VTG mode %s, but got: %v", tc.vtg, vtg)
+			}
+			gsa := fields(&GSA{})
+			if gsa[2] != tc.gsa {
+				t.Errorf("Expected GSA fix type %s, but got: %v", tc.gsa, gsa)
+			}
+
+			// GGA, GSA and GSV must agree on the satellites
+			used := 0
+			for _, prn := range gsa[3:15] {
+				if prn != "" {
+					used++
+				}
+			}
+			if gga[7] != strconv.Itoa(used) {
+				t.Errorf("Expected GGA satellites %d, like GSA, but got: %s", used, gga[7])
+			}
+			if inView, _ := strconv.Atoi(fields(&GSV{})[3]); inView < used {
+				t.Errorf("Expected at least %d satellites in view, but got: %d", used, inView)
+			}
+		})
+	}
+}
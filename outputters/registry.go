@@ -0,0 +1,150 @@
+package outputters
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+)
+
+// Options are the settings an outputter is made with, from its config
+type Options struct {
+	// Talker is the talker ID of NMEA sentences. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+	// Altitude is the altitude reference of GGA sentences
+	Altitude AltitudeReference
+	// Deviation is the compass deviation of HDG sentences in degrees, positive east
+	Deviation float64
+	// Mode is the mode indicator of THS sentences
+	Mode string
+}
+
+// Registration is an outputter that can be selected by name, eg in the config
+type Registration struct {
+	// Name identifies the outputter in the config and in commands from devices, eg "GGA" or "UBX_NAV_PVT"
+	Name string
+	// Description is a short description of the output for the user
+	Description string
+	// Rate is how often the output is sent by default, in navigation solutions. 0 turns it off by default and 1
+	// sends it with every solution
+	Rate uint
+	// New returns a new instance of the outputter
+	New func(Options) Outputter
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Registration
+)
+
+// Register will add an outputter to the registry, so it can be selected by name
+// Outputters are sent in the order they are registered. It panics if the name is already registered.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, o := range registry {
+		if o.Name == r.Name {
+			panic(fmt.Sprintf("outputter %s registered twice", r.Name))
+		}
+	}
+	registry = append(registry, r)
+}
+
+// Registered returns the registered outputters, in the order they were registered
+func Registered() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Registration(nil), registry...)
+}
+
+// Lookup returns the registered outputter with the name
+func Lookup(name string) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, o := range registry {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return Registration{}, false
+}
+
+// Every returns an Outputter that only outputs every n epochs, starting with the first
+func Every(n uint, o Outputter) Outputter {
+	if n <= 1 {
+		return o
+	}
+	return every{n, o}
+}
+
+// every is an Outputter that only outputs every n epochs
+type every struct {
+	n uint
+	Outputter
+}
+
+// Outputs will add the output of the outputter to f, if it is due with the epoch
+func (ev every) Outputs(e *Epoch, f *Frames) error {
+	if e.Count%uint64(ev.n) != 0 {
+		return nil
+	}
+	return ev.Outputter.Outputs(e, f)
+}
+
+// the built in outputters, in the order they are sent
+func init() {
+	Register(Registration{"GGA", "Time, position, altitude and fix quality", 1, func(o Options) Outputter {
+		return &GGA{Talker: o.Talker, Altitude: o.Altitude}
+	}})
+	Register(Registration{"VTG", "Track and speed over the ground", 1, func(o Options) Outputter {
+		return &VTG{Talker: o.Talker}
+	}})
+	Register(Registration{"RMC", "Time, date, position, track, speed and magnetic variation", 1,
+		func(o Options) Outputter { return Adapt(&RMC{Talker: o.Talker}) }})
+	Register(Registration{"GSV", "Satellites in view, once a second", 0, func(o Options) Outputter {
+		return &GSV{Talker: o.Talker}
+	}})
+	Register(Registration{"GSA", "Fix type, satellites used and DOPs", 0, func(o Options) Outputter {
+		return &GSA{Talker: o.Talker}
+	}})
+	Register(Registration{"HDT", "True heading", 0, func(o Options) Outputter {
+		return Adapt(&HDT{Talker: o.Talker})
+	}})
+	Register(Registration{"HDM", "Magnetic heading", 0, func(o Options) Outputter {
+		return Adapt(&HDM{Talker: o.Talker})
+	}})
+	Register(Registration{"HDG", "Magnetic heading, deviation and variation", 0, func(o Options) Outputter {
+		return Adapt(&HDG{Talker: o.Talker, Deviation: o.Deviation})
+	}})
+	Register(Registration{"THS", "True heading and mode", 0, func(o Options) Outputter {
+		return Adapt(&THS{Talker: o.Talker, Mode: o.Mode})
+	}})
+	Register(Registration{"ROT", "Rate of turn", 0, func(o Options) Outputter {
+		return Adapt(&ROT{Talker: o.Talker})
+	}})
+	Register(Registration{"XDR_ATTITUDE", "Pitch and roll transducers", 0, func(o Options) Outputter {
+		return Adapt(&XDRAttitude{Talker: o.Talker})
+	}})
+	Register(Registration{"XDR_RATES", "Roll, pitch and yaw rate transducers", 0, func(o Options) Outputter {
+		return Adapt(&XDRRates{Talker: o.Talker})
+	}})
+	Register(Registration{"PASHR", "Proprietary heading, pitch, roll and heave", 0,
+		func(o Options) Outputter { return Adapt(&PASHR{}) }})
+	Register(Registration{"PSAT_HPR", "Hemisphere proprietary heading, pitch and roll", 0,
+		func(o Options) Outputter { return Adapt(&PSATHPR{}) }})
+	Register(Registration{"UBX_NAV_PVT", "u-blox position, velocity and time", 0, func(o Options) Outputter {
+		return Adapt(&UBXNavPVT{})
+	}})
+	Register(Registration{"UBX_NAV_POSLLH", "u-blox position", 0, func(o Options) Outputter {
+		return Adapt(&UBXNavPOSLLH{})
+	}})
+	Register(Registration{"UBX_NAV_VELNED", "u-blox velocity", 0, func(o Options) Outputter {
+		return Adapt(&UBXNavVELNED{})
+	}})
+	Register(Registration{"UBX_NAV_SAT", "u-blox satellites", 0, func(o Options) Outputter {
+		return Adapt(&UBXNavSAT{})
+	}})
+	Register(Registration{"UBX_NAV_TIMEUTC", "u-blox UTC time", 0, func(o Options) Outputter {
+		return Adapt(&UBXNavTIMEUTC{})
+	}})
+}
@@ -0,0 +1,71 @@
+package outputters
+
+import (
+	"testing"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestRegistered(t *testing.T) {
+	regs := Registered()
+	expected := []string{"GGA", "VTG", "RMC"}
+	for i, name := range expected {
+		if regs[i].Name != name || regs[i].Rate != 1 {
+			t.Errorf("Expected: %s at rate 1, but got: %s at rate %d", name, regs[i].Name, regs[i].Rate)
+		}
+	}
+	for _, r := range regs[len(expected):] {
+		if r.Rate != 0 {
+			t.Errorf("Expected %s to be off by default, but got rate: %d", r.Name, r.Rate)
+		}
+	}
+	for _, r := range regs {
+		if r.Description == "" {
+			t.Errorf("Expected a description for %s", r.Name)
+		}
+		if r.New(Options{}) == nil {
+			t.Errorf("Expected a new outputter for %s", r.Name)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r, ok := Lookup("GGA")
+	if !ok {
+		t.Fatalf("Expected GGA to be registered")
+	}
+	result, err := output(r.New(Options{Talker: nmea.II}), xplane.Position{})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if result[:6] != "$IIGGA" {
+		t.Errorf("Expected: %s, but got: %s", "$IIGGA", result)
+	}
+	if _, ok := Lookup("XYZ"); ok {
+		t.Errorf("Expected XYZ not to be registered")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering GGA twice to panic")
+		}
+	}()
+	Register(Registration{Name: "GGA"})
+}
+
+func TestEvery(t *testing.T) {
+	var es Epochs
+	var f Frames
+	o := Every(3, Adapt(&HDT{}))
+	for i := 0; i < 7; i++ {
+		if err := o.Outputs(es.Next(xplane.Position{}), &f); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	if f.Len() != 3 {
+		t.Errorf("Expected: %d frames, but got: %d", 3, f.Len())
+	}
+}
@@ -0,0 +1,141 @@
+package outputters
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// templateFuncs are the functions templates can use, as well as the text/template builtins like printf
+var templateFuncs = template.FuncMap{
+	// degrees converts radians, eg the rates of the position, to degrees
+	"degrees": func(r float64) float64 { return r * 180 / math.Pi },
+}
+
+// reservedChars are the characters NMEA 0183 reserves, which a template can't put in the body of a sentence
+const reservedChars = "$*!\\^~"
+
+// Template is an Outputter of a user defined sentence, made by a Go text/template from each epoch
+// The template makes the body of the sentence, between the $ and the checksum (eg "PXYZ,{{.Lat}}"), which is
+// framed with the $, checksum and CR LF. Each line it makes is a separate sentence.
+type Template struct {
+	// Talker is the talker ID templates get from .Talker. If empty, nmea.DefaultTalker is used
+	Talker nmea.TalkerID
+
+	tmpl *template.Template
+	buf  bytes.Buffer
+}
+
+// NewTemplate returns a Template of the named sentence, or an error if the template is invalid
+func NewTemplate(name string, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template: %v", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Outputs will add the sentences the template makes for the epoch to f
+func (t *Template) Outputs(e *Epoch, f *Frames) error {
+	t.buf.Reset()
+	if err := t.tmpl.Execute(&t.buf, &TemplateData{Position: &e.Position, epoch: e, talker: t.Talker}); err != nil {
+		return fmt.Errorf("could not execute template %s: %v", t.tmpl.Name(), err)
+	}
+	lines := strings.Split(t.buf.String(), "\n")
+	// every sentence is checked before any is added, so an invalid one doesn't leave the others in the frames
+	for _, line := range lines {
+		if err := checkBody(sentenceBody(line)); err != nil {
+			return fmt.Errorf("template %s made an invalid sentence: %v", t.tmpl.Name(), err)
+		}
+	}
+	for _, line := range lines {
+		if body := sentenceBody(line); body != "" {
+			f.Add(nmea.AppendSentence(f.Next(), body))
+		}
+	}
+	return nil
+}
+
+// sentenceBody returns the body of the sentence on a line a template made, or "" if there isn't one
+func sentenceBody(line string) string {
+	return strings.TrimPrefix(strings.TrimSpace(line), "$")
+}
+
+// checkBody returns an error if the body of a sentence can't be sent in a NMEA 0183 sentence: if the framed
+// sentence is too long, or the body has characters that aren't printable ASCII or are reserved
+func checkBody(body string) error {
+	// the $, checksum and CR LF frame the body
+	if n := len(body) + 6; n > nmea.MAX_SENTENCE_LENGTH {
+		return fmt.Errorf("%q is %d characters long, max is %d", body, n, nmea.MAX_SENTENCE_LENGTH)
+	}
+	for _, c := range body {
+		if c < 0x20 || c > 0x7E || strings.ContainsRune(reservedChars, c) {
+			return fmt.Errorf("%q has the invalid character %q", body, c)
+		}
+	}
+	return nil
+}
+
+// TemplateData is what a template can use: all the fields of the position, and the values derived from it
+// The derived values are methods, so they are only worked out if the template uses them.
+type TemplateData struct {
+	*xplane.Position
+
+	epoch  *Epoch
+	talker nmea.TalkerID
+}
+
+// Talker returns the talker ID of the sentence
+func (d *TemplateData) Talker() string {
+	if d.talker == "" {
+		return string(nmea.DefaultTalker())
+	}
+	return string(d.talker)
+}
+
+// SOG returns the speed over ground in knots
+func (d *TemplateData) SOG() float64 {
+	return d.epoch.SOG * 1.943845249221964
+}
+
+// Track returns the true track over the ground in degrees
+func (d *TemplateData) Track() float64 {
+	return d.epoch.Track
+}
+
+// Variation returns the magnetic variation in degrees, positive east
+func (d *TemplateData) Variation() float64 {
+	return d.epoch.Variation()
+}
+
+// Lat returns the latitude as the two fields of a NMEA sentence, eg "4525.2000,N"
+func (d *TemplateData) Lat() string {
+	return nmea.FormatLat(d.Dat_lat)
+}
+
+// Lon returns the longitude as the two fields of a NMEA sentence, eg "07542.0000,W"
+func (d *TemplateData) Lon() string {
+	return nmea.FormatLon(d.Dat_lon)
+}
+
+// Time returns the time of the fix in UTC as a NMEA time field, eg "123519.000"
+// The time itself is .FixTime, eg for {{.FixTime.Format "15:04"}}.
+func (d *TemplateData) Time() string {
+	return d.epoch.Time.Format("150405.000")
+}
+
+// Date returns the date of the fix in UTC as a NMEA date field, eg "230394"
+func (d *TemplateData) Date() string {
+	return d.epoch.Time.Format("020106")
+}
+
+// Quality returns the quality of the fix, with nominal values for the fields that aren't known
+func (d *TemplateData) Quality() gnss.Quality {
+	return d.epoch.Quality
+}
@@ -0,0 +1,89 @@
+package outputters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestTemplate(t *testing.T) {
+	nmea.SetFormats(nmea.DEFAULTS)
+	pos := xplane.Position{
+		Dat_lat: 45.42,
+		Dat_lon: -75.70,
+		Dat_ele: 1234.5,
+		Vx_wrl:  10,
+		Time:    time.Date(2024, 3, 23, 12, 35, 19, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		talker   nmea.TalkerID
+		expected string
+	}{
+		{"Position", "PXYZ,{{.Lat}},{{.Lon}}", "", "$PXYZ,4525.2000,N,07542.0000,W*22\r\n"},
+		{"Time", "PXYZ,{{.Time}},{{.Date}}", "", "$PXYZ,123519.000,230324*1C\r\n"},
+		{"Fields", `PXYZ,{{printf "%.1f" .Dat_ele}},{{.FixTime.Format "15:04"}}`, "", "$PXYZ,1234.5,12:35*2B\r\n"},
+		{"Derived", `PXYZ,{{printf "%.2f" .SOG}},{{printf "%.0f" .Track}},{{.Quality.Fix}}`, "", "$PXYZ,19.44,90,3D*7F\r\n"},
+		{"Talker", "{{.Talker}}TXT,HELLO", nmea.II, "$IITXT,HELLO*36\r\n"},
+		{"Default Talker", "{{.Talker}}TXT,HELLO", "", "$GPTXT,HELLO*21\r\n"},
+		{"Framed", "$PXYZ,1\r\n", "", "$PXYZ,1*16\r\n"},
+		{"Lines", "PXYZ,1\n\nPXYZ,2\n", "", "$PXYZ,1*16\r\n$PXYZ,2*15\r\n"},
+		{"Empty", "{{if false}}PXYZ{{end}}", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tc.name, tc.template)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			tmpl.Talker = tc.talker
+			result, err := output(tmpl, pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected: %q, but got: %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	if _, err := NewTemplate("Parse", "PXYZ,{{.Lat"); err == nil {
+		t.Errorf("Expected an error parsing an invalid template")
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+	}{
+		{"Unknown Field", "PXYZ,{{.Nope}}"},
+		{"Checksum", "PXYZ,1*00"},
+		{"Two Sentences", "PXYZ,1$PXYZ,2"},
+		{"Too Long", "PXYZ,{{printf \"%080d\" 0}}"},
+		{"Control Character", "PXYZ,{{printf \"%c\" 7}}"},
+		{"Reserved Character", "PXYZ,1~2"},
+		{"Invalid Last Line", "PXYZ,1\nPXYZ,2*00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tc.name, tc.template)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			var f Frames
+			if err := tmpl.Outputs(NewEpoch(xplane.Position{}, nil, 0), &f); err == nil {
+				t.Errorf("Expected an error")
+			}
+			if f.Len() != 0 {
+				t.Errorf("Expected no sentences, but got: %d", f.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package outputters
+
+import (
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/ubx"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// UBXNavPVT is an Outputter that returns a UBX NAV-PVT message
+type UBXNavPVT struct{}
+
+// Output returns a UBX NAV-PVT message
+func (u *UBXNavPVT) Output(p xplane.Position) (string, error) {
+	return string(ubx.NavPVT(solution(p, p.FixTime()))), nil
+}
+
+// UBXNavPOSLLH is an Outputter that returns a UBX NAV-POSLLH message
+type UBXNavPOSLLH struct{}
+
+// Output returns a UBX NAV-POSLLH message
+func (u *UBXNavPOSLLH) Output(p xplane.Position) (string, error) {
+	return string(ubx.NavPOSLLH(solution(p, p.FixTime()))), nil
+}
+
+// UBXNavVELNED is an Outputter that returns a UBX NAV-VELNED message
+type UBXNavVELNED struct{}
+
+// Output returns a UBX NAV-VELNED message
+func (u *UBXNavVELNED) Output(p xplane.Position) (string, error) {
+	return string(ubx.NavVELNED(solution(p, p.FixTime()))), nil
+}
+
+// UBXNavTIMEUTC is an Outputter that returns a UBX NAV-TIMEUTC message
+type UBXNavTIMEUTC struct{}
+
+// Output returns a UBX NAV-TIMEUTC message
+func (u *UBXNavTIMEUTC) Output(p xplane.Position) (string, error) {
+	return string(ubx.NavTIMEUTC(solution(p, p.FixTime()))), nil
+}
+
+// UBXNavSAT is an Outputter that returns a UBX NAV-SAT message
+type UBXNavSAT struct{}
+
+// Output returns a UBX NAV-SAT message with the satellites visible from the position
+func (u *UBXNavSAT) Output(p xplane.Position) (string, error) {
+	t := p.FixTime()
+	return string(ubx.NavSAT(t, p.Sky(t))), nil
+}
+
+// solution returns the UBX navigation solution for a position at t
+func solution(p xplane.Position, t time.Time) ubx.Solution {
+	height := p.Dat_ele + geoid.Separation(p.Dat_lat, p.Dat_lon)
+	q := p.Quality.OrNominal()
+	return ubx.Solution{
+		Time:   t,
+		Lat:    p.Dat_lat,
+		Lon:    p.Dat_lon,
+		Height: height,
+		HMSL:   p.Dat_ele,
+		// X-Plane's velocities are east, up and south
+		VelN:    -float64(p.Vz_wrl),
+		VelE:    float64(p.Vx_wrl),
+		VelD:    -float64(p.Vy_wrl),
+		HeadMot: p.Track(),
+		HeadVeh: float64(p.Veh_psi_loc),
+		MagDec:  variation(p),
+		FixType: ubxFix(q.Fix),
+		NumSV:   uint8(gnss.Used(p.Sky(t))),
+		HAcc:    q.HAcc,
+		VAcc:    q.VAcc,
+		SAcc:    q.SAcc,
+		HeadAcc: gnss.NOMINAL_HEADACC,
+		TAcc:    gnss.NOMINAL_TACC,
+		PDOP:    q.PDOP,
+	}
+}
+
+// ubxFix returns the UBX fix type for a fix type
+func ubxFix(f gnss.Fix) uint8 {
+	switch f {
+	case gnss.FIX_2D:
+		return ubx.FIX_2D
+	case gnss.FIX_DR:
+		return ubx.FIX_DR
+	case gnss.FIX_NONE:
+		return ubx.FIX_NONE
+	}
+	return ubx.FIX_3D
+}
@@ -0,0 +1,59 @@
+package outputters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/ubx"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestUBX(t *testing.T) {
+	pos := xplane.Position{Dat_lat: 45.42, Dat_lon: -75.70, Dat_ele: 100, Vx_wrl: 10, Vy_wrl: 1, Vz_wrl: -10}
+
+	testCases := []struct {
+		name      string
+		outputter Simple
+		id        byte
+	}{
+		{"NAV-PVT", &UBXNavPVT{}, ubx.NAV_PVT},
+		{"NAV-POSLLH", &UBXNavPOSLLH{}, ubx.NAV_POSLLH},
+		{"NAV-VELNED", &UBXNavVELNED{}, ubx.NAV_VELNED},
+		{"NAV-TIMEUTC", &UBXNavTIMEUTC{}, ubx.NAV_TIMEUTC},
+		{"NAV-SAT", &UBXNavSAT{}, ubx.NAV_SAT},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.outputter.Output(pos)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if len(result) < 8 || result[0] != ubx.SYNC_1 || result[1] != ubx.SYNC_2 {
+				t.Fatalf("Expected a UBX message, but got: % X", result)
+			}
+			if result[2] != ubx.CLASS_NAV || result[3] != tc.id {
+				t.Errorf("Expected NAV 0x%02X, but got: % X", tc.id, result[2:4])
+			}
+		})
+	}
+}
+
+func TestSolution(t *testing.T) {
+	pos := xplane.Position{Dat_lat: 45.42, Dat_lon: -75.70, Dat_ele: 100, Vx_wrl: 10, Vy_wrl: 1, Vz_wrl: -10}
+	s := solution(pos, time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC))
+
+	if s.VelN != 10 || s.VelE != 10 || s.VelD != -1 {
+		t.Errorf("Expected NED velocity 10, 10, -1, but got: %f, %f, %f", s.VelN, s.VelE, s.VelD)
+	}
+	if s.HeadMot < 44.99 || s.HeadMot > 45.01 {
+		t.Errorf("Expected heading of motion 45, but got: %f", s.HeadMot)
+	}
+	// the geoid is about 34m below the ellipsoid in Ottawa
+	if sep := s.Height - s.HMSL; sep > -30 || sep < -40 {
+		t.Errorf("Expected a geoid separation of about -34m, but got: %f", sep)
+	}
+	if s.NumSV < 4 || s.NumSV > 12 {
+		t.Errorf("Expected 4 to 12 satellites, but got: %d", s.NumSV)
+	}
+}
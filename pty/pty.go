@@ -0,0 +1,161 @@
+// Package pty provides a virtual serial port, so local applications can open the connector like a GPS device
+// without a null-modem cable
+package pty
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+const (
+	// DEFAULT_LINK is the path of the symlink to the virtual serial port
+	DEFAULT_LINK = "/tmp/xplane-gps"
+	// DEFAULT_BAUD is the baud rate the virtual serial port starts at, until the reader changes it
+	DEFAULT_BAUD = 9600
+	// WRITE_TIMEOUT is how long a write can block when nothing is reading, before the output is dropped
+	WRITE_TIMEOUT = 100 * time.Millisecond
+)
+
+// ErrUnsupported is returned on platforms without pseudo-terminals
+var ErrUnsupported = errors.New("virtual serial ports are not supported on this platform")
+
+// Logger is the default logger for the pty package
+var Logger = slog.Default()
+
+// port is the master side of a pseudo-terminal
+type port interface {
+	// Write writes to the reader of the slave side
+	Write([]byte) (int, error)
+	// Baud returns the baud rate the reader has set on the slave side
+	Baud() (int, error)
+	// Name returns the path of the slave side, eg /dev/pts/3
+	Name() string
+	Close() error
+}
+
+// Sender is an object that will send the output of its outputters to a virtual serial port
+// Like a real serial line, only as many bytes as the baud rate set by the reader allows are sent each
+// second, and the rest of the outputs are dropped
+type Sender struct {
+	// Link is the path of a symlink to the virtual serial port, which applications can open. If empty, no link
+	// is made
+	Link       string
+	Outputters []outputters.Outputter
+
+	// open opens the pseudo-terminal, and is replaced in tests
+	open func() (port, error)
+}
+
+// NewSender returns a new Sender that publishes the virtual serial port at link, eg "/tmp/xplane-gps"
+func NewSender(link string, outputters []outputters.Outputter) *Sender {
+	return &Sender{Link: link, Outputters: outputters, open: openPTY}
+}
+
+// SendPositions will create the virtual serial port and send the positions from the channel to it
+func (s *Sender) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
+	Logger.Debug("SendPositions Started", "link", s.Link)
+
+	p, err := s.open()
+	if err != nil {
+		Logger.Error("Failed to open PTY", "err", err)
+		feedback <- "Failed to open virtual serial port"
+		return err
+	}
+	defer func() {
+		p.Close()
+		Logger.Debug("PTY closed")
+	}()
+
+	if s.Link != "" {
+		if err := link(p.Name(), s.Link); err != nil {
+			Logger.Error("Failed to link PTY", "link", s.Link, "err", err)
+			feedback <- "Failed to link virtual serial port"
+			return err
+		}
+		defer unlink(p.Name(), s.Link)
+	}
+	Logger.Info("Virtual serial port open", "port", p.Name(), "link", s.Link)
+
+	var (
+		baud   int
+		last   time.Time
+		epochs outputters.Epochs
+		frames outputters.Frames
+	)
+	for pos := range c {
+		if b, err := p.Baud(); err != nil {
+			Logger.Warn("Could not get baud rate", "err", err)
+		} else if b != baud {
+			Logger.Info("Baud rate changed", "baud", b)
+			feedback <- fmt.Sprintf("Virtual serial port baud %d", b)
+			baud = b
+		}
+
+		now := time.Now()
+		left := budget(baud, now.Sub(last))
+		last = now
+
+		// the frames are reused for every epoch, so sending doesn't allocate at high rates
+		e := epochs.Next(pos)
+		frames.Reset()
+		for _, o := range s.Outputters {
+			if err := o.Outputs(e, &frames); err != nil {
+				Logger.Warn("Output failed", "err", err)
+				feedback <- "Output failed"
+			}
+		}
+		for i := 0; i < frames.Len(); i++ {
+			buf := frames.Frame(i)
+			if len(buf) > left {
+				Logger.Debug("Baud rate too low, output dropped", "baud", baud, "len", len(buf))
+				continue
+			}
+			left -= len(buf)
+			if _, err := p.Write(buf); errors.Is(err, os.ErrDeadlineExceeded) {
+				Logger.Debug("Nothing reading, output dropped")
+			} else if err != nil {
+				Logger.Warn("Write failed", "err", err)
+				feedback <- "Virtual serial port write failed"
+			}
+		}
+	}
+
+	return nil
+}
+
+// budget returns how many bytes can be sent at baud in the time since the last epoch
+// With 8N1 framing each byte takes 10 bits. At most a second's worth is allowed, so a pause doesn't let a
+// burst through.
+func budget(baud int, since time.Duration) int {
+	if since > time.Second || since <= 0 {
+		since = time.Second
+	}
+	return int(int64(baud) * int64(since) / int64(10*time.Second))
+}
+
+// link will make a symlink at path to name, replacing any old symlink
+// A file at path that isn't a symlink is not replaced
+func link(name, path string) error {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s exists and is not a symlink", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(name, path)
+}
+
+// unlink will remove the symlink at path if it still points to name
+func unlink(name, path string) {
+	if target, err := os.Readlink(path); err == nil && target == name {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package pty
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// bauds maps the termios speed codes to baud rates
+var bauds = map[uint32]int{
+	unix.B1200:    1200,
+	unix.B2400:    2400,
+	unix.B4800:    4800,
+	unix.B9600:    9600,
+	unix.B19200:   19200,
+	unix.B38400:   38400,
+	unix.B57600:   57600,
+	unix.B115200:  115200,
+	unix.B230400:  230400,
+	unix.B460800:  460800,
+	unix.B921600:  921600,
+	unix.B1000000: 1000000,
+	unix.B2000000: 2000000,
+	unix.B4000000: 4000000,
+}
+
+// pty is a Linux pseudo-terminal
+// The slave side is kept open, so the terminal survives readers coming and going and its settings can be
+// read
+type pty struct {
+	master *os.File
+	slave  *os.File
+}
+
+// openPTY opens a new pseudo-terminal in raw mode at DEFAULT_BAUD
+func openPTY() (port, error) {
+	// a non-blocking master is added to the runtime poller, so writes can time out when nothing is reading
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open /dev/ptmx: %v", err)
+	}
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("could not unlock pty: %v", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("could not get pty number: %v", err)
+	}
+	name := fmt.Sprintf("/dev/pts/%d", n)
+	slave, err := os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("could not open %s: %v", name, err)
+	}
+
+	p := &pty{master: master, slave: slave}
+	if err := p.makeRaw(); err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	// anything the reader sends is discarded, so it never blocks on a full buffer
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			if _, err := master.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return p, nil
+}
+
+// makeRaw will put the slave side in raw mode at DEFAULT_BAUD, so sentences pass through unchanged and
+// aren't echoed back
+func (p *pty) makeRaw() error {
+	fd := int(p.slave.Fd())
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("could not get termios: %v", err)
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.B9600
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return fmt.Errorf("could not set termios: %v", err)
+	}
+	return nil
+}
+
+// Write writes to the reader of the slave side
+// If nothing reads and the terminal's buffer is full, the write times out and the stale data is flushed
+func (p *pty) Write(bs []byte) (int, error) {
+	p.master.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+	n, err := p.master.Write(bs)
+	if err != nil {
+		unix.IoctlSetInt(int(p.slave.Fd()), unix.TCFLSH, unix.TCIFLUSH)
+	}
+	return n, err
+}
+
+// Baud returns the baud rate the reader has set on the slave side
+func (p *pty) Baud() (int, error) {
+	t, err := unix.IoctlGetTermios(int(p.slave.Fd()), unix.TCGETS)
+	if err != nil {
+		return 0, fmt.Errorf("could not get termios: %v", err)
+	}
+	baud, ok := bauds[t.Cflag&unix.CBAUD]
+	if !ok {
+		return 0, fmt.Errorf("unknown baud rate code 0x%X", t.Cflag&unix.CBAUD)
+	}
+	return baud, nil
+}
+
+// Name returns the path of the slave side, eg /dev/pts/3
+func (p *pty) Name() string {
+	return p.slave.Name()
+}
+
+// Close will close both sides of the terminal
+func (p *pty) Close() error {
+	p.slave.Close()
+	return p.master.Close()
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package pty
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestPTY(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xplane-gps")
+	s := NewSender(path, []outputters.Outputter{outputters.Adapt(fixed("$GPTXT,HELLO*00\r\n"))})
+	p, err := openPTY()
+	if err != nil {
+		t.Skipf("No pseudo-terminals: %v", err)
+	}
+	p.Close()
+
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+	defer func() {
+		close(c)
+		if err := <-done; err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+	}()
+
+	// the first position makes sure the port is open and linked
+	c <- xplane.Position{}
+	if msg := <-feedback; msg != "Virtual serial port baud 9600" {
+		t.Fatalf("Expected baud feedback, but got: %s", msg)
+	}
+
+	// non-blocking, so the read deadline works
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatalf("Could not open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	// change the baud rate like a reader would
+	tio, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	if err != nil {
+		t.Fatalf("Could not get termios: %v", err)
+	}
+	tio.Cflag = tio.Cflag&^unix.CBAUD | unix.B4800
+	if err := unix.IoctlSetTermios(int(f.Fd()), unix.TCSETS, tio); err != nil {
+		t.Fatalf("Could not set termios: %v", err)
+	}
+	unix.IoctlSetInt(int(f.Fd()), unix.TCFLSH, unix.TCIFLUSH)
+
+	// leave time for the sentence at 4800 baud
+	time.Sleep(100 * time.Millisecond)
+	c <- xplane.Position{}
+	if msg := <-feedback; msg != "Virtual serial port baud 4800" {
+		t.Errorf("Expected baud feedback, but got: %s", msg)
+	}
+
+	f.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Expected a sentence, but got: %v", err)
+	}
+	if line != "$GPTXT,HELLO*00\r\n" {
+		t.Errorf("Expected: %q, but got: %q", "$GPTXT,HELLO*00\r\n", line)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package pty
+
+// openPTY returns ErrUnsupported, as pseudo-terminals are only supported on Linux
+func openPTY() (port, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,101 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// fakePort is a port that records what is written to it
+type fakePort struct {
+	bytes.Buffer
+	baud   int
+	closed bool
+}
+
+func (f *fakePort) Baud() (int, error) { return f.baud, nil }
+func (f *fakePort) Name() string       { return "/dev/pts/99" }
+func (f *fakePort) Close() error {
+	f.closed = true
+	return nil
+}
+
+// fixed is a Simple outputter that always returns the same output
+type fixed string
+
+func (f fixed) Output(p xplane.Position) (string, error) { return string(f), nil }
+
+func TestBudget(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baud     int
+		since    time.Duration
+		expected int
+	}{
+		{"First Epoch", 9600, 0, 960},
+		{"10Hz", 9600, 100 * time.Millisecond, 96},
+		{"4800 1Hz", 4800, time.Second, 480},
+		{"Long Pause", 115200, time.Minute, 11520},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := budget(tc.baud, tc.since); got != tc.expected {
+				t.Errorf("Expected: %d, but got: %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSendPositions(t *testing.T) {
+	// 100 bytes a second at 1000 baud, so the second 60 byte output doesn't fit
+	p := &fakePort{baud: 1000}
+	path := filepath.Join(t.TempDir(), "gps")
+	s := &Sender{
+		Link:       path,
+		Outputters: []outputters.Outputter{outputters.Adapt(fixed(bytes.Repeat([]byte("A"), 60))), outputters.Adapt(fixed(bytes.Repeat([]byte("B"), 60)))},
+		open:       func() (port, error) { return p, nil },
+	}
+
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+
+	c <- xplane.Position{}
+	if target, err := os.Readlink(path); err != nil || target != "/dev/pts/99" {
+		t.Errorf("Expected link to /dev/pts/99, but got: %s, %v", target, err)
+	}
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if expected := string(bytes.Repeat([]byte("A"), 60)); p.String() != expected {
+		t.Errorf("Expected: %s, but got: %s", expected, p.String())
+	}
+	if msg := <-feedback; msg != "Virtual serial port baud 1000" {
+		t.Errorf("Expected baud feedback, but got: %s", msg)
+	}
+	if !p.closed {
+		t.Errorf("Expected the port to be closed")
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the link to be removed, but got: %v", err)
+	}
+}
+
+func TestLinkNotSymlink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gps")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Could not create file: %v", err)
+	}
+	if err := link("/dev/pts/99", path); err == nil {
+		t.Errorf("Expected an error replacing a file")
+	}
+}
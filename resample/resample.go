@@ -0,0 +1,200 @@
+// Package resample resamples the positions from X-Plane to an exact rate, like the navigation rate of a receiver
+// X-Plane sends positions as its frame rate allows, so the time between them varies and a fix can be repeated
+// or missed. The resampler sends a fix on each epoch of its rate, aligned to whole seconds, interpolated between
+// the positions around it or extrapolated from the last one with its velocity.
+package resample
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// RATES are the supported output rates in Hz, which all divide a second into whole epochs
+var RATES = []uint{1, 5, 10, 20, 50}
+
+// MAX_EXTRAPOLATION is the default for how long after the last position the fix is extrapolated
+// After that X-Plane has stopped sending positions (eg when paused), and no fixes are sent until it starts again.
+const MAX_EXTRAPOLATION = time.Second
+
+// HISTORY is how long positions are kept to interpolate between, before the epoch being sent
+const HISTORY = time.Second
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000.0
+
+// Resampler resamples positions to an exact rate
+type Resampler struct {
+	// Rate is the output rate in Hz, one of RATES
+	Rate uint
+	// Delay is how long after an epoch its fix is sent
+	// With no delay the fix is almost always extrapolated from the last position, and with a delay longer than
+	// the time between X-Plane's positions it can be interpolated, which is smoother but later.
+	Delay time.Duration
+	// MaxExtrapolation is how long after the last position the fix is extrapolated
+	MaxExtrapolation time.Duration
+
+	// buf is the positions received, in order of their time
+	buf []xplane.Position
+}
+
+// NewResampler returns a new Resampler for the rate in Hz, or an error if the rate is not supported
+func NewResampler(rate uint) (*Resampler, error) {
+	for _, r := range RATES {
+		if r == rate {
+			return &Resampler{Rate: rate, MaxExtrapolation: MAX_EXTRAPOLATION}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported rate: %d Hz, must be one of %v", rate, RATES)
+}
+
+// Period returns the time between epochs
+func (r *Resampler) Period() time.Duration {
+	return time.Second / time.Duration(r.Rate)
+}
+
+// Next returns the first epoch after t
+// The epochs are aligned to whole seconds, so at 5 Hz they are at .0, .2, .4, .6 and .8 seconds.
+func (r *Resampler) Next(t time.Time) time.Time {
+	return t.Truncate(r.Period()).Add(r.Period())
+}
+
+// Add will add a position to resample
+// Positions without a time are given the time now.
+func (r *Resampler) Add(p xplane.Position) {
+	p.Time = p.FixTime()
+	i := sort.Search(len(r.buf), func(i int) bool { return r.buf[i].Time.After(p.Time) })
+	r.buf = append(r.buf, xplane.Position{})
+	copy(r.buf[i+1:], r.buf[i:])
+	r.buf[i] = p
+}
+
+// At returns the fix at t, and false if there isn't one because there are no positions around t
+// Positions more than HISTORY before t are dropped, so the epochs must be asked for in order.
+func (r *Resampler) At(t time.Time) (xplane.Position, bool) {
+	r.trim(t.Add(-HISTORY))
+	if len(r.buf) == 0 || t.Before(r.buf[0].Time) {
+		return xplane.Position{}, false
+	}
+
+	// the first position after t
+	i := sort.Search(len(r.buf), func(i int) bool { return r.buf[i].Time.After(t) })
+	if i == len(r.buf) {
+		last := r.buf[len(r.buf)-1]
+		if max := r.MaxExtrapolation; max > 0 && t.Sub(last.Time) > max {
+			return xplane.Position{}, false
+		}
+		return extrapolate(last, t), true
+	}
+	return interpolate(r.buf[i-1], r.buf[i], t), true
+}
+
+// trim will drop the positions before t, keeping the last one
+func (r *Resampler) trim(t time.Time) {
+	i := sort.Search(len(r.buf), func(i int) bool { return !r.buf[i].Time.Before(t) })
+	if i == len(r.buf) {
+		i--
+	}
+	if i > 0 {
+		r.buf = append(r.buf[:0], r.buf[i:]...)
+	}
+}
+
+// Run will send the fix on each epoch from the positions from in to out, until in is closed, when out is closed
+func (r *Resampler) Run(in <-chan xplane.Position, out chan<- xplane.Position) {
+	defer close(out)
+
+	epoch := r.Next(time.Now().Add(-r.Delay))
+	timer := time.NewTimer(time.Until(epoch.Add(r.Delay)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case p, ok := <-in:
+			if !ok {
+				return
+			}
+			r.Add(p)
+		case <-timer.C:
+			// send every epoch that is due, so none are missed if the timer was late
+			now := time.Now().Add(-r.Delay)
+			for ; !epoch.After(now); epoch = epoch.Add(r.Period()) {
+				if p, ok := r.At(epoch); ok {
+					out <- p
+				}
+			}
+			timer.Reset(time.Until(epoch.Add(r.Delay)))
+		}
+	}
+}
+
+// interpolate returns the position at t, between a and b
+// The quality of the fix is a's, as the quality of a receiver doesn't change smoothly.
+func interpolate(a, b xplane.Position, t time.Time) xplane.Position {
+	span := b.Time.Sub(a.Time)
+	if span <= 0 {
+		a.Time = t
+		return a
+	}
+	f := float64(t.Sub(a.Time)) / float64(span)
+
+	p := a
+	p.Dat_lat = lerp(a.Dat_lat, b.Dat_lat, f)
+	p.Dat_lon = normalise180(lerpAngle(a.Dat_lon, b.Dat_lon, f))
+	p.Dat_ele = lerp(a.Dat_ele, b.Dat_ele, f)
+	p.Y_agl_mtr = lerp32(a.Y_agl_mtr, b.Y_agl_mtr, f)
+	p.Veh_the_loc = lerp32(a.Veh_the_loc, b.Veh_the_loc, f)
+	p.Veh_psi_loc = float32(normalise360(lerpAngle(float64(a.Veh_psi_loc), float64(b.Veh_psi_loc), f)))
+	p.Veh_phi_loc = float32(normalise180(lerpAngle(float64(a.Veh_phi_loc), float64(b.Veh_phi_loc), f)))
+	p.Vx_wrl = lerp32(a.Vx_wrl, b.Vx_wrl, f)
+	p.Vy_wrl = lerp32(a.Vy_wrl, b.Vy_wrl, f)
+	p.Vz_wrl = lerp32(a.Vz_wrl, b.Vz_wrl, f)
+	p.Prad = lerp32(a.Prad, b.Prad, f)
+	p.Qrad = lerp32(a.Qrad, b.Qrad, f)
+	p.Rrad = lerp32(a.Rrad, b.Rrad, f)
+	p.Time = t
+	return p
+}
+
+// extrapolate returns the position at t, dead reckoned from p with its velocity
+// The attitude is held, as X-Plane's rates are in the body frame.
+func extrapolate(p xplane.Position, t time.Time) xplane.Position {
+	dt := t.Sub(p.Time).Seconds()
+	// Vx is east, Vy is up and Vz is south
+	north := -float64(p.Vz_wrl) * dt
+	east := float64(p.Vx_wrl) * dt
+	p.Dat_lat += north / earthRadius * 180 / math.Pi
+	p.Dat_lon = normalise180(p.Dat_lon + east/(earthRadius*math.Cos(p.Dat_lat*math.Pi/180))*180/math.Pi)
+	p.Dat_ele += float64(p.Vy_wrl) * dt
+	p.Time = t
+	return p
+}
+
+// lerp returns the value a fraction f of the way from a to b
+func lerp(a, b, f float64) float64 {
+	return a + (b-a)*f
+}
+
+// lerp32 returns the value a fraction f of the way from a to b
+func lerp32(a, b float32, f float64) float32 {
+	return float32(lerp(float64(a), float64(b), f))
+}
+
+// lerpAngle returns the angle a fraction f of the way from a to b in degrees, the short way round
+func lerpAngle(a, b, f float64) float64 {
+	d := math.Mod(b-a+540, 360) - 180
+	return a + d*f
+}
+
+// normalise180 returns the angle, eg a longitude, in [-180, 180)
+func normalise180(a float64) float64 {
+	return normalise360(a+180) - 180
+}
+
+// normalise360 returns the angle, eg a heading, in [0, 360)
+func normalise360(a float64) float64 {
+	return math.Mod(math.Mod(a, 360)+360, 360)
+}
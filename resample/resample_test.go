@@ -0,0 +1,151 @@
+package resample
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var start = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// flying returns the position at t of an aircraft flying north at 50 m/s and climbing at 5 m/s
+func flying(t time.Duration) xplane.Position {
+	return xplane.Position{
+		Dat_lat:     45 + 50*t.Seconds()/earthRadius*180/math.Pi,
+		Dat_lon:     -75,
+		Dat_ele:     1000 + 5*t.Seconds(),
+		Veh_psi_loc: 0,
+		Vy_wrl:      5,
+		Vz_wrl:      -50,
+		Time:        start.Add(t),
+	}
+}
+
+func TestNewResampler(t *testing.T) {
+	for _, rate := range RATES {
+		if _, err := NewResampler(rate); err != nil {
+			t.Errorf("Expected: %d Hz to be supported, but got: %v", rate, err)
+		}
+	}
+	for _, rate := range []uint{0, 3, 60} {
+		if _, err := NewResampler(rate); err == nil {
+			t.Errorf("Expected an error for: %d Hz", rate)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	testCases := []struct {
+		rate     uint
+		t        time.Duration
+		expected time.Duration
+	}{
+		{1, 300 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{5, 390 * time.Millisecond, 400 * time.Millisecond},
+		{10, 1234 * time.Millisecond, 1300 * time.Millisecond},
+		{20, 10 * time.Millisecond, 50 * time.Millisecond},
+		{50, 999 * time.Millisecond, time.Second},
+	}
+
+	for _, tc := range testCases {
+		r, _ := NewResampler(tc.rate)
+		got := r.Next(start.Add(tc.t))
+		if !got.Equal(start.Add(tc.expected)) {
+			t.Errorf("Expected: %s, but got: %s", start.Add(tc.expected), got)
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	testCases := []struct {
+		name string
+		// times of the positions from X-Plane
+		samples []time.Duration
+		t       time.Duration
+		ok      bool
+	}{
+		{"before", []time.Duration{100 * time.Millisecond}, 0, false},
+		{"exact", []time.Duration{0, 100 * time.Millisecond}, 100 * time.Millisecond, true},
+		{"interpolated", []time.Duration{0, 37 * time.Millisecond, 131 * time.Millisecond}, 100 * time.Millisecond, true},
+		{"extrapolated", []time.Duration{0, 37 * time.Millisecond}, 100 * time.Millisecond, true},
+		{"stale", []time.Duration{0}, 1500 * time.Millisecond, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := NewResampler(10)
+			for _, s := range tc.samples {
+				r.Add(flying(s))
+			}
+			got, ok := r.At(start.Add(tc.t))
+			if ok != tc.ok {
+				t.Fatalf("Expected: %t, but got: %t", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			expected := flying(tc.t)
+			if !got.Time.Equal(expected.Time) {
+				t.Errorf("Expected: %s, but got: %s", expected.Time, got.Time)
+			}
+			if d := math.Abs(got.Dat_lat-expected.Dat_lat) * math.Pi / 180 * earthRadius; d > 0.01 {
+				t.Errorf("Expected: %v, but got: %v (%.3f m)", expected.Dat_lat, got.Dat_lat, d)
+			}
+			if math.Abs(got.Dat_lon-expected.Dat_lon) > 1e-9 {
+				t.Errorf("Expected: %v, but got: %v", expected.Dat_lon, got.Dat_lon)
+			}
+			if math.Abs(got.Dat_ele-expected.Dat_ele) > 0.01 {
+				t.Errorf("Expected: %v, but got: %v", expected.Dat_ele, got.Dat_ele)
+			}
+		})
+	}
+}
+
+func TestInterpolateAngles(t *testing.T) {
+	a := xplane.Position{Dat_lon: 179.9, Veh_psi_loc: 350, Veh_phi_loc: 170, Time: start}
+	b := xplane.Position{Dat_lon: -179.9, Veh_psi_loc: 10, Veh_phi_loc: -170, Time: start.Add(time.Second)}
+	p := interpolate(a, b, start.Add(500*time.Millisecond))
+	if math.Abs(p.Dat_lon+180) > 1e-9 {
+		t.Errorf("Expected: %v, but got: %v", -180, p.Dat_lon)
+	}
+	if p.Veh_psi_loc != 0 {
+		t.Errorf("Expected: %v, but got: %v", 0, p.Veh_psi_loc)
+	}
+	if p.Veh_phi_loc != -180 {
+		t.Errorf("Expected: %v, but got: %v", -180, p.Veh_phi_loc)
+	}
+}
+
+func TestRun(t *testing.T) {
+	r, _ := NewResampler(50)
+	in := make(chan xplane.Position)
+	out := make(chan xplane.Position, 100)
+	go r.Run(in, out)
+
+	// uneven positions, at about 30 Hz
+	end := time.Now().Add(300 * time.Millisecond)
+	for i := 0; time.Now().Before(end); i++ {
+		in <- xplane.Position{Dat_lat: 45, Dat_lon: -75, Time: time.Now().UTC()}
+		time.Sleep(time.Duration(20+i%3*10) * time.Millisecond)
+	}
+	close(in)
+
+	var last time.Time
+	n := 0
+	for p := range out {
+		if p.Time.Truncate(r.Period()) != p.Time {
+			t.Errorf("Expected an epoch aligned to: %s, but got: %s", r.Period(), p.Time.Format(time.RFC3339Nano))
+		}
+		if !last.IsZero() && p.Time.Sub(last) != r.Period() {
+			t.Errorf("Expected epochs: %s apart, but got: %s", r.Period(), p.Time.Sub(last))
+		}
+		last = p.Time
+		n++
+	}
+	if n < 10 {
+		t.Errorf("Expected at least: %d fixes, but got: %d", 10, n)
+	}
+}
@@ -0,0 +1,158 @@
+package scenario
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Logger is the default logger for the scenario package
+var Logger = slog.Default()
+
+// Engine applies a scenario to the positions while it is enabled
+// The scenario starts with the first position after it is loaded or enabled.
+type Engine struct {
+	mu       sync.Mutex
+	scenario *Scenario
+	enabled  bool
+	start    time.Time
+	// good is the last position with a fix, which is held while the fix is lost
+	good    xplane.Position
+	hasGood bool
+}
+
+// NewEngine returns a new Engine without a scenario
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Load will replace the scenario and restart it
+func (e *Engine) Load(s *Scenario) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario = s
+	e.start = time.Time{}
+}
+
+// Scenario returns the loaded scenario, or nil if there isn't one
+func (e *Engine) Scenario() *Scenario {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.scenario
+}
+
+// SetEnabled will turn the scenario on or off. Turning it on restarts it
+func (e *Engine) SetEnabled(enabled bool) {
+	Logger.Info("Scenario", "enabled", enabled)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if enabled && !e.enabled {
+		e.start = time.Time{}
+	}
+	e.enabled = enabled
+}
+
+// Enabled returns whether the scenario is on
+func (e *Engine) Enabled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enabled
+}
+
+// Apply returns the position received at t with the jamming and spoofing of the scenario applied
+func (e *Engine) Apply(p xplane.Position, t time.Time) xplane.Position {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.enabled || e.scenario == nil {
+		return p
+	}
+	if e.start.IsZero() {
+		e.start = t
+	}
+	elapsed := t.Sub(e.start).Seconds()
+
+	// the spoofer's signals are what the receiver sees, so it is jammed where the spoofed position is
+	p = e.spoof(p, t, elapsed)
+	return e.jam(p, t, elapsed)
+}
+
+// spoof will apply the active spoofers to a position
+func (e *Engine) spoof(p xplane.Position, t time.Time, elapsed float64) xplane.Position {
+	var north, east, vn, ve, offset float64
+	for _, s := range e.scenario.Spoofing {
+		if !s.active(elapsed) || !s.covers(p.Dat_lat, p.Dat_lon) {
+			continue
+		}
+		d, moving := s.drag(elapsed)
+		b := s.Bearing * math.Pi / 180
+		north += d * math.Cos(b)
+		east += d * math.Sin(b)
+		// a careful spoofer moves the velocity with the position, so the receiver doesn't notice the jump
+		if moving {
+			vn += s.Rate * math.Cos(b)
+			ve += s.Rate * math.Sin(b)
+		}
+		offset += s.TimeOffset
+	}
+
+	p.Dat_lat += north / earthRadius * 180 / math.Pi
+	p.Dat_lon += east / (earthRadius * math.Cos(p.Dat_lat*math.Pi/180)) * 180 / math.Pi
+	// X-Plane's velocities are east, up and south
+	p.Vx_wrl += float32(ve)
+	p.Vz_wrl -= float32(vn)
+	if offset != 0 {
+		ft := p.Time
+		if ft.IsZero() {
+			ft = t
+		}
+		p.Time = ft.Add(time.Duration(offset * float64(time.Second)))
+	}
+	return p
+}
+
+// jam will apply the active jammers to a position
+// The strongest jammer in range raises the noise floor, so fewer satellites are used and the fix gets worse
+// until it is lost.
+func (e *Engine) jam(p xplane.Position, t time.Time, elapsed float64) xplane.Position {
+	jamming := 0.0
+	for _, j := range e.scenario.Jamming {
+		if j.active(elapsed) {
+			jamming = math.Max(jamming, j.Jamming(p.Dat_lat, p.Dat_lon, p.Dat_ele))
+		}
+	}
+	if jamming == 0 {
+		e.good, e.hasGood = p, true
+		return p
+	}
+
+	q := p.Quality.OrNominal()
+	q.Jamming = jamming
+	used := gnss.Used(q.Sky(gnss.Visible(p.Dat_lat, p.Dat_lon, p.Dat_ele, t)))
+	fix := q.Fix
+	switch {
+	case used < 3:
+		fix = gnss.FIX_NONE
+	case used == 3 && fix == gnss.FIX_3D:
+		fix = gnss.FIX_2D
+	}
+
+	// weaker signals are noisier
+	worse := math.Pow(10, jamming/40)
+	p.Quality = gnss.QualityFor(q.HAcc*worse, q.VAcc*worse, q.SAcc*worse)
+	p.Quality.Fix = fix
+	p.Quality.Jamming = jamming
+
+	if fix == gnss.FIX_NONE {
+		if e.hasGood {
+			p.Dat_lat, p.Dat_lon, p.Dat_ele = e.good.Dat_lat, e.good.Dat_lon, e.good.Dat_ele
+		}
+		p.Vx_wrl, p.Vy_wrl, p.Vz_wrl = 0, 0, 0
+		return p
+	}
+	e.good, e.hasGood = p, true
+	return p
+}
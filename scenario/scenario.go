@@ -0,0 +1,123 @@
+// Package scenario replays GPS jamming and spoofing attacks, defined in a scenario file, on the positions
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// MAX_JAMMING is the rise of the noise floor in dB close to a jammer, which loses every satellite
+const MAX_JAMMING = 40.0
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000.0
+
+// Scenario is a set of jamming zones and spoofing attacks
+// Times are in seconds from when the scenario starts, distances in meters and angles in degrees.
+type Scenario struct {
+	// Name describes the scenario
+	Name     string    `json:"name,omitempty"`
+	Jamming  []Jammer  `json:"jamming,omitempty"`
+	Spoofing []Spoofer `json:"spoofing,omitempty"`
+}
+
+// Window is when an attack is on. An End of 0 leaves it on
+type Window struct {
+	Start float64 `json:"start,omitempty"`
+	End   float64 `json:"end,omitempty"`
+}
+
+// active returns whether the window is on at elapsed seconds
+func (w Window) active(elapsed float64) bool {
+	return elapsed >= w.Start && (w.End == 0 || elapsed < w.End)
+}
+
+// Jammer is a jammer at a fixed place, which raises the noise floor of receivers in range
+type Jammer struct {
+	Window
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// Alt is the altitude of the jammer above mean sea level
+	Alt float64 `json:"alt,omitempty"`
+	// Radius is how close the jammer loses every satellite
+	Radius float64 `json:"radius"`
+	// Range is how far the jammer raises the noise floor at all, falling off from MAX_JAMMING at Radius. If not
+	// set, it is 10 times the radius
+	Range float64 `json:"range,omitempty"`
+}
+
+// Jamming returns the rise of the noise floor in dB at a position
+func (j Jammer) Jamming(lat, lon, alt float64) float64 {
+	d := math.Hypot(distance(j.Lat, j.Lon, lat, lon), alt-j.Alt)
+	rng := j.Range
+	if rng <= j.Radius {
+		rng = 10 * j.Radius
+	}
+	switch {
+	case d <= j.Radius:
+		return MAX_JAMMING
+	case d >= rng:
+		return 0
+	}
+	// the power falls off with the log of the distance
+	return MAX_JAMMING * (1 - math.Log(d/j.Radius)/math.Log(rng/j.Radius))
+}
+
+// Spoofer is a spoofing attack, which drags the position off and shifts the time while the receiver still
+// reports a good fix
+type Spoofer struct {
+	Window
+	// Lat, Lon and Radius limit the attack to within Radius of a place, if Radius is set
+	Lat    float64 `json:"lat,omitempty"`
+	Lon    float64 `json:"lon,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+	// Bearing and Rate drag the position towards Bearing (true) at Rate m/s, up to Max. If Max is not set, the
+	// drag doesn't stop
+	Bearing float64 `json:"bearing,omitempty"`
+	Rate    float64 `json:"rate,omitempty"`
+	Max     float64 `json:"max,omitempty"`
+	// TimeOffset shifts the time of the fixes, in seconds
+	TimeOffset float64 `json:"time_offset,omitempty"`
+}
+
+// covers returns whether the spoofer's area covers a position
+func (s Spoofer) covers(lat, lon float64) bool {
+	return s.Radius <= 0 || distance(s.Lat, s.Lon, lat, lon) <= s.Radius
+}
+
+// drag returns how far the position has been dragged off after elapsed seconds, and whether it is still moving
+func (s Spoofer) drag(elapsed float64) (float64, bool) {
+	d := s.Rate * (elapsed - s.Start)
+	if s.Max > 0 && d >= s.Max {
+		return s.Max, false
+	}
+	return d, s.Rate != 0
+}
+
+// Load will read a scenario from a JSON file at path
+func Load(path string) (*Scenario, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read scenario: %v", err)
+	}
+	s := &Scenario{}
+	if err := json.Unmarshal(bs, s); err != nil {
+		return nil, fmt.Errorf("could not parse scenario %s: %v", path, err)
+	}
+	for i, j := range s.Jamming {
+		if j.Radius <= 0 {
+			return nil, fmt.Errorf("jammer %d in scenario %s has no radius", i+1, path)
+		}
+	}
+	return s, nil
+}
+
+// distance returns the great circle distance between two positions in meters
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	p1, p2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dp, dl := p2-p1, (lon2-lon1)*math.Pi/180
+	a := math.Sin(dp/2)*math.Sin(dp/2) + math.Cos(p1)*math.Cos(p2)*math.Sin(dl/2)*math.Sin(dl/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,179 @@
+package scenario
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var start = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// north returns a position d meters north of 45, -75
+func north(d float64) xplane.Position {
+	return xplane.Position{Dat_lat: 45 + d/earthRadius*180/math.Pi, Dat_lon: -75, Dat_ele: 0}
+}
+
+func TestJamming(t *testing.T) {
+	j := Jammer{Lat: 45, Lon: -75, Radius: 1000, Range: 100000}
+	testCases := []struct {
+		name string
+		d    float64
+		want float64
+	}{
+		{"Inside", 500, MAX_JAMMING},
+		{"Edge", 1000, MAX_JAMMING},
+		{"Halfway", 10000, MAX_JAMMING / 2},
+		{"Out of Range", 100000, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := north(tc.d)
+			if got := j.Jamming(p.Dat_lat, p.Dat_lon, p.Dat_ele); math.Abs(got-tc.want) > 0.01 {
+				t.Errorf("Expected: %f, but got: %f", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJammed(t *testing.T) {
+	s := &Scenario{Jamming: []Jammer{{Window: Window{Start: 10}, Lat: 45, Lon: -75, Radius: 1000, Range: 100000}}}
+	e := NewEngine()
+	e.Load(s)
+	e.SetEnabled(true)
+
+	// before the jammer starts
+	clear := e.Apply(north(2000), start)
+	if clear.Quality != (gnss.Quality{}) {
+		t.Errorf("Expected: no change before the jammer starts, but got: %+v", clear.Quality)
+	}
+
+	// the noise floor rises, and the signals get weaker
+	weak := e.Apply(north(20000), start.Add(20*time.Second))
+	if weak.Quality.Jamming <= 0 || weak.Quality.HDOP <= gnss.NOMINAL_HDOP {
+		t.Errorf("Expected: jamming and a worse HDOP, but got: %+v", weak.Quality)
+	}
+	if weak.Quality.Fix == gnss.FIX_NONE {
+		t.Errorf("Expected: a fix in range of the jammer, but got: %s", weak.Quality.Fix)
+	}
+	visible := gnss.Visible(weak.Dat_lat, weak.Dat_lon, weak.Dat_ele, start)
+	for i, sat := range weak.Quality.Sky(visible) {
+		if sat.SNR >= visible[i].SNR {
+			t.Errorf("PRN %d: Expected a lower SNR than %f, but got: %f", sat.PRN, visible[i].SNR, sat.SNR)
+		}
+	}
+
+	// close to the jammer the fix is lost, and the last position is held
+	lost := e.Apply(north(500), start.Add(30*time.Second))
+	if lost.Quality.Fix != gnss.FIX_NONE {
+		t.Errorf("Expected: %s, but got: %s", gnss.FIX_NONE, lost.Quality.Fix)
+	}
+	if lost.Dat_lat != weak.Dat_lat {
+		t.Errorf("Expected: the last position %f, but got: %f", weak.Dat_lat, lost.Dat_lat)
+	}
+	if used := gnss.Used(lost.Quality.Sky(visible)); used != 0 {
+		t.Errorf("Expected: no satellites used, but got: %d", used)
+	}
+}
+
+func TestSpoofing(t *testing.T) {
+	s := &Scenario{Spoofing: []Spoofer{{
+		Window:     Window{Start: 10, End: 100},
+		Bearing:    90,
+		Rate:       10,
+		Max:        500,
+		TimeOffset: 3600,
+	}}}
+	e := NewEngine()
+	e.Load(s)
+	e.SetEnabled(true)
+
+	p := north(0)
+	p.Time = start
+	testCases := []struct {
+		name    string
+		elapsed time.Duration
+		east    float64
+		ve      float32
+		offset  time.Duration
+	}{
+		{"Before", 0, 0, 0, 0},
+		{"Dragging", 30 * time.Second, 200, 10, time.Hour},
+		{"Held", 90 * time.Second, 500, 0, time.Hour},
+		{"After", 100 * time.Second, 0, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := e.Apply(p, start.Add(tc.elapsed))
+			east := (q.Dat_lon - p.Dat_lon) * math.Pi / 180 * earthRadius * math.Cos(p.Dat_lat*math.Pi/180)
+			if math.Abs(east-tc.east) > 0.01 {
+				t.Errorf("Expected: %fm east, but got: %f", tc.east, east)
+			}
+			if q.Vx_wrl != tc.ve {
+				t.Errorf("Expected: %f m/s east, but got: %f", tc.ve, q.Vx_wrl)
+			}
+			if offset := q.Time.Sub(p.Time); offset != tc.offset {
+				t.Errorf("Expected: time offset %s, but got: %s", tc.offset, offset)
+			}
+			if q.Quality.Fix != gnss.FIX_3D {
+				t.Errorf("Expected: a good fix while spoofed, but got: %s", q.Quality.Fix)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	e := NewEngine()
+	e.Load(&Scenario{Spoofing: []Spoofer{{Bearing: 0, Rate: 10}}})
+
+	p := north(0)
+	if q := e.Apply(p, start.Add(10*time.Second)); q != p {
+		t.Errorf("Expected: no change while disabled, but got: %+v", q)
+	}
+
+	// enabling it starts the scenario again
+	e.SetEnabled(true)
+	e.Apply(p, start.Add(time.Minute))
+	q := e.Apply(p, start.Add(time.Minute+10*time.Second))
+	if d := (q.Dat_lat - p.Dat_lat) * math.Pi / 180 * earthRadius; math.Abs(d-100) > 0.01 {
+		t.Errorf("Expected: 100m north after 10s, but got: %f", d)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		name  string
+		json  string
+		valid bool
+	}{
+		{"Valid", `{"name": "Drag off", "jamming": [{"lat": 45, "lon": -75, "radius": 1000}], "spoofing": [{"start": 60, "bearing": 90, "rate": 1}]}`, true},
+		{"No Radius", `{"jamming": [{"lat": 45, "lon": -75}]}`, false},
+		{"Invalid", `{"jamming": `, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".json")
+			if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			s, err := Load(path)
+			if tc.valid != (err == nil) {
+				t.Fatalf("Expected valid: %t, but got: %v", tc.valid, err)
+			}
+			if tc.valid && (len(s.Jamming) != 1 || s.Spoofing[0].Start != 60) {
+				t.Errorf("Expected: the scenario, but got: %+v", s)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
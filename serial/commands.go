@@ -0,0 +1,373 @@
+package serial
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/ubx"
+)
+
+// MAX_UBX_PAYLOAD is the largest UBX payload read from the device, anything longer is treated as noise
+const MAX_UBX_PAYLOAD = 1024
+
+// ubxMessages are the UBX class and ID of the outputs that can be configured with CFG-MSG
+var ubxMessages = map[string][2]byte{
+	"GGA":             {ubx.CLASS_NMEA, ubx.NMEA_GGA},
+	"RMC":             {ubx.CLASS_NMEA, ubx.NMEA_RMC},
+	"GSA":             {ubx.CLASS_NMEA, ubx.NMEA_GSA},
+	"GSV":             {ubx.CLASS_NMEA, ubx.NMEA_GSV},
+	"VTG":             {ubx.CLASS_NMEA, ubx.NMEA_VTG},
+	"THS":             {ubx.CLASS_NMEA, ubx.NMEA_THS},
+	"UBX_NAV_POSLLH":  {ubx.CLASS_NAV, ubx.NAV_POSLLH},
+	"UBX_NAV_PVT":     {ubx.CLASS_NAV, ubx.NAV_PVT},
+	"UBX_NAV_VELNED":  {ubx.CLASS_NAV, ubx.NAV_VELNED},
+	"UBX_NAV_TIMEUTC": {ubx.CLASS_NAV, ubx.NAV_TIMEUTC},
+	"UBX_NAV_SAT":     {ubx.CLASS_NAV, ubx.NAV_SAT},
+}
+
+// BAUD_RATES are the standard baud rates a device can change the port to
+var BAUD_RATES = [...]int{4800, 9600, 14400, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// validBaud returns whether b is one of the standard baud rates, so a garbled command can't set the port to
+// a rate the device can't use
+func validBaud(b int) bool {
+	for _, r := range BAUD_RATES {
+		if b == r {
+			return true
+		}
+	}
+	return false
+}
+
+// pmtkMessages are the names of the outputs in the order of the PMTK314 fields
+var pmtkMessages = [...]string{"GLL", "RMC", "VTG", "GGA", "GSA", "GSV"}
+
+// ubxIgnored are the CFG messages that are acknowledged, but have no effect
+var ubxIgnored = map[byte]bool{
+	ubx.CFG_CFG:    true,
+	ubx.CFG_SBAS:   true,
+	ubx.CFG_NAVX5:  true,
+	ubx.CFG_NAV5:   true,
+	ubx.CFG_TP5:    true,
+	ubx.CFG_GNSS:   true,
+	ubx.CFG_VALSET: true,
+}
+
+// readCommands will read UBX and NMEA commands from r until it fails, and write the replies to w
+// setBaud is called to change the baud rate, after the reply to the command has been written. The error
+// that stopped the reader is returned.
+func (s *Serial) readCommands(r io.Reader, w io.Writer, setBaud func(int) error) error {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			Logger.Debug("Command reader stopped", "err", err)
+			return err
+		}
+
+		var reply []byte
+		var baud int
+		switch b {
+		case ubx.SYNC_1:
+			m, err := readUBX(br)
+			if err != nil {
+				Logger.Debug("Invalid UBX command", "err", err)
+				continue
+			}
+			Logger.Debug("UBX command", "class", m.Class, "id", m.ID, "len", len(m.Payload))
+			reply, baud = s.handleUBX(m)
+		case '$':
+			line, err := br.ReadString('\n')
+			if err != nil {
+				Logger.Debug("Command reader stopped", "err", err)
+				return err
+			}
+			fields, err := nmea.Parse("$" + line)
+			if err != nil {
+				Logger.Debug("Invalid NMEA command", "err", err)
+				continue
+			}
+			Logger.Debug("NMEA command", "fields", fields)
+			reply, baud = s.handleNMEA(fields)
+		default:
+			continue
+		}
+
+		if len(reply) > 0 {
+			s.write(w, reply)
+		}
+		if baud > 0 {
+			Logger.Info("Baud rate changed by device", "baud", baud)
+			if err := setBaud(baud); err != nil {
+				Logger.Warn("Failed to set baud rate", "baud", baud, "err", err)
+			}
+		}
+	}
+}
+
+// readUBX will read the rest of a UBX message after the first sync character
+func readUBX(br *bufio.Reader) (ubx.Message, error) {
+	// don't consume the next byte if it isn't a sync character, it could be the start of the next command
+	if b, err := br.Peek(1); err != nil || b[0] != ubx.SYNC_2 {
+		return ubx.Message{}, fmt.Errorf("%w: no second sync character", ubx.ErrInvalidMessage)
+	}
+	header := make([]byte, 6)
+	header[0] = ubx.SYNC_1
+	if _, err := io.ReadFull(br, header[1:]); err != nil {
+		return ubx.Message{}, err
+	}
+	n := int(binary.LittleEndian.Uint16(header[4:]))
+	if n > MAX_UBX_PAYLOAD {
+		return ubx.Message{}, fmt.Errorf("%w: payload of %d is too long", ubx.ErrInvalidMessage, n)
+	}
+	frame := make([]byte, 6+n+2)
+	copy(frame, header)
+	if _, err := io.ReadFull(br, frame[6:]); err != nil {
+		return ubx.Message{}, err
+	}
+	return ubx.Parse(frame)
+}
+
+// handleUBX returns the reply to a UBX message, and the baud rate to change to once it is sent (or 0)
+func (s *Serial) handleUBX(m ubx.Message) (reply []byte, baud int) {
+	p := m.Payload
+	switch {
+	case m.Class == ubx.CLASS_MON && m.ID == ubx.MON_VER && len(p) == 0:
+		return ubx.MonVER("XPLANE-SERIAL-GPS", "00080000", "PROTVER=18.00"), 0
+	case m.Class != ubx.CLASS_CFG:
+		return nil, 0
+	}
+
+	ack := ubx.Ack(m.Class, m.ID)
+	nak := ubx.Nak(m.Class, m.ID)
+	switch m.ID {
+	case ubx.CFG_PRT:
+		switch len(p) {
+		case 0, 1:
+			return append(ubx.CfgPRT(uint32(s.Mode().BaudRate)), ack...), 0
+		case 20:
+			if p[0] != ubx.UART1 {
+				return ack, 0
+			}
+			b := int(binary.LittleEndian.Uint32(p[8:]))
+			if !validBaud(b) {
+				return nak, 0
+			}
+			if b == s.Mode().BaudRate {
+				return ack, 0
+			}
+			return ack, b
+		}
+	case ubx.CFG_MSG:
+		if len(p) < 2 {
+			break
+		}
+		name, ok := ubxMessageName(p[0], p[1])
+		if !ok {
+			return nak, 0
+		}
+		switch len(p) {
+		case 2:
+			return append(ubx.CfgMSG(p[0], p[1], byte(s.rate(name))), ack...), 0
+		case 3:
+			s.setRate(name, uint(p[2]))
+			return ack, 0
+		case 8:
+			s.setRate(name, uint(p[2+ubx.UART1]))
+			return ack, 0
+		}
+	case ubx.CFG_RATE:
+		switch len(p) {
+		case 0:
+			return append(ubx.CfgRATE(s.measRate()), ack...), 0
+		case 6:
+			measRate := binary.LittleEndian.Uint16(p[0:])
+			navRate := binary.LittleEndian.Uint16(p[2:])
+			if measRate < 10 || navRate == 0 {
+				return nak, 0
+			}
+			s.setNavRate(time.Duration(measRate) * time.Duration(navRate) * time.Millisecond)
+			return ack, 0
+		}
+	case ubx.CFG_RST:
+		// receivers reset without acknowledging
+		return nil, 0
+	default:
+		if ubxIgnored[m.ID] {
+			return ack, 0
+		}
+	}
+	return nak, 0
+}
+
+// handleNMEA returns the reply to a proprietary NMEA command, and the baud rate to change to once it is sent
+// (or 0)
+func (s *Serial) handleNMEA(fields []string) (reply []byte, baud int) {
+	switch {
+	case fields[0] == "PUBX":
+		return nil, s.handlePUBX(fields)
+	case strings.HasPrefix(fields[0], "PMTK"):
+		cmd, err := strconv.Atoi(fields[0][4:])
+		if err != nil {
+			return nil, 0
+		}
+		flag, baud := s.handlePMTK(cmd, fields[1:])
+		return []byte(nmea.ToPMTK001(cmd, flag)), baud
+	}
+	return nil, 0
+}
+
+// handlePUBX will apply a u-blox PUBX command and return the baud rate to change to (or 0)
+// PUBX commands are not acknowledged
+func (s *Serial) handlePUBX(fields []string) (baud int) {
+	if len(fields) < 2 {
+		return 0
+	}
+	switch fields[1] {
+	case "40":
+		// $PUBX,40,msgId,rddc,rus1,rus2,rusb,rspi,reserved
+		if len(fields) < 5 {
+			return 0
+		}
+		rate, err := strconv.ParseUint(fields[4], 10, 8)
+		if err != nil {
+			return 0
+		}
+		s.setRate(fields[2], uint(rate))
+	case "41":
+		// $PUBX,41,portId,inProto,outProto,baudrate,autobauding
+		if len(fields) < 6 || fields[2] != strconv.Itoa(ubx.UART1) {
+			return 0
+		}
+		b, err := strconv.Atoi(fields[5])
+		if err != nil || !validBaud(b) || b == s.Mode().BaudRate {
+			return 0
+		}
+		return b
+	}
+	return 0
+}
+
+// handlePMTK will apply a MediaTek PMTK command, returning the PMTK001 flag and the baud rate to change to
+// (or 0)
+func (s *Serial) handlePMTK(cmd int, args []string) (flag int, baud int) {
+	switch cmd {
+	case 220:
+		// $PMTK220,interval in ms
+		if len(args) < 1 {
+			return nmea.PMTK_ACK_INVALID, 0
+		}
+		ms, err := strconv.Atoi(args[0])
+		if err != nil || ms < 10 {
+			return nmea.PMTK_ACK_INVALID, 0
+		}
+		s.setNavRate(time.Duration(ms) * time.Millisecond)
+	case 251:
+		// $PMTK251,baud where 0 is the default
+		if len(args) < 1 {
+			return nmea.PMTK_ACK_INVALID, 0
+		}
+		b, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nmea.PMTK_ACK_INVALID, 0
+		}
+		if b == 0 {
+			b = 9600
+		}
+		if !validBaud(b) {
+			return nmea.PMTK_ACK_FAILED, 0
+		}
+		if b != s.Mode().BaudRate {
+			baud = b
+		}
+	case 314:
+		// $PMTK314,GLL,RMC,VTG,GGA,GSA,GSV,... or $PMTK314,-1 for the defaults
+		if len(args) == 1 && args[0] == "-1" {
+			s.resetRates()
+			break
+		}
+		for i, a := range args {
+			if i >= len(pmtkMessages) {
+				break
+			}
+			rate, err := strconv.ParseUint(a, 10, 8)
+			if err != nil {
+				return nmea.PMTK_ACK_INVALID, 0
+			}
+			s.setRate(pmtkMessages[i], uint(rate))
+		}
+	default:
+		return nmea.PMTK_ACK_UNSUPPORTED, 0
+	}
+	return nmea.PMTK_ACK_SUCCEEDED, baud
+}
+
+// ubxMessageName returns the name of the output with a UBX class and ID
+func ubxMessageName(class, id byte) (string, bool) {
+	for name, m := range ubxMessages {
+		if m[0] == class && m[1] == id {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// rate returns the rate of the named output, or 0 if there is no such output
+func (s *Serial) rate(name string) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.Outputs {
+		if o.Name == name {
+			return o.Rate
+		}
+	}
+	return 0
+}
+
+// setRate will set the rate of the named output, if there is one
+func (s *Serial) setRate(name string, rate uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.Outputs {
+		if o.Name == name {
+			Logger.Info("Rate changed by device", "output", name, "rate", rate)
+			o.Rate = rate
+			o.count = 0
+		}
+	}
+}
+
+// resetRates will set all outputs back to their original rates
+func (s *Serial) resetRates() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.Outputs {
+		o.Rate = o.defaultRate
+		o.count = 0
+	}
+}
+
+// setNavRate will set the interval between navigation solutions
+func (s *Serial) setNavRate(d time.Duration) {
+	Logger.Info("Navigation rate changed by device", "interval", d)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.navRate = d
+}
+
+// measRate returns the interval between navigation solutions in milliseconds, or 1000 if every position is a
+// solution
+func (s *Serial) measRate() uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.navRate == 0 {
+		return 1000
+	}
+	return uint16(s.navRate / time.Millisecond)
+}
@@ -0,0 +1,301 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/ubx"
+)
+
+func newTestSerial() *Serial {
+	return NewSerial([]*Output{
+		{Name: "GGA", Outputter: &outputters.GGA{}, Rate: 1},
+		{Name: "VTG", Outputter: &outputters.VTG{}, Rate: 1},
+		{Name: "RMC", Outputter: outputters.Adapt(&outputters.RMC{}), Rate: 0},
+		{Name: "UBX_NAV_PVT", Outputter: outputters.Adapt(&outputters.UBXNavPVT{}), Rate: 0},
+	})
+}
+
+func command(class, id byte, payload []byte) ubx.Message {
+	return ubx.Message{Class: class, ID: id, Payload: payload}
+}
+
+func rates(s *Serial) map[string]uint {
+	r := make(map[string]uint)
+	for _, o := range s.Outputs {
+		r[o.Name] = o.Rate
+	}
+	return r
+}
+
+func TestHandleUBX(t *testing.T) {
+	cfgPRT := make([]byte, 20)
+	cfgPRT[0] = ubx.UART1
+	binary.LittleEndian.PutUint32(cfgPRT[8:], 115200)
+	cfgPRTBad := make([]byte, 20)
+	cfgPRTBad[0] = ubx.UART1
+	binary.LittleEndian.PutUint32(cfgPRTBad[8:], 4000000000)
+	cfgPRTUSB := make([]byte, 20)
+	cfgPRTUSB[0] = 3
+	binary.LittleEndian.PutUint32(cfgPRTUSB[8:], 115200)
+
+	testCases := []struct {
+		name     string
+		msg      ubx.Message
+		expected []byte
+		baud     int
+		rates    map[string]uint
+	}{
+		{"Enable NAV-PVT", command(ubx.CLASS_CFG, ubx.CFG_MSG, []byte{ubx.CLASS_NAV, ubx.NAV_PVT, 1}),
+			ubx.Ack(ubx.CLASS_CFG, ubx.CFG_MSG), 0, map[string]uint{"GGA": 1, "VTG": 1, "RMC": 0, "UBX_NAV_PVT": 1}},
+		{"Disable GGA On UART1", command(ubx.CLASS_CFG, ubx.CFG_MSG, []byte{ubx.CLASS_NMEA, ubx.NMEA_GGA, 1, 0, 1, 1, 1, 0}),
+			ubx.Ack(ubx.CLASS_CFG, ubx.CFG_MSG), 0, map[string]uint{"GGA": 0, "VTG": 1, "RMC": 0, "UBX_NAV_PVT": 0}},
+		{"Poll VTG Rate", command(ubx.CLASS_CFG, ubx.CFG_MSG, []byte{ubx.CLASS_NMEA, ubx.NMEA_VTG}),
+			append(ubx.CfgMSG(ubx.CLASS_NMEA, ubx.NMEA_VTG, 1), ubx.Ack(ubx.CLASS_CFG, ubx.CFG_MSG)...), 0, nil},
+		{"Unsupported Message", command(ubx.CLASS_CFG, ubx.CFG_MSG, []byte{ubx.CLASS_NMEA, ubx.NMEA_ZDA, 1}),
+			ubx.Nak(ubx.CLASS_CFG, ubx.CFG_MSG), 0, nil},
+		{"Set Baud", command(ubx.CLASS_CFG, ubx.CFG_PRT, cfgPRT),
+			ubx.Ack(ubx.CLASS_CFG, ubx.CFG_PRT), 115200, nil},
+		{"Set Invalid Baud", command(ubx.CLASS_CFG, ubx.CFG_PRT, cfgPRTBad),
+			ubx.Nak(ubx.CLASS_CFG, ubx.CFG_PRT), 0, nil},
+		{"Set Baud On Another Port", command(ubx.CLASS_CFG, ubx.CFG_PRT, cfgPRTUSB),
+			ubx.Ack(ubx.CLASS_CFG, ubx.CFG_PRT), 0, nil},
+		{"Poll Port", command(ubx.CLASS_CFG, ubx.CFG_PRT, nil),
+			append(ubx.CfgPRT(9600), ubx.Ack(ubx.CLASS_CFG, ubx.CFG_PRT)...), 0, nil},
+		{"Poll Rate", command(ubx.CLASS_CFG, ubx.CFG_RATE, nil),
+			append(ubx.CfgRATE(1000), ubx.Ack(ubx.CLASS_CFG, ubx.CFG_RATE)...), 0, nil},
+		{"Invalid Rate", command(ubx.CLASS_CFG, ubx.CFG_RATE, []byte{0, 0, 1, 0, 1, 0}),
+			ubx.Nak(ubx.CLASS_CFG, ubx.CFG_RATE), 0, nil},
+		{"Ignored NAV5", command(ubx.CLASS_CFG, ubx.CFG_NAV5, make([]byte, 36)),
+			ubx.Ack(ubx.CLASS_CFG, ubx.CFG_NAV5), 0, nil},
+		{"Unknown CFG", command(ubx.CLASS_CFG, 0x7F, nil),
+			ubx.Nak(ubx.CLASS_CFG, 0x7F), 0, nil},
+		{"Reset", command(ubx.CLASS_CFG, ubx.CFG_RST, []byte{0, 0, 1, 0}), nil, 0, nil},
+		{"Poll NAV-PVT", command(ubx.CLASS_NAV, ubx.NAV_PVT, nil), nil, 0, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSerial()
+			reply, baud := s.handleUBX(tc.msg)
+			if !bytes.Equal(reply, tc.expected) {
+				t.Errorf("Expected: % X, but got: % X", tc.expected, reply)
+			}
+			if baud != tc.baud {
+				t.Errorf("Expected baud: %d, but got: %d", tc.baud, baud)
+			}
+			if tc.rates == nil {
+				return
+			}
+			for name, rate := range rates(s) {
+				if tc.rates[name] != rate {
+					t.Errorf("%s: Expected rate: %d, but got: %d", name, tc.rates[name], rate)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleUBXNavRate(t *testing.T) {
+	s := newTestSerial()
+	// 100ms measurements, with a solution every second measurement
+	_, _ = s.handleUBX(command(ubx.CLASS_CFG, ubx.CFG_RATE, []byte{100, 0, 2, 0, 1, 0}))
+	if s.navRate != 200*time.Millisecond {
+		t.Errorf("Expected navigation rate: 200ms, but got: %v", s.navRate)
+	}
+	if r := s.measRate(); r != 200 {
+		t.Errorf("Expected measurement rate: 200, but got: %d", r)
+	}
+}
+
+func TestHandleMonVER(t *testing.T) {
+	s := newTestSerial()
+	reply, _ := s.handleUBX(command(ubx.CLASS_MON, ubx.MON_VER, nil))
+	m, err := ubx.Parse(reply)
+	if err != nil {
+		t.Fatalf("Expected a valid MON-VER, but got: %v", err)
+	}
+	if m.Class != ubx.CLASS_MON || m.ID != ubx.MON_VER {
+		t.Errorf("Expected MON-VER, but got: %02X %02X", m.Class, m.ID)
+	}
+}
+
+func TestHandleNMEA(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sentence string
+		expected string
+		baud     int
+		navRate  time.Duration
+		rates    map[string]uint
+	}{
+		{"PUBX Enable RMC", "$PUBX,40,RMC,0,1,0,0,0,0", "", 0, 0, map[string]uint{"GGA": 1, "VTG": 1, "RMC": 1, "UBX_NAV_PVT": 0}},
+		{"PUBX Disable GGA", "$PUBX,40,GGA,0,0,0,0,0,0", "", 0, 0, map[string]uint{"GGA": 0, "VTG": 1, "RMC": 0, "UBX_NAV_PVT": 0}},
+		{"PUBX Baud", "$PUBX,41,1,0007,0003,38400,0", "", 38400, 0, nil},
+		{"PUBX Baud Other Port", "$PUBX,41,2,0007,0003,38400,0", "", 0, 0, nil},
+		{"PUBX Invalid Baud", "$PUBX,41,1,0007,0003,7,0", "", 0, 0, nil},
+		{"PMTK Baud", "$PMTK251,57600", nmea.ToPMTK001(251, nmea.PMTK_ACK_SUCCEEDED), 57600, 0, nil},
+		{"PMTK Invalid Baud", "$PMTK251,4000000000", nmea.ToPMTK001(251, nmea.PMTK_ACK_FAILED), 0, 0, nil},
+		{"PMTK Same Baud", "$PMTK251,0", nmea.ToPMTK001(251, nmea.PMTK_ACK_SUCCEEDED), 0, 0, nil},
+		{"PMTK Fix Interval", "$PMTK220,200", nmea.ToPMTK001(220, nmea.PMTK_ACK_SUCCEEDED), 0, 200 * time.Millisecond, nil},
+		{"PMTK Invalid Fix Interval", "$PMTK220,abc", nmea.ToPMTK001(220, nmea.PMTK_ACK_INVALID), 0, 0, nil},
+		{"PMTK Rates", "$PMTK314,0,1,0,5,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0", nmea.ToPMTK001(314, nmea.PMTK_ACK_SUCCEEDED), 0, 0,
+			map[string]uint{"GGA": 5, "VTG": 0, "RMC": 1, "UBX_NAV_PVT": 0}},
+		{"PMTK Default Rates", "$PMTK314,-1", nmea.ToPMTK001(314, nmea.PMTK_ACK_SUCCEEDED), 0, 0,
+			map[string]uint{"GGA": 1, "VTG": 1, "RMC": 0, "UBX_NAV_PVT": 0}},
+		{"PMTK Unsupported", "$PMTK605", nmea.ToPMTK001(605, nmea.PMTK_ACK_UNSUPPORTED), 0, 0, nil},
+		{"Other Sentence", "$GPGGA,1,2,3", "", 0, 0, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSerial()
+			fields, err := nmea.Parse(tc.sentence)
+			if err != nil {
+				t.Fatalf("Invalid test sentence: %v", err)
+			}
+			reply, baud := s.handleNMEA(fields)
+			if string(reply) != tc.expected {
+				t.Errorf("Expected: %q, but got: %q", tc.expected, reply)
+			}
+			if baud != tc.baud {
+				t.Errorf("Expected baud: %d, but got: %d", tc.baud, baud)
+			}
+			if s.navRate != tc.navRate {
+				t.Errorf("Expected navigation rate: %v, but got: %v", tc.navRate, s.navRate)
+			}
+			if tc.rates == nil {
+				return
+			}
+			for name, rate := range rates(s) {
+				if tc.rates[name] != rate {
+					t.Errorf("%s: Expected rate: %d, but got: %d", name, tc.rates[name], rate)
+				}
+			}
+		})
+	}
+}
+
+func TestReadCommands(t *testing.T) {
+	cfgPRT := make([]byte, 20)
+	cfgPRT[0] = ubx.UART1
+	binary.LittleEndian.PutUint32(cfgPRT[8:], 115200)
+
+	var in bytes.Buffer
+	in.WriteString("noise\xB5\x00")
+	in.Write(ubx.Frame(ubx.CLASS_CFG, ubx.CFG_MSG, []byte{ubx.CLASS_NAV, ubx.NAV_PVT, 1}))
+	in.WriteString("$PMTK220,200*2C\r\n")
+	in.WriteString("$PMTK220,200*FF\r\n")
+	in.Write(ubx.Frame(ubx.CLASS_CFG, ubx.CFG_PRT, cfgPRT))
+
+	s := newTestSerial()
+	var out bytes.Buffer
+	var bauds []int
+	s.readCommands(&in, &out, func(baud int) error {
+		// the reply must be sent at the old baud rate
+		if !bytes.HasSuffix(out.Bytes(), ubx.Ack(ubx.CLASS_CFG, ubx.CFG_PRT)) {
+			t.Errorf("Expected the ACK before the baud rate change")
+		}
+		bauds = append(bauds, baud)
+		return nil
+	})
+
+	var expected []byte
+	expected = append(expected, ubx.Ack(ubx.CLASS_CFG, ubx.CFG_MSG)...)
+	expected = append(expected, nmea.ToPMTK001(220, nmea.PMTK_ACK_SUCCEEDED)...)
+	expected = append(expected, ubx.Ack(ubx.CLASS_CFG, ubx.CFG_PRT)...)
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("Expected: %q, but got: %q", expected, out.Bytes())
+	}
+	if len(bauds) != 1 || bauds[0] != 115200 {
+		t.Errorf("Expected a baud rate change to 115200, but got: %v", bauds)
+	}
+	if r := rates(s)["UBX_NAV_PVT"]; r != 1 {
+		t.Errorf("Expected NAV-PVT rate: 1, but got: %d", r)
+	}
+}
+
+func TestNavDue(t *testing.T) {
+	s := newTestSerial()
+	start := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// without a navigation rate, every position is a solution
+	for i := 0; i < 3; i++ {
+		if !s.navDue(start.Add(time.Duration(i) * 100 * time.Millisecond)) {
+			t.Errorf("Expected every position to be due")
+		}
+	}
+
+	// 10Hz positions with a 1Hz navigation rate
+	s.setNavRate(time.Second)
+	var due []time.Time
+	for i := 0; i < 50; i++ {
+		now := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		if s.navDue(now) {
+			due = append(due, now)
+		}
+	}
+	if len(due) != 6 {
+		t.Fatalf("Expected 6 solutions, but got: %v", due)
+	}
+	// the first solution can be early by the jitter allowance, but after that they are a second apart
+	for i := 2; i < len(due); i++ {
+		if d := due[i].Sub(due[i-1]); d != time.Second {
+			t.Errorf("Expected solutions 1s apart, but got: %v", d)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	s := newTestSerial()
+	s.setRate("VTG", 2)
+
+	counts := make(map[outputters.Outputter]int)
+	for i := 0; i < 4; i++ {
+		for _, o := range s.due() {
+			counts[o]++
+		}
+	}
+	if n := counts[s.Outputs[0].Outputter]; n != 4 {
+		t.Errorf("Expected GGA 4 times, but got: %d", n)
+	}
+	if n := counts[s.Outputs[1].Outputter]; n != 2 {
+		t.Errorf("Expected VTG 2 times, but got: %d", n)
+	}
+	if n := counts[s.Outputs[2].Outputter]; n != 0 {
+		t.Errorf("Expected RMC 0 times, but got: %d", n)
+	}
+}
+
+func TestSetOutputs(t *testing.T) {
+	s := newTestSerial()
+	s.setRate("VTG", 5)
+	s.SetOutputs([]*Output{
+		{Name: "RMC", Outputter: outputters.Adapt(&outputters.RMC{}), Rate: 2},
+		{Name: "GSV", Outputter: &outputters.GSV{}, Rate: 0},
+	})
+
+	s.setRate("GSV", 1)
+	s.resetRates()
+	if r := s.rate("RMC"); r != 2 {
+		t.Errorf("Expected RMC rate: %d, but got: %d", 2, r)
+	}
+	if r := s.rate("GSV"); r != 0 {
+		t.Errorf("Expected GSV rate: %d, but got: %d", 0, r)
+	}
+	if r := s.rate("VTG"); r != 0 {
+		t.Errorf("Expected no VTG output, but got rate: %d", r)
+	}
+}
+
+func TestMessagesRegistered(t *testing.T) {
+	for name := range ubxMessages {
+		if _, ok := outputters.Lookup(name); !ok {
+			t.Errorf("Expected %s to be a registered outputter", name)
+		}
+	}
+}
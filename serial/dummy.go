@@ -13,15 +13,18 @@ type Dummy struct {
 
 // SendPositions will send the positions from the channel to the serial port
 func (s *Dummy) SendPositions(c <-chan xplane.Position) error {
+	var epochs outputters.Epochs
 	for pos := range c {
 		Logger.Info("Position", "pos", pos)
+		e := epochs.Next(pos)
+		var frames outputters.Frames
 		for _, o := range s.Outputters {
-			msg, err := o.Output(pos)
-			if err != nil {
+			if err := o.Outputs(e, &frames); err != nil {
 				Logger.Warn("Output failed", "err", err)
-				continue
 			}
-			Logger.Info("Output", "msg", msg)
+		}
+		for i := 0; i < frames.Len(); i++ {
+			Logger.Info("Output", "msg", string(frames.Frame(i)))
 		}
 	}
 	return nil
@@ -35,3 +38,16 @@ func (s *Dummy) SetPort(port string) {}
 
 // SetBaud will set the baud rate
 func (s *Dummy) SetBaud(baud int) {}
+
+// SetPin will pin the serial port to a USB device
+func (s *Dummy) SetPin(id USBID) {}
+
+// SetOutputs will set the outputters to the outputs that are on
+func (s *Dummy) SetOutputs(outputs []*Output) {
+	s.Outputters = nil
+	for _, o := range outputs {
+		if o.Rate > 0 {
+			s.Outputters = append(s.Outputters, o.Outputter)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// REOPEN_INTERVAL is how often the ports are enumerated to find a lost port again
+const REOPEN_INTERVAL = time.Second
+
+// EventType is the kind of change in the state of the serial port
+type EventType uint8
+
+// Possible event types
+const (
+	// PortLost is when a write to the port fails or the device is removed
+	PortLost EventType = iota
+	// PortReopened is when a lost port has been found again and reopened
+	PortReopened
+)
+
+// Event is a change in the state of the serial port while sending
+type Event struct {
+	Type EventType
+	// Port is the name of the port, which can change when a USB device is plugged in again
+	Port string
+	// Err is why the port was lost
+	Err error
+}
+
+// String returns the event as a status message
+func (e Event) String() string {
+	switch e.Type {
+	case PortLost:
+		return fmt.Sprintf("Serial port %s lost, waiting for it", e.Port)
+	case PortReopened:
+		return fmt.Sprintf("Serial port %s reopened", e.Port)
+	}
+	return fmt.Sprintf("Serial port %s event %d", e.Port, e.Type)
+}
+
+// port is an open serial port
+type port interface {
+	io.ReadWriteCloser
+	SetMode(mode *serial.Mode) error
+}
+
+// openPort opens a serial port
+func openPort(name string, mode *serial.Mode) (port, error) {
+	return serial.Open(name, mode)
+}
+
+// identify returns the USB identity of the named port, or an empty identity if it isn't a USB device or
+// the ports can't be listed
+func (s *Serial) identify(name string) USBID {
+	ports, err := s.ports()
+	if err != nil {
+		Logger.Debug("Could not list ports", "err", err)
+		return USBID{}
+	}
+	for _, p := range ports {
+		if p.Name == name {
+			return p.USBID
+		}
+	}
+	return USBID{}
+}
+
+// find returns the name of the port that is the device with id, which was last at name, or "" if it isn't
+// plugged in
+// A device with a serial number is matched on that and its VID and PID wherever it is. One without is
+// matched on VID and PID, preferring the same name. Without a USB identity, the same name must reappear.
+func (s *Serial) find(name string, id USBID) string {
+	ports, err := s.ports()
+	if err != nil {
+		Logger.Debug("Could not list ports", "err", err)
+		return ""
+	}
+
+	if id.IsZero() {
+		for _, p := range ports {
+			if p.Name == name {
+				return name
+			}
+		}
+		return ""
+	}
+
+	match := ""
+	for _, p := range ports {
+		if !p.IsUSB || p.VID != strings.ToUpper(id.VID) || p.PID != strings.ToUpper(id.PID) {
+			continue
+		}
+		if id.SerialNumber != "" {
+			if p.SerialNumber == id.SerialNumber {
+				return p.Name
+			}
+			continue
+		}
+		if p.Name == name {
+			return name
+		}
+		if match == "" {
+			match = p.Name
+		}
+	}
+	return match
+}
+
+// ports returns the ports on the system with their USB details, where the system provides them
+func (s *Serial) ports() ([]Port, error) {
+	return findPorts(s.list)
+}
+
+// event will report an event to the feedback channel and the Events channel, if there is one
+// Events are dropped if the Events channel is full, so a slow reader doesn't stop the positions
+func (s *Serial) event(e Event, feedback chan<- string) {
+	Logger.Info("Serial port event", "event", e.String(), "err", e.Err)
+	feedback <- e.String()
+	if s.Events == nil {
+		return
+	}
+	select {
+	case s.Events <- e:
+	default:
+	}
+}
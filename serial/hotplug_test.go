@@ -0,0 +1,219 @@
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+var errUnplugged = errors.New("device unplugged")
+
+// fakePort is a port that records what is written to it, and fails like a removed device once unplugged
+type fakePort struct {
+	mu         sync.Mutex
+	buf        bytes.Buffer
+	failWrites bool
+	unplugged  chan struct{}
+	once       sync.Once
+}
+
+func newFakePort() *fakePort {
+	return &fakePort{unplugged: make(chan struct{})}
+}
+
+func (f *fakePort) Read(bs []byte) (int, error) {
+	<-f.unplugged
+	return 0, errUnplugged
+}
+
+func (f *fakePort) Write(bs []byte) (int, error) {
+	select {
+	case <-f.unplugged:
+		return 0, errUnplugged
+	default:
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failWrites {
+		return 0, errUnplugged
+	}
+	return f.buf.Write(bs)
+}
+
+func (f *fakePort) String() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buf.String()
+}
+
+func (f *fakePort) unplug()                         { f.once.Do(func() { close(f.unplugged) }) }
+func (f *fakePort) Close() error                    { f.unplug(); return nil }
+func (f *fakePort) SetMode(mode *serial.Mode) error { return nil }
+
+// fakeSystem is the serial ports plugged in to a fake system
+type fakeSystem struct {
+	mu     sync.Mutex
+	ports  []*enumerator.PortDetails
+	opened map[string]*fakePort
+}
+
+func (f *fakeSystem) plug(ports ...*enumerator.PortDetails) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ports = ports
+}
+
+func (f *fakeSystem) port(name string) *fakePort {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opened[name]
+}
+
+func (f *fakeSystem) list() ([]*enumerator.PortDetails, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*enumerator.PortDetails(nil), f.ports...), nil
+}
+
+func (f *fakeSystem) open(name string, mode *serial.Mode) (port, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.ports {
+		if p.Name == name {
+			fp := newFakePort()
+			f.opened[name] = fp
+			return fp, nil
+		}
+	}
+	return nil, errors.New("no such port")
+}
+
+func TestFind(t *testing.T) {
+	ftdi := func(name, sn string) *enumerator.PortDetails {
+		return &enumerator.PortDetails{Name: name, IsUSB: true, VID: "0403", PID: "6001", SerialNumber: sn}
+	}
+	testCases := []struct {
+		name     string
+		ports    []*enumerator.PortDetails
+		last     string
+		id       USBID
+		expected string
+	}{
+		{"Same Port", []*enumerator.PortDetails{ftdi("/dev/ttyUSB0", "A1")}, "/dev/ttyUSB0", USBID{"0403", "6001", "A1"}, "/dev/ttyUSB0"},
+		{"Moved Port", []*enumerator.PortDetails{ftdi("/dev/ttyUSB0", "B2"), ftdi("/dev/ttyUSB1", "A1")}, "/dev/ttyUSB0", USBID{"0403", "6001", "A1"}, "/dev/ttyUSB1"},
+		{"Other Device", []*enumerator.PortDetails{ftdi("/dev/ttyUSB0", "B2")}, "/dev/ttyUSB0", USBID{"0403", "6001", "A1"}, ""},
+		{"No Serial Number Prefers Name", []*enumerator.PortDetails{ftdi("/dev/ttyUSB0", ""), ftdi("/dev/ttyUSB1", "")}, "/dev/ttyUSB1", USBID{"0403", "6001", ""}, "/dev/ttyUSB1"},
+		{"No Serial Number Moved", []*enumerator.PortDetails{ftdi("/dev/ttyUSB2", "")}, "/dev/ttyUSB1", USBID{"0403", "6001", ""}, "/dev/ttyUSB2"},
+		{"Not USB", []*enumerator.PortDetails{{Name: "/dev/ttyS0"}}, "/dev/ttyS0", USBID{}, "/dev/ttyS0"},
+		{"Not USB Gone", []*enumerator.PortDetails{{Name: "/dev/ttyS1"}}, "/dev/ttyS0", USBID{}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSerial()
+			s.list = func() ([]*enumerator.PortDetails, error) { return tc.ports, nil }
+			if got := s.find(tc.last, tc.id); got != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	sys := &fakeSystem{opened: make(map[string]*fakePort)}
+	sys.plug(&enumerator.PortDetails{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A1"})
+
+	events := make(chan Event, 10)
+	s := newTestSerial()
+	s.SetPort("/dev/ttyUSB0")
+	s.Events = events
+	s.open = sys.open
+	s.list = sys.list
+
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+
+	c <- xplane.Position{}
+	first := sys.port("/dev/ttyUSB0")
+	if first == nil || first.String() == "" {
+		t.Fatalf("Expected output on /dev/ttyUSB0")
+	}
+
+	// unplug the device, and plug it in again as another port
+	sys.plug()
+	first.unplug()
+	select {
+	case e := <-events:
+		if e.Type != PortLost || e.Port != "/dev/ttyUSB0" || !errors.Is(e.Err, errUnplugged) {
+			t.Errorf("Expected /dev/ttyUSB0 lost, but got: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the port to be lost")
+	}
+	sys.plug(&enumerator.PortDetails{Name: "/dev/ttyUSB1", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A1"})
+
+	select {
+	case e := <-events:
+		if e.Type != PortReopened || e.Port != "/dev/ttyUSB1" {
+			t.Errorf("Expected /dev/ttyUSB1 reopened, but got: %+v", e)
+		}
+	case <-time.After(3 * REOPEN_INTERVAL):
+		t.Fatalf("Expected the port to be reopened")
+	}
+
+	c <- xplane.Position{}
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if second := sys.port("/dev/ttyUSB1"); second == nil || second.String() == "" {
+		t.Errorf("Expected output on /dev/ttyUSB1")
+	}
+	if msg := <-feedback; msg != "Serial port /dev/ttyUSB0 lost, waiting for it" {
+		t.Errorf("Expected lost feedback, but got: %s", msg)
+	}
+}
+
+func TestWriteFailure(t *testing.T) {
+	sys := &fakeSystem{opened: make(map[string]*fakePort)}
+	sys.plug(&enumerator.PortDetails{Name: "/dev/ttyS0"})
+
+	events := make(chan Event, 10)
+	s := newTestSerial()
+	s.SetPort("/dev/ttyS0")
+	s.Events = events
+	s.open = sys.open
+	s.list = sys.list
+
+	c := make(chan xplane.Position)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, make(chan string, 10)) }()
+
+	c <- xplane.Position{}
+	// a write that fails loses the port, even if the reader hasn't noticed
+	p := sys.port("/dev/ttyS0")
+	p.mu.Lock()
+	p.failWrites = true
+	p.mu.Unlock()
+	c <- xplane.Position{}
+
+	select {
+	case e := <-events:
+		if e.Type != PortLost || !errors.Is(e.Err, errUnplugged) {
+			t.Errorf("Expected the port to be lost, but got: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the port to be lost")
+	}
+	close(c)
+	<-done
+}
@@ -0,0 +1,103 @@
+package serial
+
+import (
+	"fmt"
+	"strings"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// USBID identifies a USB serial device, so it can be found again if it is plugged in to a different port
+type USBID struct {
+	VID          string
+	PID          string
+	SerialNumber string
+}
+
+// IsZero returns whether the identity is empty, eg for a port that isn't a USB device
+func (id USBID) IsZero() bool {
+	return id == USBID{}
+}
+
+// String returns the identity as VID:PID and the serial number, eg "0403:6001 A10K3XYZ"
+func (id USBID) String() string {
+	if id.SerialNumber == "" {
+		return fmt.Sprintf("%s:%s", id.VID, id.PID)
+	}
+	return fmt.Sprintf("%s:%s %s", id.VID, id.PID, id.SerialNumber)
+}
+
+// Port is a serial port on the system, with the details of its USB device if it is one
+type Port struct {
+	// Name is the name to open the port with, eg "/dev/ttyUSB0" or "COM3"
+	Name  string
+	IsUSB bool
+	USBID
+	// Product describes the USB device, eg "FT232R USB UART". Not all systems provide it
+	Product string
+}
+
+// Description returns the port with its USB details, for people to choose between ports
+// eg "/dev/ttyUSB0 - FT232R USB UART (0403:6001 A10K3XYZ)"
+func (p Port) Description() string {
+	if !p.IsUSB {
+		return p.Name
+	}
+	var b strings.Builder
+	b.WriteString(p.Name)
+	if p.Product != "" {
+		b.WriteString(" - ")
+		b.WriteString(p.Product)
+	}
+	fmt.Fprintf(&b, " (%s)", p.USBID)
+	return b.String()
+}
+
+// FindPorts will find the available serial ports on the system, with USB details where the system provides
+// them
+func FindPorts() ([]Port, error) {
+	return findPorts(listPorts)
+}
+
+// findPorts will find the available serial ports with the details from list, or just their names if list fails
+func findPorts(list func() ([]*enumerator.PortDetails, error)) ([]Port, error) {
+	details, err := list()
+	if err == nil {
+		ports := toPorts(details)
+		Logger.Debug("Found ports", "ports", ports)
+		return ports, nil
+	}
+
+	// detailed enumeration isn't available everywhere, so fall back to the names
+	Logger.Debug("Could not list port details", "err", err)
+	names, err := serial.GetPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("could not list serial ports: %v", err)
+	}
+	ports := make([]Port, len(names))
+	for i, n := range names {
+		ports[i] = Port{Name: n}
+	}
+	Logger.Debug("Found ports", "ports", ports)
+	return ports, nil
+}
+
+// toPorts returns the Ports for the details from the enumerator
+// VIDs and PIDs are upper case, as some systems report them in lower case
+func toPorts(details []*enumerator.PortDetails) []Port {
+	ports := make([]Port, len(details))
+	for i, d := range details {
+		ports[i] = Port{Name: d.Name, IsUSB: d.IsUSB}
+		if d.IsUSB {
+			ports[i].USBID = USBID{VID: strings.ToUpper(d.VID), PID: strings.ToUpper(d.PID), SerialNumber: d.SerialNumber}
+			ports[i].Product = d.Product
+		}
+	}
+	return ports
+}
+
+// listPorts lists the serial ports with their USB details
+func listPorts() ([]*enumerator.PortDetails, error) {
+	return enumerator.GetDetailedPortsList()
+}
@@ -0,0 +1,103 @@
+package serial
+
+import (
+	"errors"
+	"testing"
+
+	"go.bug.st/serial/enumerator"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestDescription(t *testing.T) {
+	testCases := []struct {
+		name     string
+		port     Port
+		expected string
+	}{
+		{"Not USB", Port{Name: "/dev/ttyS0"}, "/dev/ttyS0"},
+		{"USB", Port{Name: "/dev/ttyUSB0", IsUSB: true, USBID: USBID{"0403", "6001", "A10K3XYZ"}, Product: "FT232R USB UART"},
+			"/dev/ttyUSB0 - FT232R USB UART (0403:6001 A10K3XYZ)"},
+		{"No Product", Port{Name: "COM3", IsUSB: true, USBID: USBID{"067B", "2303", ""}}, "COM3 (067B:2303)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.port.Description(); got != tc.expected {
+				t.Errorf("Expected: %s, but got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestToPorts(t *testing.T) {
+	ports := toPorts([]*enumerator.PortDetails{
+		{Name: "/dev/ttyS0", VID: "ignored"},
+		{Name: "/dev/ttyACM0", IsUSB: true, VID: "1546", PID: "01a8", SerialNumber: "abc", Product: "u-blox GNSS receiver"},
+	})
+
+	if len(ports) != 2 {
+		t.Fatalf("Expected 2 ports, but got: %d", len(ports))
+	}
+	if !ports[0].USBID.IsZero() {
+		t.Errorf("Expected no USB identity, but got: %s", ports[0].USBID)
+	}
+	expected := USBID{VID: "1546", PID: "01A8", SerialNumber: "abc"}
+	if ports[1].USBID != expected {
+		t.Errorf("Expected: %s, but got: %s", expected, ports[1].USBID)
+	}
+}
+
+func TestFindPorts(t *testing.T) {
+	details := func() ([]*enumerator.PortDetails, error) {
+		return []*enumerator.PortDetails{{Name: "/dev/ttyACM0", IsUSB: true, VID: "1546", PID: "01a8"}}, nil
+	}
+	ports, err := findPorts(details)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(ports) != 1 || ports[0].Name != "/dev/ttyACM0" || ports[0].PID != "01A8" {
+		t.Errorf("Expected the port from the details, but got: %v", ports)
+	}
+
+	// without details, the names of the ports are listed instead
+	failing := func() ([]*enumerator.PortDetails, error) { return nil, errors.New("not supported") }
+	if _, err := findPorts(failing); err != nil {
+		t.Errorf("Expected the names of the ports, but got: %v", err)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	sys := &fakeSystem{opened: make(map[string]*fakePort)}
+	sys.plug(
+		&enumerator.PortDetails{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "B2"},
+		&enumerator.PortDetails{Name: "/dev/ttyUSB1", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A1"},
+	)
+
+	s := newTestSerial()
+	s.SetPort("/dev/ttyUSB0")
+	s.SetPin(USBID{VID: "0403", PID: "6001", SerialNumber: "A1"})
+	s.open = sys.open
+	s.list = sys.list
+
+	c := make(chan xplane.Position)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, make(chan string, 10)) }()
+	c <- xplane.Position{}
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if sys.port("/dev/ttyUSB0") != nil {
+		t.Errorf("Expected the selected port not to be opened")
+	}
+	if p := sys.port("/dev/ttyUSB1"); p == nil || p.String() == "" {
+		t.Errorf("Expected output on the pinned port /dev/ttyUSB1")
+	}
+
+	// a pinned device that isn't plugged in is an error
+	s.SetPin(USBID{VID: "0403", PID: "6001", SerialNumber: "C3"})
+	if err := s.SendPositions(make(chan xplane.Position), make(chan string, 10)); err == nil {
+		t.Errorf("Expected an error for a missing pinned port")
+	}
+}
@@ -1,9 +1,15 @@
 package serial
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"sync"
+	"time"
 
 	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
 
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
 	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
@@ -24,65 +30,252 @@ type Sender interface {
 	SetPort(string)
 	// SetBaud will set the baud rate
 	SetBaud(int)
+	// SetPin will pin the serial port to a USB device
+	SetPin(USBID)
+	// SetOutputs will set the outputs sent to the serial port
+	SetOutputs([]*Output)
+}
+
+// Output is an outputter that is sent to the serial port every Rate navigation solutions
+type Output struct {
+	// Name identifies the output in commands from the device, eg "GGA" or "UBX_NAV_PVT"
+	Name      string
+	Outputter outputters.Outputter
+	// Rate is how often the output is sent, in navigation solutions. 0 turns it off and 1 sends it with every
+	// solution
+	Rate uint
+
+	defaultRate uint
+	count       uint
 }
 
 // Serial is an object that will send positions to a serial port
+// It also reads the commands the device sends back, so the device can configure it like a GPS receiver
 type Serial struct {
-	port       string
-	mode       *serial.Mode
-	Outputters []outputters.Outputter
+	port    string
+	mode    *serial.Mode
+	Outputs []*Output
+	// Events receives changes in the state of the port while sending, if it is set
+	Events chan<- Event
+
+	// open and list are replaced in tests
+	open func(name string, mode *serial.Mode) (port, error)
+	list func() ([]*enumerator.PortDetails, error)
+
+	// mu protects the rates, which commands from the device change while sending, and the pin
+	mu      sync.Mutex
+	pin     USBID
+	navRate time.Duration
+	nextNav time.Time
+	// writeMu stops command replies and outputs being interleaved
+	writeMu sync.Mutex
 }
 
 // NewSerial returns a new Serial
-func NewSerial(outputters []outputters.Outputter) *Serial {
+func NewSerial(outputs []*Output) *Serial {
+	setDefaultRates(outputs)
 	return &Serial{
 		mode: &serial.Mode{
 			BaudRate: 9600,
 			Parity:   serial.NoParity,
 			DataBits: 8,
 		},
-		Outputters: outputters,
+		Outputs: outputs,
+		open:    openPort,
+		list:    listPorts,
 	}
 }
 
 // SendPositions will send the positions from the channel to the serial port
+// If the port is lost, because a write fails or the device is removed, positions are dropped and the ports
+// are enumerated every REOPEN_INTERVAL until it is found and reopened.
 func (s *Serial) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
 	Logger.Debug("SendPositions Started")
 
-	ser, err := serial.Open(
-		s.port,
-		s.mode,
-	)
+	name, id := s.port, s.Pin()
+	if id.IsZero() {
+		id = s.identify(name)
+	} else if name = s.find(s.port, id); name == "" {
+		Logger.Error("Pinned serial port not found", "pin", id)
+		feedback <- fmt.Sprintf("Serial port %s not found", id)
+		return fmt.Errorf("could not find serial port %s", id)
+	}
+
+	ser, err := s.open(name, s.modePtr())
 	if err != nil {
 		Logger.Error("Failed to open serial port", "err", err)
 		feedback <- "Failed to open serial port"
 		return err
 	}
+	Logger.Debug("Serial port opened", "port", name, "mode", s.mode)
+
+	sess := s.start(ser)
 	defer func() {
-		ser.Close()
-		Logger.Debug("Serial port closed")
+		if sess != nil {
+			sess.close()
+		}
 	}()
-	Logger.Debug("Serial port opened", "port", s.port, "mode", s.mode)
 
-	for pos := range c {
-		for _, o := range s.Outputters {
-			msg, err := o.Output(pos)
+	ticker := time.NewTicker(REOPEN_INTERVAL)
+	defer ticker.Stop()
+
+	var (
+		epochs outputters.Epochs
+		frames outputters.Frames
+	)
+
+	lost := func(err error) {
+		sess.close()
+		sess = nil
+		s.event(Event{Type: PortLost, Port: name, Err: err}, feedback)
+	}
+
+	for {
+		// a nil channel blocks, so there is nothing to wait for while the port is lost
+		var readerDone <-chan error
+		if sess != nil {
+			readerDone = sess.done
+		}
+
+		select {
+		case pos, ok := <-c:
+			if !ok {
+				return nil
+			}
+			if sess == nil || !s.navDue(time.Now()) {
+				continue
+			}
+			// the frames are reused for every epoch, so sending doesn't allocate at high rates
+			e := epochs.Next(pos)
+			frames.Reset()
+			for _, o := range s.due() {
+				if err := o.Outputs(e, &frames); err != nil {
+					Logger.Warn("Output failed", "err", err)
+					feedback <- "Output failed"
+				}
+			}
+			for i := 0; i < frames.Len(); i++ {
+				if err := s.write(sess.port, frames.Frame(i)); err != nil {
+					lost(err)
+					break
+				}
+				if Logger.Enabled(context.Background(), slog.LevelDebug) {
+					Logger.Debug("Sent", "msg", string(frames.Frame(i)))
+				}
+			}
+		case err := <-readerDone:
+			// the reader only stops by itself when the port fails
+			sess.done = nil
+			lost(err)
+		case <-ticker.C:
+			if sess != nil {
+				continue
+			}
+			found := s.find(name, id)
+			if found == "" {
+				continue
+			}
+			ser, err := s.open(found, s.modePtr())
 			if err != nil {
-				Logger.Warn("Output failed", "err", err)
-				feedback <- "Output failed"
+				Logger.Debug("Could not reopen serial port", "port", found, "err", err)
 				continue
 			}
-			ser.Write([]byte(msg))
-			Logger.Debug("Sent", "msg", msg)
+			name = found
+			sess = s.start(ser)
+			s.event(Event{Type: PortReopened, Port: name}, feedback)
 		}
 	}
+}
 
-	return nil
+// session is an open serial port with a reader of commands from the device
+type session struct {
+	port port
+	// done receives the error that stopped the reader, and is nil once it has been received
+	done chan error
+}
+
+// start will start reading commands from an open port
+func (s *Serial) start(p port) *session {
+	sess := &session{port: p, done: make(chan error, 1)}
+	go func() {
+		sess.done <- s.readCommands(p, p, func(baud int) error {
+			// the rate is only kept if the port changes to it, so a reopen doesn't use a rate that failed
+			mode := s.Mode()
+			mode.BaudRate = baud
+			if err := p.SetMode(&mode); err != nil {
+				return err
+			}
+			s.SetBaud(baud)
+			return nil
+		})
+	}()
+	return sess
+}
+
+// close will close the port and wait for the reader to stop
+func (sess *session) close() {
+	sess.port.Close()
+	if sess.done != nil {
+		<-sess.done
+	}
+	Logger.Debug("Serial port closed")
+}
+
+// modePtr returns a copy of the current mode to open the port with
+func (s *Serial) modePtr() *serial.Mode {
+	m := s.Mode()
+	return &m
+}
+
+// navDue returns whether a navigation solution is due at now
+// Without a navigation rate every position is a solution. Otherwise positions are dropped until the next
+// solution is due, allowing for a little jitter in when positions arrive.
+func (s *Serial) navDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.navRate == 0 {
+		return true
+	}
+	if now.Before(s.nextNav.Add(-s.navRate / 10)) {
+		return false
+	}
+	s.nextNav = s.nextNav.Add(s.navRate)
+	// start again if solutions have fallen behind, eg when the rate was changed
+	if s.nextNav.Before(now) {
+		s.nextNav = now.Add(s.navRate)
+	}
+	return true
+}
+
+// due returns the outputters due to be sent with this navigation solution
+func (s *Serial) due() []outputters.Outputter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []outputters.Outputter
+	for _, o := range s.Outputs {
+		if o.Rate == 0 {
+			continue
+		}
+		o.count++
+		if o.count >= o.Rate {
+			o.count = 0
+			due = append(due, o.Outputter)
+		}
+	}
+	return due
+}
+
+// write will write bs to w, without interleaving other writes
+func (s *Serial) write(w io.Writer, bs []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := w.Write(bs)
+	return err
 }
 
 // Configured will return true if the serial port is configured
 func (s *Serial) Configured() bool {
-	return s.port != "" && s.mode.BaudRate != 0
+	return (s.port != "" || !s.Pin().IsZero()) && s.Mode().BaudRate != 0
 }
 
 // SetPort will set the serial port
@@ -91,40 +284,73 @@ func (s *Serial) SetPort(port string) {
 	s.port = port
 }
 
+// SetPin will pin the serial port to a USB device, so it is used whichever port it is plugged in to
+// An empty identity unpins the port.
+func (s *Serial) SetPin(id USBID) {
+	Logger.Debug("SetPin", "pin", id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pin = id
+}
+
+// Pin returns the USB device the serial port is pinned to, or an empty identity if it isn't pinned
+func (s *Serial) Pin() USBID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pin
+}
+
+// SetOutputs will set the outputs sent to the serial port, even while it is sending
+// The rates the device set are replaced by the rates of the new outputs.
+func (s *Serial) SetOutputs(outputs []*Output) {
+	setDefaultRates(outputs)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Outputs = outputs
+}
+
+// setDefaultRates will set the rates the outputs are reset to, to their current rates
+func setDefaultRates(outputs []*Output) {
+	for _, o := range outputs {
+		o.defaultRate = o.Rate
+	}
+}
+
 // SetBaud will set the baud rate
 func (s *Serial) SetBaud(baud int) {
 	Logger.Debug("SetBaud", "baud", baud)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.mode.BaudRate = baud
 }
 
 // SetDataBits will set the data bits
 func (s *Serial) SetDataBits(dataBits int) {
 	Logger.Debug("Set DataBits", "databits", dataBits)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.mode.DataBits = dataBits
 }
 
 // SetParity will set the parity
 func (s *Serial) SetParity(parity serial.Parity) {
 	Logger.Debug("Set Parity", "parity", parity)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.mode.Parity = parity
 }
 
 // SetStopBits will set the stop bits
 func (s *Serial) SetStopBits(stopBits serial.StopBits) {
 	Logger.Debug("Set StopBits", "stopbits", stopBits)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.mode.StopBits = stopBits
 }
 
 // Mode will return the current mode
-func (s *Serial) Mode() serial.Mode { return *s.mode }
-
-// FindPorts will find the available serial ports on the system
-func FindPorts() []string {
-	ports, err := serial.GetPortsList()
-	if err != nil {
-		panic(err)
-	}
-
-	Logger.Debug("Found ports", "ports", ports)
-	return ports
+func (s *Serial) Mode() serial.Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.mode
 }
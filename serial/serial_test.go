@@ -0,0 +1,137 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// countingPort is a port that counts what is written to it and throws it away
+type countingPort struct {
+	mu      sync.Mutex
+	written int
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (p *countingPort) Read(bs []byte) (int, error) {
+	<-p.closed
+	return 0, errUnplugged
+}
+
+func (p *countingPort) Write(bs []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written += len(bs)
+	return len(bs), nil
+}
+
+func (p *countingPort) Close() error                    { p.once.Do(func() { close(p.closed) }); return nil }
+func (p *countingPort) SetMode(mode *serial.Mode) error { return nil }
+
+// rposPacket returns a RPOS packet from X-Plane, of an aircraft flying west
+func rposPacket() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xplane.RPOS_HEADER)
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Lon, Lat, Ele                           float64
+		Agl, The, Psi, Phi, Vx, Vy, Vz, P, Q, R float32
+	}{-75.5, 45.25, 1500, 1200, 2, 271, -5, -60, 1, 2, 0, 0, 0.01})
+	return buf.Bytes()
+}
+
+// BenchmarkThroughput measures the whole path of a position through the serial port: parsing the RPOS packet
+// from X-Plane, formatting the sentences and writing them to the port
+// cpu% is the share of one CPU needed to keep up with X-Plane at each rate.
+func BenchmarkThroughput(b *testing.B) {
+	for _, freq := range []uint{10, 20, 50, xplane.MAX_POSITION_FREQ} {
+		b.Run(fmt.Sprintf("%dHz", freq), func(b *testing.B) {
+			s := NewSerial([]*Output{
+				{Name: "GGA", Outputter: &outputters.GGA{}, Rate: 1},
+				{Name: "VTG", Outputter: &outputters.VTG{}, Rate: 1},
+				{Name: "RMC", Outputter: outputters.Adapt(&outputters.RMC{}), Rate: 1},
+			})
+			s.SetPort("/dev/ttyBENCH")
+			p := &countingPort{closed: make(chan struct{})}
+			s.open = func(name string, mode *serial.Mode) (port, error) { return p, nil }
+			s.list = (&fakeSystem{}).list
+
+			c := make(chan xplane.Position)
+			feedback := make(chan string, 10)
+			done := make(chan error)
+			go func() { done <- s.SendPositions(c, feedback) }()
+
+			packet := rposPacket()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var pos xplane.Position
+				if err := xplane.ParsePosition(packet[len(xplane.RPOS_HEADER):], &pos); err != nil {
+					b.Fatal(err)
+				}
+				pos.Time = time.Now()
+				c <- pos
+			}
+			close(c)
+			if err := <-done; err != nil {
+				b.Fatal(err)
+			}
+			b.StopTimer()
+
+			perPosition := float64(b.Elapsed().Nanoseconds()) / float64(b.N)
+			b.ReportMetric(perPosition*float64(freq)/1e9*100, "cpu%")
+			b.ReportMetric(float64(p.written)/float64(b.N), "bytes/position")
+		})
+	}
+}
+
+// commandPort is a port that the device sends commands from, and that can fail to change its mode
+type commandPort struct {
+	commands *bytes.Reader
+	failMode bool
+	mode     serial.Mode
+}
+
+func (p *commandPort) Read(bs []byte) (int, error)  { return p.commands.Read(bs) }
+func (p *commandPort) Write(bs []byte) (int, error) { return len(bs), nil }
+func (p *commandPort) Close() error                 { return nil }
+func (p *commandPort) SetMode(mode *serial.Mode) error {
+	if p.failMode {
+		return fmt.Errorf("unsupported baud rate")
+	}
+	p.mode = *mode
+	return nil
+}
+
+func TestStartBaud(t *testing.T) {
+	testCases := []struct {
+		name     string
+		failMode bool
+		expected int
+	}{
+		{"Changed", false, 57600},
+		{"Failed", true, 9600},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSerial()
+			s.SetBaud(9600)
+			p := &commandPort{commands: bytes.NewReader([]byte("$PMTK251,57600*2C\r\n")), failMode: tc.failMode}
+			sess := s.start(p)
+			<-sess.done
+
+			if baud := s.Mode().BaudRate; baud != tc.expected {
+				t.Errorf("Expected: %d, but got: %d", tc.expected, baud)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/config"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/delay"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/faults"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gdl90"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gpsd"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/mavlink"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/nmea"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/noise"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/pty"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/resample"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/scenario"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/serial"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/udp"
+)
+
+// applyConfig will apply the global settings in the config
+func applyConfig(cfg *config.Config, logger *slog.Logger) {
+	if cfg.Talker != "" {
+		t, err := nmea.ParseTalker(cfg.Talker)
+		if err != nil {
+			logger.Error("Invalid talker in config", "err", err)
+		} else {
+			nmea.SetDefaultTalker(t)
+		}
+	}
+}
+
+// namedOutputter is an outputter with its name, and how often it is sent in navigation solutions (0 is off)
+type namedOutputter struct {
+	name      string
+	rate      uint
+	outputter outputters.Outputter
+}
+
+// registrations returns the registered outputters, followed by the templates in the config
+// Templates are on by default, and a template with the name of a registered outputter, or that is invalid, is
+// skipped.
+func registrations(cfg *config.Config, logger *slog.Logger) []outputters.Registration {
+	regs := outputters.Registered()
+	names := make([]string, 0, len(cfg.Templates))
+	for name := range cfg.Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		name, text := name, cfg.Templates[name]
+		if _, ok := outputters.Lookup(name); ok {
+			logger.Error("Template has the name of a built in sentence", "template", name)
+			continue
+		}
+		if _, err := outputters.NewTemplate(name, text); err != nil {
+			logger.Error("Invalid template in config", "template", name, "err", err)
+			continue
+		}
+		regs = append(regs, outputters.Registration{
+			Name:        name,
+			Description: "Template from the config",
+			Rate:        1,
+			New: func(o outputters.Options) outputters.Outputter {
+				t, _ := outputters.NewTemplate(name, text)
+				t.Talker = o.Talker
+				return t
+			},
+		})
+	}
+	return regs
+}
+
+// allOutputters returns new instances of all the registered outputters and templates for NMEA style sinks, with
+// their rates
+// If the sink selects its sentences, only they are on, otherwise the outputters enabled in the config (or by
+// default) are on.
+func allOutputters(cfg *config.Config, sentences []string, logger *slog.Logger) []namedOutputter {
+	regs := registrations(cfg, logger)
+	for _, name := range sentences {
+		if !slices.ContainsFunc(regs, func(r outputters.Registration) bool { return r.Name == name }) {
+			logger.Error("Unknown sentence in config", "sentence", name)
+		}
+	}
+
+	outs := make([]namedOutputter, len(regs))
+	for i, r := range regs {
+		c := cfg.Outputter(r.Name)
+		on := c.IsEnabled(r.Rate > 0)
+		if sentences != nil {
+			on = slices.Contains(sentences, r.Name)
+		}
+		outs[i] = namedOutputter{name: r.Name, outputter: r.New(outputterOptions(cfg, r.Name, logger))}
+		if on {
+			outs[i].rate = c.Rate
+			if outs[i].rate == 0 {
+				outs[i].rate = max(r.Rate, 1)
+			}
+		}
+	}
+	return outs
+}
+
+// enabledOutputters returns the outputters that are on for a sink, with the sentences it selects
+func enabledOutputters(cfg *config.Config, sentences []string, logger *slog.Logger) []outputters.Outputter {
+	var outs []outputters.Outputter
+	for _, o := range allOutputters(cfg, sentences, logger) {
+		if o.rate > 0 {
+			outs = append(outs, outputters.Every(o.rate, o.outputter))
+		}
+	}
+	return outs
+}
+
+// NMEA_SINKS are the names of the sinks that send the registered outputters, and can select their sentences
+var NMEA_SINKS = []string{"serial", "pty"}
+
+// parseSentences returns the sentence names in a comma separated list, eg from the command line, or an error if
+// one of them can't be sent
+func parseSentences(cfg *config.Config, list string, logger *slog.Logger) ([]string, error) {
+	regs := registrations(cfg, logger)
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(regs, func(r outputters.Registration) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("unknown sentence: %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// sinkSentences returns the sentences selected for the named sink, or nil if it sends the enabled outputters
+// The sentences chosen on the command line, which aren't saved in the config, are used before the config's.
+func sinkSentences(cfg *config.Config, chosen map[string][]string, sink string) []string {
+	if s, ok := chosen[sink]; ok {
+		return s
+	}
+	switch sink {
+	case "serial":
+		return cfg.Serial.Sentences
+	case "pty":
+		return cfg.PTY.Sentences
+	}
+	return nil
+}
+
+// onSentences returns the names of the outputters that are on for the named sink
+func onSentences(cfg *config.Config, chosen map[string][]string, sink string, logger *slog.Logger) []string {
+	var names []string
+	for _, o := range allOutputters(cfg, sinkSentences(cfg, chosen, sink), logger) {
+		if o.rate > 0 {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
+
+// newOutputs returns the outputs for the serial port
+// Outputs that are off are included with a rate of 0, so the device can turn them on.
+func newOutputs(cfg *config.Config, chosen map[string][]string, logger *slog.Logger) []*serial.Output {
+	all := allOutputters(cfg, sinkSentences(cfg, chosen, "serial"), logger)
+	outputs := make([]*serial.Output, len(all))
+	enabled := 0
+	for i, o := range all {
+		outputs[i] = &serial.Output{Name: o.name, Outputter: o.outputter, Rate: o.rate}
+		if o.rate > 0 {
+			enabled++
+		}
+	}
+	logger.Debug("Outputs", "count", len(outputs), "enabled", enabled)
+	return outputs
+}
+
+// serialPin returns the USB device the config pins the serial port to, or an empty identity
+func serialPin(cfg *config.Config) serial.USBID {
+	p := cfg.Serial.Pin
+	if p == nil {
+		return serial.USBID{}
+	}
+	return serial.USBID{VID: p.VID, PID: p.PID, SerialNumber: p.SerialNumber}
+}
+
+// hasSinks returns whether the config enables any sinks other than the serial port
+func hasSinks(cfg *config.Config) bool {
+	return cfg.GDL90.Enabled || cfg.ForeFlight.Enabled || cfg.MAVLink.Enabled || cfg.GPSD.Enabled || cfg.PTY.Enabled
+}
+
+// namedSink is a sink with its name in the config
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// SINKS are the names of the sinks in the config, eg for their delays
+var SINKS = []string{"serial", "gdl90", "foreflight", "mavlink", "gpsd", "pty"}
+
+// newSinks returns the sinks, other than the serial port, enabled by the config
+func newSinks(cfg *config.Config, chosen map[string][]string, logger *slog.Logger) []namedSink {
+	var sinks []namedSink
+	if cfg.GDL90.Enabled {
+		if s := newGDL90(cfg.GDL90, logger); s != nil {
+			sinks = append(sinks, namedSink{"gdl90", s})
+		}
+	}
+	if cfg.ForeFlight.Enabled {
+		if s := newForeFlight(cfg.ForeFlight, logger); s != nil {
+			sinks = append(sinks, namedSink{"foreflight", s})
+		}
+	}
+	if cfg.MAVLink.Enabled {
+		if s := newMAVLink(cfg.MAVLink, logger); s != nil {
+			sinks = append(sinks, namedSink{"mavlink", s})
+		}
+	}
+	if cfg.GPSD.Enabled {
+		sinks = append(sinks, namedSink{"gpsd", newGPSD(cfg.GPSD)})
+	}
+	if cfg.PTY.Enabled {
+		sinks = append(sinks, namedSink{"pty", newPTY(cfg, chosen, logger)})
+	}
+	logger.Debug("Sinks", "count", len(sinks))
+	return sinks
+}
+
+// newGDL90 returns a GDL90 sender for the config, or nil if the address is invalid
+func newGDL90(cfg config.GDL90, logger *slog.Logger) *gdl90.Sender {
+	addr := cfg.Addr
+	if addr == "" {
+		addr = gdl90.DEFAULT_ADDR
+	}
+	s, err := gdl90.NewSender(addr)
+	if err != nil {
+		logger.Error("Invalid GDL90 address in config", "err", err)
+		return nil
+	}
+	s.Callsign = cfg.Callsign
+	if cfg.ICAO != "" {
+		icao, err := strconv.ParseUint(cfg.ICAO, 16, 24)
+		if err != nil {
+			logger.Error("Invalid GDL90 ICAO address in config", "icao", cfg.ICAO, "err", err)
+		} else {
+			s.Address = uint32(icao)
+		}
+	}
+	return s
+}
+
+// newForeFlight returns a UDP sender of XGPS and XATT messages for the config, or nil if the address is
+// invalid
+func newForeFlight(cfg config.ForeFlight, logger *slog.Logger) *udp.Sender {
+	addr := cfg.Addr
+	if addr == "" {
+		addr = outputters.FOREFLIGHT_ADDR
+	}
+	outs := []outputters.Outputter{outputters.Adapt(&outputters.XGPS{Name: cfg.Name})}
+	if !cfg.NoAttitude {
+		outs = append(outs, outputters.Adapt(&outputters.XATT{Name: cfg.Name}))
+	}
+	s, err := udp.NewSender(addr, outs)
+	if err != nil {
+		logger.Error("Invalid ForeFlight address in config", "err", err)
+		return nil
+	}
+	return s
+}
+
+// newMAVLink returns a MAVLink sender for the config, over the serial port if one is set or UDP if not, or nil
+// if the config is invalid
+func newMAVLink(cfg config.MAVLink, logger *slog.Logger) *mavlink.Sender {
+	var s *mavlink.Sender
+	if cfg.Port != "" {
+		baud := cfg.Baud
+		if baud == 0 {
+			baud = 57600
+		}
+		s = mavlink.NewSerialSender(cfg.Port, baud)
+	} else {
+		addr := cfg.Addr
+		if addr == "" {
+			addr = mavlink.DEFAULT_UDP_ADDR
+		}
+		var err error
+		s, err = mavlink.NewUDPSender(addr)
+		if err != nil {
+			logger.Error("Invalid MAVLink address in config", "err", err)
+			return nil
+		}
+	}
+
+	switch cfg.Message {
+	case "", "gps_input":
+		s.Message = mavlink.MSG_GPS_INPUT
+	case "hil_gps":
+		s.Message = mavlink.MSG_HIL_GPS
+	default:
+		logger.Error("Invalid MAVLink message in config, using gps_input", "message", cfg.Message)
+	}
+	if cfg.SystemID > 0 && cfg.SystemID < 256 {
+		s.Encoder.SystemID = byte(cfg.SystemID)
+	}
+	if cfg.ComponentID > 0 && cfg.ComponentID < 256 {
+		s.Encoder.ComponentID = byte(cfg.ComponentID)
+	}
+	return s
+}
+
+// newGPSD returns a gpsd server for the config
+func newGPSD(cfg config.GPSD) *gpsd.Server {
+	addr := cfg.Addr
+	if addr == "" {
+		addr = gpsd.DEFAULT_ADDR
+	}
+	return gpsd.NewServer(addr)
+}
+
+// newPTY returns a virtual serial port sender of the outputters that are on for it
+func newPTY(cfg *config.Config, chosen map[string][]string, logger *slog.Logger) *pty.Sender {
+	link := cfg.PTY.Link
+	if link == "" {
+		link = pty.DEFAULT_LINK
+	}
+	return pty.NewSender(link, enabledOutputters(cfg, sinkSentences(cfg, chosen, "pty"), logger))
+}
+
+// newStages returns the stages the config applies to the positions before they are sent, in order
+// The scenario engine and fault injector go after the error model, so attacks and faults work on the positions
+// with errors, and the fault injector is reset with the schedule in the config
+func newStages(cfg *config.Config, eng *scenario.Engine, inj *faults.Injector, logger *slog.Logger) []Stage {
+	var stages []Stage
+	if cfg.Errors.Enabled {
+		stages = append(stages, newErrorModel(cfg.Errors, logger))
+	}
+	if eng != nil {
+		stages = append(stages, eng)
+	}
+	if inj != nil {
+		inj.Reset(faultSchedule(cfg.Faults, logger))
+		stages = append(stages, inj)
+	}
+	return stages
+}
+
+// faultSchedule returns the schedule of faults in the config, skipping invalid faults
+func faultSchedule(cfg config.Faults, logger *slog.Logger) []faults.Step {
+	var steps []faults.Step
+	for _, s := range cfg.Schedule {
+		f, err := faults.ParseFault(s.Fault)
+		if err != nil {
+			logger.Error("Invalid fault in config", "err", err)
+			continue
+		}
+		steps = append(steps, faults.Step{At: time.Duration(s.At * float64(time.Second)), Fault: f})
+	}
+	return steps
+}
+
+// newResampler returns the resampler for the config, or nil if the positions are sent as they are received
+func newResampler(cfg config.Resample, logger *slog.Logger) *resample.Resampler {
+	if cfg.Rate == 0 {
+		return nil
+	}
+	r, err := resample.NewResampler(cfg.Rate)
+	if err != nil {
+		logger.Error("Invalid resample rate in config", "err", err)
+		return nil
+	}
+	r.Delay = time.Duration(cfg.Delay * float64(time.Second))
+	return r
+}
+
+// newLink returns the simulated link to a sink for the config, or nil if the config doesn't delay it
+func newLink(cfg config.Delay, sink string, logger *slog.Logger) *delay.Link {
+	if cfg.IsZero() {
+		return nil
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	logger.Info("Link delay", "sink", sink, "latency", cfg.Latency, "jitter", cfg.Jitter, "seed", seed)
+
+	l := delay.NewLink(seed)
+	l.Latency = time.Duration(cfg.Latency * float64(time.Second))
+	l.Jitter = time.Duration(cfg.Jitter * float64(time.Second))
+	if cfg.Distribution != "" {
+		d, err := delay.ParseDistribution(cfg.Distribution)
+		if err != nil {
+			logger.Error("Invalid delay distribution in config", "sink", sink, "err", err)
+		}
+		l.Distribution = d
+	}
+	l.Reorder = cfg.Reorder
+	l.Drop = cfg.Drop
+	return l
+}
+
+// newErrorModel returns a GPS error model for the config
+func newErrorModel(cfg config.Errors, logger *slog.Logger) *noise.Model {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	logger.Info("Error model", "seed", seed)
+
+	m := noise.NewModel(seed)
+	param(&m.HorizontalDrift, cfg.HorizontalDrift)
+	param(&m.VerticalDrift, cfg.VerticalDrift)
+	paramDuration(&m.CorrelationTime, cfg.CorrelationTime)
+	param(&m.HorizontalNoise, cfg.HorizontalNoise)
+	param(&m.VerticalNoise, cfg.VerticalNoise)
+	param(&m.VelocityNoise, cfg.VelocityNoise)
+	paramDuration(&m.MultipathInterval, cfg.MultipathInterval)
+	param(&m.MultipathSize, cfg.MultipathSize)
+	paramDuration(&m.MultipathDuration, cfg.MultipathDuration)
+	return m
+}
+
+// param will set p to v if it is set, or turn it off if it is negative
+func param(p *float64, v float64) {
+	switch {
+	case v < 0:
+		*p = 0
+	case v > 0:
+		*p = v
+	}
+}
+
+// paramDuration will set p to v seconds if it is set, or turn it off if it is negative
+func paramDuration(p *time.Duration, v float64) {
+	switch {
+	case v < 0:
+		*p = 0
+	case v > 0:
+		*p = time.Duration(v * float64(time.Second))
+	}
+}
+
+// outputterOptions returns the options the config sets for the named outputter
+func outputterOptions(cfg *config.Config, name string, logger *slog.Logger) outputters.Options {
+	c := cfg.Outputter(name)
+	return outputters.Options{
+		Talker:    outputterTalker(cfg, name, logger),
+		Altitude:  outputterAltitude(cfg, name, logger),
+		Deviation: c.Deviation,
+		Mode:      outputterMode(cfg, name, logger),
+	}
+}
+
+// outputterTalker returns the talker override for the named outputter, or an empty talker to use the global
+// one
+func outputterTalker(cfg *config.Config, name string, logger *slog.Logger) nmea.TalkerID {
+	s := cfg.Outputter(name).Talker
+	if s == "" {
+		return ""
+	}
+	t, err := nmea.ParseTalker(s)
+	if err != nil {
+		logger.Error("Invalid talker in config", "outputter", name, "err", err)
+		return ""
+	}
+	return t
+}
+
+// outputterMode returns the THS mode indicator for the named outputter, or an empty mode for the default
+func outputterMode(cfg *config.Config, name string, logger *slog.Logger) string {
+	s := cfg.Outputter(name).Mode
+	if s == "" {
+		return ""
+	}
+	m, err := nmea.ParseTHSMode(s)
+	if err != nil {
+		logger.Error("Invalid mode in config", "outputter", name, "err", err)
+	}
+	return m
+}
+
+// outputterAltitude returns the altitude reference for the named outputter, defaulting to MSL
+func outputterAltitude(cfg *config.Config, name string, logger *slog.Logger) outputters.AltitudeReference {
+	s := cfg.Outputter(name).Altitude
+	if s == "" {
+		return outputters.MSL
+	}
+	a, err := outputters.ParseAltitudeReference(s)
+	if err != nil {
+		logger.Error("Invalid altitude in config", "outputter", name, "err", err)
+	}
+	return a
+}
@@ -0,0 +1,131 @@
+package ubx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// UBX message classes used to configure a receiver
+const (
+	CLASS_ACK  = 0x05
+	CLASS_CFG  = 0x06
+	CLASS_MON  = 0x0A
+	CLASS_NMEA = 0xF0
+)
+
+// UBX ACK message IDs
+const (
+	ACK_NAK = 0x00
+	ACK_ACK = 0x01
+)
+
+// UBX CFG message IDs
+const (
+	CFG_PRT    = 0x00
+	CFG_MSG    = 0x01
+	CFG_RST    = 0x04
+	CFG_RATE   = 0x08
+	CFG_CFG    = 0x09
+	CFG_SBAS   = 0x16
+	CFG_NAVX5  = 0x23
+	CFG_NAV5   = 0x24
+	CFG_TP5    = 0x31
+	CFG_GNSS   = 0x3E
+	CFG_VALSET = 0x8A
+)
+
+// UBX MON message IDs
+const (
+	MON_VER = 0x04
+)
+
+// Standard NMEA message IDs, in the CLASS_NMEA class
+const (
+	NMEA_GGA = 0x00
+	NMEA_GLL = 0x01
+	NMEA_GSA = 0x02
+	NMEA_GSV = 0x03
+	NMEA_RMC = 0x04
+	NMEA_VTG = 0x05
+	NMEA_ZDA = 0x08
+	NMEA_THS = 0x0E
+)
+
+// UART1 is the port ID of the first UART, which is the port the connector pretends to be
+const UART1 = 1
+
+// ErrInvalidMessage is returned when a message can not be parsed
+var ErrInvalidMessage = errors.New("invalid UBX message")
+
+// Message is a UBX message
+type Message struct {
+	Class   byte
+	ID      byte
+	Payload []byte
+}
+
+// Parse returns the message in a frame, checking the sync characters, length and checksum
+func Parse(frame []byte) (Message, error) {
+	if len(frame) < 8 || frame[0] != SYNC_1 || frame[1] != SYNC_2 {
+		return Message{}, fmt.Errorf("%w: no header", ErrInvalidMessage)
+	}
+	n := int(binary.LittleEndian.Uint16(frame[4:]))
+	if len(frame) != n+8 {
+		return Message{}, fmt.Errorf("%w: length is %d, expected %d", ErrInvalidMessage, len(frame)-8, n)
+	}
+	a, b := Checksum(frame[2 : n+6])
+	if frame[n+6] != a || frame[n+7] != b {
+		return Message{}, fmt.Errorf("%w: bad checksum", ErrInvalidMessage)
+	}
+	return Message{Class: frame[2], ID: frame[3], Payload: frame[6 : n+6]}, nil
+}
+
+// Ack returns an ACK-ACK message, acknowledging a message of class and id
+func Ack(class, id byte) []byte {
+	return Frame(CLASS_ACK, ACK_ACK, []byte{class, id})
+}
+
+// Nak returns an ACK-NAK message, rejecting a message of class and id
+func Nak(class, id byte) []byte {
+	return Frame(CLASS_ACK, ACK_NAK, []byte{class, id})
+}
+
+// CfgPRT returns a CFG-PRT message for UART1 at baud, with 8N1, UBX and NMEA in and out
+func CfgPRT(baud uint32) []byte {
+	p := make([]byte, 20)
+	le := binary.LittleEndian
+	p[0] = UART1
+	le.PutUint32(p[4:], 0x000008D0)
+	le.PutUint32(p[8:], baud)
+	le.PutUint16(p[12:], 0x0007)
+	le.PutUint16(p[14:], 0x0003)
+	return Frame(CLASS_CFG, CFG_PRT, p)
+}
+
+// CfgMSG returns a CFG-MSG message with the rate of the message class and id on the current port
+func CfgMSG(class, id, rate byte) []byte {
+	return Frame(CLASS_CFG, CFG_MSG, []byte{class, id, rate})
+}
+
+// CfgRATE returns a CFG-RATE message with the measurement rate in milliseconds, one navigation solution per
+// measurement and aligned to GPS time
+func CfgRATE(measRate uint16) []byte {
+	p := make([]byte, 6)
+	binary.LittleEndian.PutUint16(p[0:], measRate)
+	binary.LittleEndian.PutUint16(p[2:], 1)
+	binary.LittleEndian.PutUint16(p[4:], 1)
+	return Frame(CLASS_CFG, CFG_RATE, p)
+}
+
+// MonVER returns a MON-VER message with the software and hardware versions and any extensions
+// (eg "PROTVER=18.00")
+func MonVER(sw, hw string, extensions ...string) []byte {
+	p := make([]byte, 40+30*len(extensions))
+	copy(p[0:30], sw)
+	copy(p[30:40], hw)
+	for i, e := range extensions {
+		copy(p[40+30*i:70+30*i], e)
+	}
+	return Frame(CLASS_MON, MON_VER, p)
+}
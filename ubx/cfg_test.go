@@ -0,0 +1,88 @@
+package ubx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		frame    []byte
+		expected Message
+		err      bool
+	}{
+		{"ACK-ACK", []byte{0xB5, 0x62, 0x05, 0x01, 0x02, 0x00, 0x06, 0x00, 0x0E, 0x37}, Message{CLASS_ACK, ACK_ACK, []byte{0x06, 0x00}}, false},
+		{"Empty Payload", Frame(CLASS_MON, MON_VER, nil), Message{CLASS_MON, MON_VER, []byte{}}, false},
+		{"Bad Checksum", []byte{0xB5, 0x62, 0x05, 0x01, 0x02, 0x00, 0x06, 0x00, 0x0E, 0x38}, Message{}, true},
+		{"Bad Sync", []byte{0xB5, 0x63, 0x05, 0x01, 0x02, 0x00, 0x06, 0x00, 0x0E, 0x37}, Message{}, true},
+		{"Short", []byte{0xB5, 0x62, 0x05, 0x01, 0x03, 0x00, 0x06, 0x00, 0x0E, 0x37}, Message{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Parse(tc.frame)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidMessage) {
+					t.Errorf("Expected ErrInvalidMessage, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if result.Class != tc.expected.Class || result.ID != tc.expected.ID || !bytes.Equal(result.Payload, tc.expected.Payload) {
+				t.Errorf("Expected: %+v, but got: %+v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCfgMessages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		msg      []byte
+		expected []byte
+	}{
+		{"ACK-ACK", Ack(CLASS_CFG, CFG_PRT), []byte{0xB5, 0x62, 0x05, 0x01, 0x02, 0x00, 0x06, 0x00, 0x0E, 0x37}},
+		{"ACK-NAK", Nak(CLASS_CFG, CFG_PRT), []byte{0xB5, 0x62, 0x05, 0x00, 0x02, 0x00, 0x06, 0x00, 0x0D, 0x32}},
+		{"CFG-MSG", CfgMSG(CLASS_NAV, NAV_PVT, 1), []byte{0xB5, 0x62, 0x06, 0x01, 0x03, 0x00, 0x01, 0x07, 0x01, 0x13, 0x51}},
+		{"CFG-RATE", CfgRATE(200), []byte{0xB5, 0x62, 0x06, 0x08, 0x06, 0x00, 0xC8, 0x00, 0x01, 0x00, 0x01, 0x00, 0xDE, 0x6A}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !bytes.Equal(tc.msg, tc.expected) {
+				t.Errorf("Expected: % X, but got: % X", tc.expected, tc.msg)
+			}
+		})
+	}
+}
+
+func TestCfgPRT(t *testing.T) {
+	m, err := Parse(CfgPRT(115200))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expected := []byte{0x01, 0, 0, 0, 0xD0, 0x08, 0, 0, 0x00, 0xC2, 0x01, 0x00, 0x07, 0x00, 0x03, 0x00, 0, 0, 0, 0}
+	if !bytes.Equal(m.Payload, expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, m.Payload)
+	}
+}
+
+func TestMonVER(t *testing.T) {
+	m, err := Parse(MonVER("ROM CORE 3.01", "00080000", "PROTVER=18.00"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(m.Payload) != 70 {
+		t.Fatalf("Expected payload length: 70, but got: %d", len(m.Payload))
+	}
+	if sw := string(bytes.TrimRight(m.Payload[:30], "\x00")); sw != "ROM CORE 3.01" {
+		t.Errorf("Expected: ROM CORE 3.01, but got: %q", sw)
+	}
+	if ext := string(bytes.TrimRight(m.Payload[40:], "\x00")); ext != "PROTVER=18.00" {
+		t.Errorf("Expected: PROTVER=18.00, but got: %q", ext)
+	}
+}
@@ -0,0 +1,210 @@
+package ubx
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+)
+
+// UBX sync characters that start every message
+const (
+	SYNC_1 = 0xB5
+	SYNC_2 = 0x62
+)
+
+// UBX message classes
+const (
+	CLASS_NAV = 0x01
+)
+
+// UBX NAV message IDs
+const (
+	NAV_POSLLH  = 0x02
+	NAV_PVT     = 0x07
+	NAV_VELNED  = 0x12
+	NAV_TIMEUTC = 0x21
+	NAV_SAT     = 0x35
+)
+
+// Fix types used in NAV-PVT
+const (
+	FIX_NONE = 0
+	FIX_DR   = 1
+	FIX_2D   = 2
+	FIX_3D   = 3
+)
+
+// Solution is a navigation solution to encode into UBX NAV messages
+type Solution struct {
+	Time     time.Time // UTC time of the fix
+	Lat, Lon float64   // degrees
+	Height   float64   // height above the ellipsoid in meters
+	HMSL     float64   // height above mean sea level in meters
+	VelN     float64   // north velocity in m/s
+	VelE     float64   // east velocity in m/s
+	VelD     float64   // down velocity in m/s
+	HeadMot  float64   // heading of motion (track) in degrees
+	HeadVeh  float64   // heading of the vehicle in degrees
+	MagDec   float64   // magnetic declination in degrees, positive east
+	FixType  uint8
+	NumSV    uint8
+	HAcc     float64 // horizontal accuracy in meters
+	VAcc     float64 // vertical accuracy in meters
+	SAcc     float64 // speed accuracy in m/s
+	HeadAcc  float64 // heading accuracy in degrees
+	TAcc     float64 // time accuracy in seconds
+	PDOP     float64
+}
+
+// Checksum returns the 8-bit Fletcher checksum of bs, which is the class, ID, length and payload of a message
+func Checksum(bs []byte) (a, b byte) {
+	for _, c := range bs {
+		a += c
+		b += a
+	}
+	return a, b
+}
+
+// Frame returns the message with sync characters, class, ID, length and checksum added to the payload
+func Frame(class, id byte, payload []byte) []byte {
+	msg := make([]byte, 0, len(payload)+8)
+	msg = append(msg, SYNC_1, SYNC_2, class, id, byte(len(payload)), byte(len(payload)>>8))
+	msg = append(msg, payload...)
+	a, b := Checksum(msg[2:])
+	return append(msg, a, b)
+}
+
+// ITOW returns the GPS time of week in milliseconds for a UTC time
+func ITOW(t time.Time) uint32 {
+	_, tow := gnss.GPSTime(t)
+	return uint32(tow / time.Millisecond)
+}
+
+// NavPVT returns a NAV-PVT message, the position, velocity and time solution
+func NavPVT(s Solution) []byte {
+	p := make([]byte, 92)
+	t := s.Time.UTC()
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], ITOW(t))
+	le.PutUint16(p[4:], uint16(t.Year()))
+	p[6] = byte(t.Month())
+	p[7] = byte(t.Day())
+	p[8] = byte(t.Hour())
+	p[9] = byte(t.Minute())
+	p[10] = byte(t.Second())
+	// valid date, valid time, fully resolved and valid declination
+	p[11] = 0x0F
+	le.PutUint32(p[12:], uint32(math.Round(s.TAcc*1e9)))
+	le.PutUint32(p[16:], uint32(int32(t.Nanosecond())))
+	p[20] = s.FixType
+	// gnssFixOK and headVehValid
+	if s.FixType != FIX_NONE {
+		p[21] = 0x21
+	}
+	// confirmedAvai, confirmedDate and confirmedTime
+	p[22] = 0xE0
+	p[23] = s.NumSV
+	le.PutUint32(p[24:], uint32(scale(s.Lon, 1e7)))
+	le.PutUint32(p[28:], uint32(scale(s.Lat, 1e7)))
+	le.PutUint32(p[32:], uint32(scale(s.Height, 1e3)))
+	le.PutUint32(p[36:], uint32(scale(s.HMSL, 1e3)))
+	le.PutUint32(p[40:], uint32(scale(s.HAcc, 1e3)))
+	le.PutUint32(p[44:], uint32(scale(s.VAcc, 1e3)))
+	le.PutUint32(p[48:], uint32(scale(s.VelN, 1e3)))
+	le.PutUint32(p[52:], uint32(scale(s.VelE, 1e3)))
+	le.PutUint32(p[56:], uint32(scale(s.VelD, 1e3)))
+	le.PutUint32(p[60:], uint32(scale(math.Hypot(s.VelN, s.VelE), 1e3)))
+	le.PutUint32(p[64:], uint32(scale(heading(s.HeadMot), 1e5)))
+	le.PutUint32(p[68:], uint32(scale(s.SAcc, 1e3)))
+	le.PutUint32(p[72:], uint32(scale(s.HeadAcc, 1e5)))
+	le.PutUint16(p[76:], uint16(scale(s.PDOP, 100)))
+	le.PutUint32(p[84:], uint32(scale(heading(s.HeadVeh), 1e5)))
+	le.PutUint16(p[88:], uint16(scale(s.MagDec, 100)))
+	le.PutUint16(p[90:], 100)
+	return Frame(CLASS_NAV, NAV_PVT, p)
+}
+
+// NavPOSLLH returns a NAV-POSLLH message, the geodetic position
+func NavPOSLLH(s Solution) []byte {
+	p := make([]byte, 28)
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], ITOW(s.Time))
+	le.PutUint32(p[4:], uint32(scale(s.Lon, 1e7)))
+	le.PutUint32(p[8:], uint32(scale(s.Lat, 1e7)))
+	le.PutUint32(p[12:], uint32(scale(s.Height, 1e3)))
+	le.PutUint32(p[16:], uint32(scale(s.HMSL, 1e3)))
+	le.PutUint32(p[20:], uint32(scale(s.HAcc, 1e3)))
+	le.PutUint32(p[24:], uint32(scale(s.VAcc, 1e3)))
+	return Frame(CLASS_NAV, NAV_POSLLH, p)
+}
+
+// NavVELNED returns a NAV-VELNED message, the velocity in north, east and down
+func NavVELNED(s Solution) []byte {
+	p := make([]byte, 36)
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], ITOW(s.Time))
+	le.PutUint32(p[4:], uint32(scale(s.VelN, 100)))
+	le.PutUint32(p[8:], uint32(scale(s.VelE, 100)))
+	le.PutUint32(p[12:], uint32(scale(s.VelD, 100)))
+	le.PutUint32(p[16:], uint32(scale(math.Sqrt(s.VelN*s.VelN+s.VelE*s.VelE+s.VelD*s.VelD), 100)))
+	le.PutUint32(p[20:], uint32(scale(math.Hypot(s.VelN, s.VelE), 100)))
+	le.PutUint32(p[24:], uint32(scale(heading(s.HeadMot), 1e5)))
+	le.PutUint32(p[28:], uint32(scale(s.SAcc, 100)))
+	le.PutUint32(p[32:], uint32(scale(s.HeadAcc, 1e5)))
+	return Frame(CLASS_NAV, NAV_VELNED, p)
+}
+
+// NavTIMEUTC returns a NAV-TIMEUTC message, the UTC time
+func NavTIMEUTC(s Solution) []byte {
+	p := make([]byte, 20)
+	t := s.Time.UTC()
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], ITOW(t))
+	le.PutUint32(p[4:], uint32(math.Round(s.TAcc*1e9)))
+	le.PutUint32(p[8:], uint32(int32(t.Nanosecond())))
+	le.PutUint16(p[12:], uint16(t.Year()))
+	p[14] = byte(t.Month())
+	p[15] = byte(t.Day())
+	p[16] = byte(t.Hour())
+	p[17] = byte(t.Minute())
+	p[18] = byte(t.Second())
+	// valid time of week, week number and UTC, from the USNO
+	p[19] = 0x37
+	return Frame(CLASS_NAV, NAV_TIMEUTC, p)
+}
+
+// NavSAT returns a NAV-SAT message, the satellites being tracked at t
+func NavSAT(t time.Time, sats []gnss.Satellite) []byte {
+	p := make([]byte, 8+12*len(sats))
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], ITOW(t))
+	p[4] = 1 // version
+	p[5] = byte(len(sats))
+	for i, s := range sats {
+		b := p[8+12*i:]
+		b[0] = 0 // GPS
+		b[1] = s.PRN
+		b[2] = byte(math.Round(s.SNR))
+		b[3] = byte(int8(math.Round(s.Elevation)))
+		le.PutUint16(b[4:], uint16(int16(math.Round(s.Azimuth))))
+		// quality is code and carrier locked, healthy, with an ephemeris orbit that is available
+		flags := uint32(0x7 | 0x1<<4 | 0x1<<8 | 0x1<<11)
+		if s.Used {
+			flags |= 0x1 << 3
+		}
+		le.PutUint32(b[8:], flags)
+	}
+	return Frame(CLASS_NAV, NAV_SAT, p)
+}
+
+// scale returns v multiplied by f and rounded, as the integer UBX uses for a scaled field
+func scale(v, f float64) int32 {
+	return int32(math.Round(v * f))
+}
+
+// heading will limit a heading in degrees to [0, 360)
+func heading(h float64) float64 {
+	return math.Mod(math.Mod(h, 360)+360, 360)
+}
@@ -0,0 +1,183 @@
+package ubx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+)
+
+var ts = time.Date(2022, time.January, 1, 0, 0, 1, 500000000, time.UTC)
+
+var solution = Solution{
+	Time:    ts,
+	Lat:     45.1234567,
+	Lon:     -75.7654321,
+	Height:  1200.5,
+	HMSL:    1234.5,
+	VelN:    -10.25,
+	VelE:    20.5,
+	VelD:    1.5,
+	HeadMot: -45,
+	HeadVeh: 400,
+	MagDec:  -12.34,
+	FixType: FIX_3D,
+	NumSV:   12,
+	HAcc:    0.5,
+	VAcc:    0.8,
+	SAcc:    0.1,
+	HeadAcc: 0.5,
+	TAcc:    20e-9,
+	PDOP:    1.2,
+}
+
+func TestFrame(t *testing.T) {
+	// ACK-ACK for a CFG-PRT message
+	expected := []byte{0xB5, 0x62, 0x05, 0x01, 0x02, 0x00, 0x06, 0x00, 0x0E, 0x37}
+	result := Frame(0x05, 0x01, []byte{0x06, 0x00})
+	if !bytes.Equal(result, expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, result)
+	}
+}
+
+func TestITOW(t *testing.T) {
+	testCases := []struct {
+		name     string
+		t        time.Time
+		expected uint32
+	}{
+		// 1 January 2022 was a Saturday, and GPS time is 18 seconds ahead of UTC
+		{"Saturday", ts, 6*24*3600000 + 18000 + 1500},
+		{"Week Rollover", time.Date(2022, time.January, 1, 23, 59, 50, 0, time.UTC), 8000},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := ITOW(tc.t); result != tc.expected {
+				t.Errorf("Expected: %d, but got: %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+// checkFrame will check the framing of a message and return its payload
+func checkFrame(t *testing.T, msg []byte, id byte, length int) []byte {
+	t.Helper()
+	if len(msg) != length+8 {
+		t.Fatalf("Expected length: %d, but got: %d", length+8, len(msg))
+	}
+	if msg[0] != SYNC_1 || msg[1] != SYNC_2 || msg[2] != CLASS_NAV || msg[3] != id {
+		t.Fatalf("Expected NAV 0x%02X header, but got: % X", id, msg[:4])
+	}
+	if n := binary.LittleEndian.Uint16(msg[4:]); int(n) != length {
+		t.Errorf("Expected payload length: %d, but got: %d", length, n)
+	}
+	a, b := Checksum(msg[2 : len(msg)-2])
+	if msg[len(msg)-2] != a || msg[len(msg)-1] != b {
+		t.Errorf("Expected checksum: %02X %02X, but got: % X", a, b, msg[len(msg)-2:])
+	}
+	return msg[6 : len(msg)-2]
+}
+
+func i32(bs []byte, offset int) int32 {
+	return int32(binary.LittleEndian.Uint32(bs[offset:]))
+}
+
+func TestNavPVT(t *testing.T) {
+	p := checkFrame(t, NavPVT(solution), NAV_PVT, 92)
+
+	testCases := []struct {
+		name     string
+		offset   int
+		expected int32
+	}{
+		{"iTOW", 0, 6*24*3600000 + 18000 + 1500},
+		{"nano", 16, 500000000},
+		{"lon", 24, -757654321},
+		{"lat", 28, 451234567},
+		{"height", 32, 1200500},
+		{"hMSL", 36, 1234500},
+		{"velN", 48, -10250},
+		{"velE", 52, 20500},
+		{"velD", 56, 1500},
+		{"gSpeed", 60, 22920},
+		{"headMot", 64, 31500000},
+		{"headVeh", 84, 4000000},
+	}
+	for _, tc := range testCases {
+		if result := i32(p, tc.offset); result != tc.expected {
+			t.Errorf("%s: Expected: %d, but got: %d", tc.name, tc.expected, result)
+		}
+	}
+
+	if y := binary.LittleEndian.Uint16(p[4:]); y != 2022 || p[6] != 1 || p[7] != 1 || p[8] != 0 || p[9] != 0 || p[10] != 1 {
+		t.Errorf("Expected 2022-01-01 00:00:01, but got: % X", p[4:11])
+	}
+	if p[20] != FIX_3D || p[21]&0x01 == 0 || p[23] != 12 {
+		t.Errorf("Expected a 3D fix with 12 satellites, but got: fixType %d flags %02X numSV %d", p[20], p[21], p[23])
+	}
+	if pdop := binary.LittleEndian.Uint16(p[76:]); pdop != 120 {
+		t.Errorf("Expected pDOP: 120, but got: %d", pdop)
+	}
+	if magDec := int16(binary.LittleEndian.Uint16(p[88:])); magDec != -1234 {
+		t.Errorf("Expected magDec: -1234, but got: %d", magDec)
+	}
+}
+
+func TestNavPOSLLH(t *testing.T) {
+	p := checkFrame(t, NavPOSLLH(solution), NAV_POSLLH, 28)
+	expected := []int32{6*24*3600000 + 18000 + 1500, -757654321, 451234567, 1200500, 1234500, 500, 800}
+	for i, e := range expected {
+		if result := i32(p, 4*i); result != e {
+			t.Errorf("Field %d: Expected: %d, but got: %d", i, e, result)
+		}
+	}
+}
+
+func TestNavVELNED(t *testing.T) {
+	p := checkFrame(t, NavVELNED(solution), NAV_VELNED, 36)
+	expected := []int32{6*24*3600000 + 18000 + 1500, -1025, 2050, 150, 2297, 2292, 31500000, 10, 50000}
+	for i, e := range expected {
+		if result := i32(p, 4*i); result != e {
+			t.Errorf("Field %d: Expected: %d, but got: %d", i, e, result)
+		}
+	}
+}
+
+func TestNavTIMEUTC(t *testing.T) {
+	p := checkFrame(t, NavTIMEUTC(solution), NAV_TIMEUTC, 20)
+	if tAcc := i32(p, 4); tAcc != 20 {
+		t.Errorf("Expected tAcc: 20, but got: %d", tAcc)
+	}
+	if nano := i32(p, 8); nano != 500000000 {
+		t.Errorf("Expected nano: 500000000, but got: %d", nano)
+	}
+	expected := []byte{0xE6, 0x07, 1, 1, 0, 0, 1, 0x37}
+	if !bytes.Equal(p[12:], expected) {
+		t.Errorf("Expected: % X, but got: % X", expected, p[12:])
+	}
+}
+
+func TestNavSAT(t *testing.T) {
+	sats := []gnss.Satellite{
+		{PRN: 5, Elevation: 45.4, Azimuth: 359.6, SNR: 42, Used: true},
+		{PRN: 17, Elevation: 7, Azimuth: 90, SNR: 28, Used: false},
+	}
+	p := checkFrame(t, NavSAT(ts, sats), NAV_SAT, 8+12*len(sats))
+
+	if p[4] != 1 || p[5] != 2 {
+		t.Errorf("Expected version 1 with 2 satellites, but got: version %d with %d", p[4], p[5])
+	}
+
+	expected := [][]byte{
+		{0, 5, 42, 45, 0x68, 0x01, 0, 0, 0x1F, 0x09, 0, 0},
+		{0, 17, 28, 7, 0x5A, 0x00, 0, 0, 0x17, 0x09, 0, 0},
+	}
+	for i, e := range expected {
+		if result := p[8+12*i : 20+12*i]; !bytes.Equal(result, e) {
+			t.Errorf("Satellite %d: Expected: % X, but got: % X", i, e, result)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package udp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+// Logger is the default logger for the udp package
+var Logger = slog.Default()
+
+// Sender is an object that will send the output of its outputters over UDP, one datagram per output
+type Sender struct {
+	// Addr is the address to send to, which may be a broadcast address
+	Addr       *net.UDPAddr
+	Outputters []outputters.Outputter
+}
+
+// NewSender returns a new Sender that sends to addr, eg "255.255.255.255:49002"
+func NewSender(addr string, outputters []outputters.Outputter) (*Sender, error) {
+	a, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve UDP address %s: %v", addr, err)
+	}
+	return &Sender{Addr: a, Outputters: outputters}, nil
+}
+
+// SendPositions will send the positions from the channel to the UDP address
+func (s *Sender) SendPositions(c <-chan xplane.Position, feedback chan<- string) error {
+	Logger.Debug("SendPositions Started", "addr", s.Addr)
+
+	// an unconnected socket, so a receiver that isn't listening doesn't cause write errors
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		Logger.Error("Failed to open UDP socket", "err", err)
+		feedback <- "Failed to open UDP socket"
+		return err
+	}
+	defer func() {
+		conn.Close()
+		Logger.Debug("UDP socket closed")
+	}()
+
+	var (
+		epochs outputters.Epochs
+		frames outputters.Frames
+	)
+	for pos := range c {
+		e := epochs.Next(pos)
+		frames.Reset()
+		for _, o := range s.Outputters {
+			if err := o.Outputs(e, &frames); err != nil {
+				Logger.Warn("Output failed", "err", err)
+				feedback <- "Output failed"
+			}
+		}
+		for i := 0; i < frames.Len(); i++ {
+			if _, err := conn.WriteToUDP(frames.Frame(i), s.Addr); err != nil {
+				Logger.Warn("Write failed", "err", err)
+				feedback <- "UDP write failed"
+				continue
+			}
+			if Logger.Enabled(context.Background(), slog.LevelDebug) {
+				Logger.Debug("Sent", "msg", string(frames.Frame(i)))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package udp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/outputters"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/xplane"
+)
+
+func TestSendPositions(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+	defer conn.Close()
+
+	s, err := NewSender(conn.LocalAddr().String(), []outputters.Outputter{outputters.Adapt(&outputters.XGPS{}), outputters.Adapt(&outputters.XATT{})})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	c := make(chan xplane.Position)
+	feedback := make(chan string, 10)
+	done := make(chan error)
+	go func() { done <- s.SendPositions(c, feedback) }()
+
+	c <- xplane.Position{Dat_lat: 45, Dat_lon: -75}
+	close(c)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	for _, expected := range []string{"XGPS", "XATT"} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("Expected %s message, but got: %v", expected, err)
+		}
+		if msg := string(buf[:n]); !strings.HasPrefix(msg, expected) {
+			t.Errorf("Expected prefix: %s, but got: %s", expected, msg)
+		}
+	}
+}
+
+func TestNewSenderInvalid(t *testing.T) {
+	if _, err := NewSender("not an address", nil); err == nil {
+		t.Errorf("Expected an error, but got none")
+	}
+}
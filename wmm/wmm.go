@@ -0,0 +1,204 @@
+package wmm
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDegree is the highest degree (and order) of the spherical harmonic expansion in a WMM model
+const MaxDegree = 12
+
+const (
+	// WGS84 ellipsoid semi-major axis in km
+	wgs84A = 6378.137
+	// WGS84 ellipsoid flattening
+	wgs84F = 1 / 298.257223563
+	// geomagnetic reference radius in km
+	refRadius = 6371.2
+)
+
+//go:embed WMM.COF
+var defaultCOF string
+
+// Default is the embedded World Magnetic Model
+var Default = mustParse(defaultCOF)
+
+// Model is a World Magnetic Model: the Gauss coefficients of the main field at an epoch and their secular
+// variation (rate of change per year)
+type Model struct {
+	Name  string
+	Epoch float64 // decimal year the coefficients are valid for
+	g, h  [MaxDegree + 1][MaxDegree + 1]float64
+	gd    [MaxDegree + 1][MaxDegree + 1]float64
+	hd    [MaxDegree + 1][MaxDegree + 1]float64
+}
+
+// Field is the magnetic field vector at a point, in nanoTesla
+type Field struct {
+	X float64 // north component
+	Y float64 // east component
+	Z float64 // down component
+}
+
+// H returns the horizontal intensity in nT
+func (f Field) H() float64 { return math.Hypot(f.X, f.Y) }
+
+// F returns the total intensity in nT
+func (f Field) F() float64 { return math.Sqrt(f.X*f.X + f.Y*f.Y + f.Z*f.Z) }
+
+// Declination returns the angle between true north and magnetic north in degrees, positive east
+func (f Field) Declination() float64 { return math.Atan2(f.Y, f.X) * 180 / math.Pi }
+
+// Inclination returns the angle of the field below the horizontal in degrees
+func (f Field) Inclination() float64 { return math.Atan2(f.Z, f.H()) * 180 / math.Pi }
+
+// Parse will read a model from r in the NOAA WMM.COF format
+func Parse(r io.Reader) (*Model, error) {
+	m := &Model{}
+	s := bufio.NewScanner(r)
+	header := true
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if header {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid header: %q", s.Text())
+			}
+			epoch, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid epoch: %v", err)
+			}
+			m.Epoch = epoch
+			m.Name = fields[1]
+			header = false
+			continue
+		}
+		if strings.HasPrefix(fields[0], "9999") {
+			return m, nil
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid coefficient line: %q", s.Text())
+		}
+		var v [6]float64
+		for i, f := range fields {
+			x, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid coefficient line %q: %v", s.Text(), err)
+			}
+			v[i] = x
+		}
+		n, mm := int(v[0]), int(v[1])
+		if n < 1 || n > MaxDegree || mm < 0 || mm > n {
+			return nil, fmt.Errorf("invalid degree and order: %d %d", n, mm)
+		}
+		m.g[n][mm], m.h[n][mm], m.gd[n][mm], m.hd[n][mm] = v[2], v[3], v[4], v[5]
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("missing end of coefficients marker")
+}
+
+// mustParse will parse the model or panic
+func mustParse(cof string) *Model {
+	m, err := Parse(strings.NewReader(cof))
+	if err != nil {
+		panic(fmt.Sprintf("wmm: invalid embedded model: %v", err))
+	}
+	return m
+}
+
+// DecimalYear returns t as a decimal year, eg 2025.5 for the middle of 2025
+func DecimalYear(t time.Time) float64 {
+	t = t.UTC()
+	start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return float64(t.Year()) + float64(t.Sub(start))/float64(end.Sub(start))
+}
+
+// Field returns the magnetic field at a geodetic latitude and longitude in degrees, altitude above the WGS84
+// ellipsoid in meters, and time
+func (m *Model) Field(lat, lon, alt float64, t time.Time) Field {
+	dt := DecimalYear(t) - m.Epoch
+
+	// keep away from the poles, where the east component is undefined
+	lat = math.Max(-89.999, math.Min(89.999, lat))
+	phi := lat * math.Pi / 180
+	lambda := lon * math.Pi / 180
+	h := alt / 1000
+
+	// geodetic to geocentric spherical coordinates
+	e2 := wgs84F * (2 - wgs84F)
+	sinPhi, cosPhi := math.Sincos(phi)
+	rc := wgs84A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	p := (rc + h) * cosPhi
+	z := (rc*(1-e2) + h) * sinPhi
+	r := math.Hypot(p, z)
+	phiC := math.Asin(z / r)
+
+	// Schmidt semi-normalised associated Legendre functions and their derivatives
+	var pnm, dpnm [MaxDegree + 1][MaxDegree + 1]float64
+	mu, cosPhiC := math.Sincos(phiC)
+	pnm[0][0] = 1
+	for n := 1; n <= MaxDegree; n++ {
+		if n == 1 {
+			pnm[1][1] = cosPhiC
+		} else {
+			pnm[n][n] = math.Sqrt(float64(2*n-1)/float64(2*n)) * cosPhiC * pnm[n-1][n-1]
+		}
+		for mm := 0; mm < n; mm++ {
+			k := math.Sqrt(float64((n-1)*(n-1) - mm*mm))
+			if n-2 < mm {
+				k = 0
+			}
+			prev := 0.0
+			if n >= 2 {
+				prev = pnm[n-2][mm]
+			}
+			pnm[n][mm] = (float64(2*n-1)*mu*pnm[n-1][mm] - k*prev) / math.Sqrt(float64(n*n-mm*mm))
+		}
+		for mm := 0; mm <= n; mm++ {
+			dpnm[n][mm] = (-float64(n)*mu*pnm[n][mm] + math.Sqrt(float64(n*n-mm*mm))*pnm[n-1][mm]) / cosPhiC
+		}
+	}
+
+	// sum the field in geocentric coordinates
+	var xc, yc, zc float64
+	ratio := refRadius / r
+	scale := ratio * ratio
+	for n := 1; n <= MaxDegree; n++ {
+		scale *= ratio
+		for mm := 0; mm <= n; mm++ {
+			g := m.g[n][mm] + dt*m.gd[n][mm]
+			hh := m.h[n][mm] + dt*m.hd[n][mm]
+			sinM, cosM := math.Sincos(float64(mm) * lambda)
+			a := g*cosM + hh*sinM
+			xc -= scale * a * dpnm[n][mm]
+			yc += scale * float64(mm) * (g*sinM - hh*cosM) * pnm[n][mm]
+			zc -= scale * float64(n+1) * a * pnm[n][mm]
+		}
+	}
+	yc /= cosPhiC
+
+	// rotate into the geodetic frame
+	sinD, cosD := math.Sincos(phiC - phi)
+	return Field{
+		X: xc*cosD - zc*sinD,
+		Y: yc,
+		Z: xc*sinD + zc*cosD,
+	}
+}
+
+// Declination returns the magnetic declination in degrees (positive east) from the Default model at a
+// latitude and longitude in degrees, altitude in meters, and time
+func Declination(lat, lon, alt float64, t time.Time) float64 {
+	return Default.Field(lat, lon, alt, t).Declination()
+}
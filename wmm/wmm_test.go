@@ -0,0 +1,131 @@
+package wmm
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decimalYearTime returns the time at a decimal year
+func decimalYearTime(y float64) time.Time {
+	start := time.Date(int(y), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return start.Add(time.Duration((y - math.Floor(y)) * float64(end.Sub(start))))
+}
+
+func TestDecimalYear(t *testing.T) {
+	testCases := []struct {
+		t        time.Time
+		expected float64
+	}{
+		{time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), 2025.0},
+		{time.Date(2025, time.July, 2, 12, 0, 0, 0, time.UTC), 2025.5},
+		{time.Date(2024, time.July, 2, 0, 0, 0, 0, time.UTC), 2024.5},
+		{time.Date(2025, time.January, 1, 2, 0, 0, 0, time.FixedZone("", 3600*2)), 2025.0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.t.String(), func(t *testing.T) {
+			result := DecimalYear(tc.t)
+			if math.Abs(result-tc.expected) > 1e-9 {
+				t.Errorf("Expected: %f, but got: %f", tc.expected, result)
+			}
+		})
+	}
+}
+
+// TestWMM2020 checks the calculation against the test values published with the WMM2020 model
+func TestWMM2020(t *testing.T) {
+	f, err := os.Open("testdata/WMM2020.COF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	testCases := []struct {
+		year, alt, lat, lon float64
+		x, y, z, i, d       float64
+	}{
+		{2020.0, 0, 80, 0, 6570.4, -146.3, 54606.0, 83.14, -1.28},
+		{2020.0, 0, 0, 120, 39624.3, 109.9, -10932.5, -15.42, 0.16},
+		{2020.0, 0, -80, 240, 5940.6, 15772.1, -52480.8, -72.20, 69.36},
+		{2020.0, 100, 80, 0, 6261.8, -185.5, 52429.1, 83.19, -1.70},
+		{2020.0, 100, 0, 120, 37636.7, 104.9, -10474.8, -15.55, 0.16},
+		{2020.0, 100, -80, 240, 5744.9, 14799.5, -49969.4, -72.37, 68.78},
+		{2022.5, 0, 80, 0, 6529.9, 1.1, 54713.4, 83.19, 0.01},
+		{2022.5, 0, 0, 120, 39684.7, -42.2, -10809.5, -15.24, -0.06},
+		{2022.5, 0, -80, 240, 6016.5, 15776.7, -52251.6, -72.09, 69.13},
+	}
+
+	for _, tc := range testCases {
+		f := m.Field(tc.lat, tc.lon, tc.alt*1000, decimalYearTime(tc.year))
+		for _, c := range []struct {
+			name                  string
+			got, expected, within float64
+		}{
+			{"X", f.X, tc.x, 0.1},
+			{"Y", f.Y, tc.y, 0.1},
+			{"Z", f.Z, tc.z, 0.1},
+			{"I", f.Inclination(), tc.i, 0.01},
+			{"D", f.Declination(), tc.d, 0.01},
+		} {
+			if math.Abs(c.got-c.expected) > c.within+1e-9 {
+				t.Errorf("%.1f %.0fkm %.0f,%.0f: %s expected: %.2f, but got: %.2f", tc.year, tc.alt, tc.lat, tc.lon, c.name, c.expected, c.got)
+			}
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if Default.Name != "WMM-2025" || Default.Epoch != 2025.0 {
+		t.Errorf("Unexpected default model: %s %.1f", Default.Name, Default.Epoch)
+	}
+
+	ts := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		lat, lon float64
+		expected float64
+	}{
+		{"Ottawa", 45.42, -75.70, -12.6},
+		{"London", 51.51, -0.13, 1.2},
+		{"Sydney", -33.87, 151.21, 12.9},
+		{"Cape Town", -33.92, 18.42, -25.6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Declination(tc.lat, tc.lon, 0, ts)
+			if math.Abs(result-tc.expected) > 1 {
+				t.Errorf("Expected about: %.1f, but got: %.2f", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		cof  string
+	}{
+		{"Empty", ""},
+		{"Bad Epoch", "abc WMM\n"},
+		{"Bad Degree", "2025.0 WMM\n 13  0 1.0 0.0 0.0 0.0\n9999\n"},
+		{"Short Line", "2025.0 WMM\n 1  0 1.0 0.0\n9999\n"},
+		{"No End Marker", "2025.0 WMM\n 1  0 1.0 0.0 0.0 0.0\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tc.cof)); err == nil {
+				t.Errorf("Expected an error, but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package xplane
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// GPS_FAILURE_DATAREFS are the failures of the GPS receivers in X-Plane
+var GPS_FAILURE_DATAREFS = []string{
+	"sim/operation/failures/rel_gps",
+	"sim/operation/failures/rel_gps2",
+}
+
+// FAILED is the value of a failure dataref when the failure is active
+const FAILED = 6
+
+// Dataref is a value of a dataref sent by X-Plane
+type Dataref struct {
+	// Index is the index of the dataref in the names requested
+	Index int
+	Value float32
+}
+
+// getDatarefRequest will return a byte slice with the request for a dataref at index
+// freq is the frequency in Hz, and 0 stops X-Plane sending it
+func getDatarefRequest(freq uint, index int, name string) []byte {
+	bs := make([]byte, 5+4+4+400)
+	copy(bs, "RREF\x00")
+	binary.LittleEndian.PutUint32(bs[5:], uint32(freq))
+	binary.LittleEndian.PutUint32(bs[9:], uint32(index))
+	copy(bs[13:], name)
+	return bs
+}
+
+// readDatarefs reads the datarefs in a RREF packet, after the header
+func readDatarefs(bs []byte) []Dataref {
+	var drs []Dataref
+	r := bytes.NewReader(bs)
+	for {
+		var v struct {
+			Index int32
+			Value float32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+			return drs
+		}
+		drs = append(drs, Dataref{Index: int(v.Index), Value: v.Value})
+	}
+}
+
+// RequestDatarefs will request the named datarefs from X-Plane and send their values to the channel
+// ctx is the context to stop requesting datarefs
+// xp_addr is the address of the X-Plane instance
+// freq is how often X-Plane sends the values, in Hz
+func RequestDatarefs(ctx context.Context, xp_addr *net.UDPAddr, freq uint, names []string, c chan<- Dataref) error {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// stop requesting datarefs and close the connection
+		for i, name := range names {
+			conn.WriteToUDP(getDatarefRequest(0, i, name), xp_addr)
+		}
+		conn.Close()
+	}()
+
+	for i, name := range names {
+		if _, err := conn.WriteToUDP(getDatarefRequest(freq, i, name), xp_addr); err != nil {
+			Logger.Error("Failed to request dataref", "name", name, "err", err)
+			return err
+		}
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		conn.SetDeadline(time.Now().Add(1 * time.Second))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				continue
+			}
+			Logger.Error("Failed to read datarefs", "err", err)
+			return err
+		}
+		// the header is RREF and one more byte
+		if n < 5 || string(buf[:4]) != "RREF" {
+			Logger.Warn("Invalid dataref header", "header", string(buf[:min(n, 5)]))
+			continue
+		}
+		for _, dr := range readDatarefs(buf[5:n]) {
+			select {
+			case c <- dr:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"math"
 	"net"
 	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
 )
 
 type Position struct {
@@ -25,6 +28,43 @@ type Position struct {
 	Prad        float32 // float roll rate in radians per second
 	Qrad        float32 // float pitch rate in radians per second
 	Rrad        float32 // float yaw rate in radians per second
+
+	// The fields below are not sent by X-Plane, they are set when it is received and by the stages between
+	// X-Plane and the sinks
+
+	// Time is the time of the fix. If zero, it is now
+	Time time.Time
+	// Quality is the quality of the fix, as a receiver would estimate it. Zero fields are nominal
+	Quality gnss.Quality
+}
+
+// MAX_POSITION_FREQ is the fastest rate X-Plane sends positions at, in Hz
+const MAX_POSITION_FREQ = 60
+
+// RPOS_HEADER starts every RPOS packet, followed by the position
+const RPOS_HEADER = "RPOS4"
+
+// RPOS_SIZE is the size of the position in a RPOS packet, after the header
+const RPOS_SIZE = 3*8 + 10*4
+
+// MAX_PACKET_SIZE is the largest UDP packet read from X-Plane
+const MAX_PACKET_SIZE = 1500
+
+// rpos is the position in a RPOS packet, as X-Plane sends it
+type rpos struct {
+	Dat_lon     float64
+	Dat_lat     float64
+	Dat_ele     float64
+	Y_agl_mtr   float32
+	Veh_the_loc float32
+	Veh_psi_loc float32
+	Veh_phi_loc float32
+	Vx_wrl      float32
+	Vy_wrl      float32
+	Vz_wrl      float32
+	Prad        float32
+	Qrad        float32
+	Rrad        float32
 }
 
 // SOG returns the speed over ground in m/s
@@ -33,19 +73,92 @@ func (p *Position) SOG() float64 {
 	return math.Sqrt(float64(p.Vx_wrl*p.Vx_wrl) + float64(p.Vz_wrl*p.Vz_wrl))
 }
 
+// Track returns the true track over the ground in degrees, [0, 360)
+// When the aircraft is almost stationary the track is noise, so the true heading is returned instead
+func (p *Position) Track() float64 {
+	if p.SOG() < 0.5 {
+		return math.Mod(float64(p.Veh_psi_loc)+360, 360)
+	}
+	// Vx is east and Vz is south
+	t := math.Atan2(float64(p.Vx_wrl), -float64(p.Vz_wrl)) * 180 / math.Pi
+	return math.Mod(t+360, 360)
+}
+
+// FixTime returns the time of the fix in UTC, which is now if the position doesn't have one
+func (p *Position) FixTime() time.Time {
+	if p.Time.IsZero() {
+		return time.Now().UTC()
+	}
+	return p.Time.UTC()
+}
+
+// Sky returns the satellites in view of the position at t, as the receiver sees them with the quality of the fix
+// Every output with satellites uses it, so they agree on the satellites in view and used
+func (p *Position) Sky(t time.Time) []gnss.Satellite {
+	// the satellites are seen from the height above the ellipsoid, X-Plane reports it above mean sea level
+	height := p.Dat_ele + geoid.Separation(p.Dat_lat, p.Dat_lon)
+	return p.Quality.OrNominal().Sky(gnss.Visible(p.Dat_lat, p.Dat_lon, height, t))
+}
+
 // ReadPosition reads a Position from an io.Reader
 func ReadPosition(r io.Reader) (*Position, error) {
-	pos := &Position{}
-	err := binary.Read(r, binary.LittleEndian, pos)
+	rp := &rpos{}
+	err := binary.Read(r, binary.LittleEndian, rp)
 	if err != nil {
-		Logger.Warn("binary.Read failed", "err", err, "size", binary.Size(pos))
+		Logger.Warn("binary.Read failed", "err", err, "size", binary.Size(rp))
 		return nil, err
 	}
-	return pos, nil
+	return &Position{
+		Dat_lon:     rp.Dat_lon,
+		Dat_lat:     rp.Dat_lat,
+		Dat_ele:     rp.Dat_ele,
+		Y_agl_mtr:   rp.Y_agl_mtr,
+		Veh_the_loc: rp.Veh_the_loc,
+		Veh_psi_loc: rp.Veh_psi_loc,
+		Veh_phi_loc: rp.Veh_phi_loc,
+		Vx_wrl:      rp.Vx_wrl,
+		Vy_wrl:      rp.Vy_wrl,
+		Vz_wrl:      rp.Vz_wrl,
+		Prad:        rp.Prad,
+		Qrad:        rp.Qrad,
+		Rrad:        rp.Rrad,
+	}, nil
+}
+
+// ParsePosition will parse the position from a RPOS packet, after the header, into p, without allocating
+// The fields that X-Plane doesn't send are left as they are.
+func ParsePosition(bs []byte, p *Position) error {
+	if len(bs) < RPOS_SIZE {
+		return fmt.Errorf("could not parse position: %d bytes, need %d", len(bs), RPOS_SIZE)
+	}
+	le := binary.LittleEndian
+	f32 := func(i int) float32 { return math.Float32frombits(le.Uint32(bs[i:])) }
+	p.Dat_lon = math.Float64frombits(le.Uint64(bs[0:]))
+	p.Dat_lat = math.Float64frombits(le.Uint64(bs[8:]))
+	p.Dat_ele = math.Float64frombits(le.Uint64(bs[16:]))
+	p.Y_agl_mtr = f32(24)
+	p.Veh_the_loc = f32(28)
+	p.Veh_psi_loc = f32(32)
+	p.Veh_phi_loc = f32(36)
+	p.Vx_wrl = f32(40)
+	p.Vy_wrl = f32(44)
+	p.Vz_wrl = f32(48)
+	p.Prad = f32(52)
+	p.Qrad = f32(56)
+	p.Rrad = f32(60)
+	return nil
+}
+
+// CheckPositionFreq returns an error if X-Plane can't send positions at freq Hz
+func CheckPositionFreq(freq uint) error {
+	if freq == 0 || freq > MAX_POSITION_FREQ {
+		return fmt.Errorf("unsupported position frequency: %d Hz, must be 1 to %d", freq, MAX_POSITION_FREQ)
+	}
+	return nil
 }
 
 // getRequest will return a byte slice with the request for positions
-// freq is the frequency in Hz. Valid values are numbers up to 60
+// freq is the frequency in Hz. Valid values are numbers up to MAX_POSITION_FREQ, and 0 stops the positions
 func getRequest(freq uint) []byte {
 	return []byte(fmt.Sprintf("RPOS\x00%d\x00", freq))
 }
@@ -56,6 +169,12 @@ func getRequest(freq uint) []byte {
 // c is the channel to send the positions to
 // wg is the wait group to signal when the function is done
 func RequestPositions(ctx context.Context, xp_addr *net.UDPAddr, freq uint, c chan<- Position, feedback chan<- string) {
+	if err := CheckPositionFreq(freq); err != nil {
+		Logger.Error("Invalid position frequency", "err", err)
+		feedback <- "Invalid position frequency"
+		return
+	}
+
 	// create a udp connection
 	conn, err := net.ListenUDP("udp", nil)
 	if err != nil {
@@ -75,13 +194,14 @@ func RequestPositions(ctx context.Context, xp_addr *net.UDPAddr, freq uint, c ch
 		return
 	}
 
+	// the buffer is reused for every packet, as the position is copied out of it
+	buf := make([]byte, MAX_PACKET_SIZE)
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 			conn.SetDeadline(time.Now().Add(1 * time.Second))
-			buf := make([]byte, 1500)
 
 			n, _, err := conn.ReadFromUDP(buf)
 			if err != nil {
@@ -94,22 +214,23 @@ func RequestPositions(ctx context.Context, xp_addr *net.UDPAddr, freq uint, c ch
 				feedback <- "Failed to read from UDP"
 				return
 			}
-			buffer := bytes.NewBuffer(buf[:n])
-			if string(buffer.Next(5)) != "RPOS4" {
-				Logger.Warn("Invalid header", "header", buffer.String())
+			packet := buf[:n]
+			if !bytes.HasPrefix(packet, []byte(RPOS_HEADER)) {
+				Logger.Warn("Invalid header", "header", string(packet[:min(n, len(RPOS_HEADER))]))
 				feedback <- "Invalid header"
 				continue
 			}
 
-			pos, err := ReadPosition(buffer)
-			if err != nil {
-				Logger.Warn("ReadPosition failed", "err", err)
-				feedback <- "ReadPosition failed"
+			var pos Position
+			if err := ParsePosition(packet[len(RPOS_HEADER):], &pos); err != nil {
+				Logger.Warn("ParsePosition failed", "err", err)
+				feedback <- "ParsePosition failed"
 				continue
 			}
 
+			pos.Time = time.Now().UTC()
 			feedback <- ""
-			c <- *pos
+			c <- pos
 		}
 	}
 }
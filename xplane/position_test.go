@@ -0,0 +1,113 @@
+package xplane
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/geoid"
+	"github.com/duncanvanzyl/xplane-serial-gps-connector/gnss"
+)
+
+// packet returns a RPOS packet with the position, as X-Plane sends it
+func packet(p Position) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(RPOS_HEADER)
+	binary.Write(&buf, binary.LittleEndian, rpos{
+		Dat_lon: p.Dat_lon, Dat_lat: p.Dat_lat, Dat_ele: p.Dat_ele, Y_agl_mtr: p.Y_agl_mtr,
+		Veh_the_loc: p.Veh_the_loc, Veh_psi_loc: p.Veh_psi_loc, Veh_phi_loc: p.Veh_phi_loc,
+		Vx_wrl: p.Vx_wrl, Vy_wrl: p.Vy_wrl, Vz_wrl: p.Vz_wrl, Prad: p.Prad, Qrad: p.Qrad, Rrad: p.Rrad,
+	})
+	return buf.Bytes()
+}
+
+var testPosition = Position{
+	Dat_lon: -75.123456789, Dat_lat: 45.987654321, Dat_ele: 1234.5, Y_agl_mtr: 1000.25,
+	Veh_the_loc: 2.5, Veh_psi_loc: 271.75, Veh_phi_loc: -15.5,
+	Vx_wrl: -60.5, Vy_wrl: 2.25, Vz_wrl: 3.125, Prad: 0.01, Qrad: -0.02, Rrad: 0.03,
+}
+
+func TestParsePosition(t *testing.T) {
+	bs := packet(testPosition)
+	if len(bs) != len(RPOS_HEADER)+RPOS_SIZE {
+		t.Fatalf("Expected: %d bytes, but got: %d", len(RPOS_HEADER)+RPOS_SIZE, len(bs))
+	}
+
+	var got Position
+	if err := ParsePosition(bs[len(RPOS_HEADER):], &got); err != nil {
+		t.Fatalf("ParsePosition failed: %v", err)
+	}
+	if got != testPosition {
+		t.Errorf("Expected: %+v, but got: %+v", testPosition, got)
+	}
+
+	// ParsePosition must agree with ReadPosition
+	read, err := ReadPosition(bytes.NewReader(bs[len(RPOS_HEADER):]))
+	if err != nil {
+		t.Fatalf("ReadPosition failed: %v", err)
+	}
+	if *read != got {
+		t.Errorf("Expected: %+v, but got: %+v", *read, got)
+	}
+
+	if err := ParsePosition(bs[len(RPOS_HEADER):len(bs)-1], &got); err == nil {
+		t.Errorf("Expected an error for a short packet")
+	}
+}
+
+func TestCheckPositionFreq(t *testing.T) {
+	testCases := []struct {
+		freq uint
+		ok   bool
+	}{
+		{0, false},
+		{1, true},
+		{20, true},
+		{50, true},
+		{MAX_POSITION_FREQ, true},
+		{MAX_POSITION_FREQ + 1, false},
+	}
+
+	for _, tc := range testCases {
+		if err := CheckPositionFreq(tc.freq); (err == nil) != tc.ok {
+			t.Errorf("Expected %d Hz valid: %t, but got: %v", tc.freq, tc.ok, err)
+		}
+	}
+}
+
+func BenchmarkParsePosition(b *testing.B) {
+	bs := packet(testPosition)[len(RPOS_HEADER):]
+	var p Position
+	b.ReportAllocs()
+	b.SetBytes(int64(len(bs)))
+	for i := 0; i < b.N; i++ {
+		if err := ParsePosition(bs, &p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadPosition(b *testing.B) {
+	bs := packet(testPosition)[len(RPOS_HEADER):]
+	b.ReportAllocs()
+	b.SetBytes(int64(len(bs)))
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadPosition(bytes.NewReader(bs)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSky(t *testing.T) {
+	// the geoid is about 100m below the ellipsoid south of India
+	p := Position{Dat_lat: 0, Dat_lon: 80, Dat_ele: 1000, Quality: gnss.Quality{Jamming: 5}}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	height := p.Dat_ele + geoid.Separation(p.Dat_lat, p.Dat_lon)
+	expected := p.Quality.OrNominal().Sky(gnss.Visible(p.Dat_lat, p.Dat_lon, height, now))
+	if got := p.Sky(now); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %+v, but got: %+v", expected, got)
+	}
+}